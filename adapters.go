@@ -0,0 +1,66 @@
+package shttp
+
+import (
+	"context"
+	"net/http"
+)
+
+// WrapHTTPHandler adapts a net/http handler to a Handler, so stdlib
+// compatible handlers (http.FileServer, promhttp, pprof, ...) can be
+// registered as routes. The handler sees the request with ctx as its
+// context, including the values set by the shttp middleware, and never
+// fails: it writes its own error responses.
+func WrapHTTPHandler(h http.Handler) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		h.ServeHTTP(w, r.WithContext(ctx))
+		return nil
+	}
+}
+
+// WrapHandlerFunc is WrapHTTPHandler for a handler function.
+func WrapHandlerFunc(f http.HandlerFunc) Handler {
+	return WrapHTTPHandler(f)
+}
+
+// ToHTTPHandler adapts a Handler to a net/http handler, so it can be mounted
+// on an http.ServeMux or wrapped by stdlib middleware. When the handler
+// fails before writing a response, errorHandler writes the error response;
+// a nil errorHandler answers like the router does (see HTTPError).
+func ToHTTPHandler(h Handler, errorHandler func(w http.ResponseWriter, r *http.Request, err error)) http.Handler {
+	if errorHandler == nil {
+		errorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			writeError(w, r, &route{}, err)
+		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := wrapResponseWriter(w)
+		if err := h(r.Context(), rw, r); err != nil && !rw.wroteHeader {
+			errorHandler(w, r, err)
+		}
+	})
+}
+
+// stdMiddlewareErrKey is the context key under which WrapStdMiddleware
+// passes the slot receiving the wrapped handler's error.
+type stdMiddlewareErrKey struct{}
+
+// WrapStdMiddleware adapts a net/http middleware (chi, gorilla/handlers,
+// nosurf, ...) to a Middleware. The request reaches the rest of the chain
+// with the context and writer the middleware passes on, and the error of the
+// next handler is returned as is, so the router still writes error
+// responses. The middleware must call the next handler synchronously; if it
+// answers the request itself, nil is returned.
+func WrapStdMiddleware(mw func(http.Handler) http.Handler) Middleware {
+	return func(next Handler) Handler {
+		h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if errp, ok := r.Context().Value(stdMiddlewareErrKey{}).(*error); ok {
+				*errp = next(r.Context(), w, r)
+			}
+		}))
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			var err error
+			h.ServeHTTP(w, r.WithContext(context.WithValue(ctx, stdMiddlewareErrKey{}, &err)))
+			return err
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package shttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWrapHTTPHandler(t *testing.T) {
+	router := NewRouter()
+	router.Use(RequestIDMiddleware())
+	router.GET("/std", WrapHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Values set by shttp middleware reach the stdlib handler.
+		w.Write([]byte("id=" + GetRequestID(r.Context())))
+	}))
+	router.GET("/files/", WrapHTTPHandler(http.NotFoundHandler()))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/std", nil))
+	if want := "id=" + w.Header().Get("X-Request-ID"); w.Code != http.StatusOK || w.Body.String() != want {
+		t.Errorf("got %d %q, want 200 %q", w.Code, w.Body.String(), want)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestToHTTPHandler(t *testing.T) {
+	failing := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return NewHTTPError(http.StatusTeapot, "short and stout")
+	}
+	partial := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusAccepted)
+		return errors.New("failed after writing")
+	}
+	custom := func(w http.ResponseWriter, r *http.Request, err error) {
+		http.Error(w, "custom: "+err.Error(), http.StatusBadGateway)
+	}
+
+	tests := []struct {
+		name         string
+		handler      Handler
+		errorHandler func(http.ResponseWriter, *http.Request, error)
+		wantStatus   int
+		wantBody     string
+	}{
+		{"success", simpleHandler("ok"), nil, http.StatusOK, "ok"},
+		{"default error handler", failing, nil, http.StatusTeapot, "short and stout\n"},
+		{"custom error handler", failing, custom, http.StatusBadGateway, "custom: short and stout\n"},
+		{"error after writing", partial, custom, http.StatusAccepted, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.Handle("/", ToHTTPHandler(tt.handler, tt.errorHandler))
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code != tt.wantStatus || w.Body.String() != tt.wantBody {
+				t.Errorf("got %d %q, want %d %q", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestWrapStdMiddleware(t *testing.T) {
+	type userKey struct{}
+	std := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Block") != "" {
+				http.Error(w, "blocked", http.StatusForbidden)
+				return
+			}
+			w.Header().Set("X-Std", "seen")
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, "alice")))
+		})
+	}
+
+	router := NewRouter()
+	router.Use(WrapStdMiddleware(std), WrapStdMiddleware(std))
+	router.GET("/user", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Write([]byte(ctx.Value(userKey{}).(string)))
+		return nil
+	})
+	router.GET("/fail", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return NewHTTPError(http.StatusConflict, "conflict")
+	})
+
+	tests := []struct {
+		name       string
+		path       string
+		block      bool
+		wantStatus int
+		wantBody   string
+	}{
+		{"context passed on", "/user", false, http.StatusOK, "alice"},
+		{"error returned to the router", "/fail", false, http.StatusConflict, "conflict\n"},
+		{"middleware answers itself", "/user", true, http.StatusForbidden, "blocked\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.block {
+				req.Header.Set("X-Block", "1")
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus || w.Body.String() != tt.wantBody {
+				t.Errorf("got %d %q, want %d %q", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package shttp
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ResponseSummary describes a completed response, as passed to AfterResponse hooks.
+type ResponseSummary struct {
+	Method   string
+	Path     string
+	Pattern  string
+	Status   int
+	Bytes    int64
+	Duration time.Duration
+}
+
+// AfterResponse registers fn to run after every response has been flushed
+// to the client, e.g. to publish domain events or analytics without
+// delaying the response. Hooks run in registration order in a goroutine
+// tracked by the server (see Stats), with the request context detached from
+// its cancellation. A panicking hook is logged and does not affect the
+// others. Hooks must be registered before the server starts.
+//
+// The response is flushed before the hooks start, which ends net/http's
+// chance to compute its Content-Length: over HTTP/1.1, responses whose
+// handler did not set Content-Length are sent with chunked encoding once a
+// hook is registered. Handlers wanting a fixed length, e.g. for clients that
+// show download progress, set the header themselves; empty responses get
+// "Content-Length: 0".
+func (s *Server) AfterResponse(fn func(ctx context.Context, summary ResponseSummary)) {
+	s.afterResponse = append(s.afterResponse, fn)
+}
+
+// runAfterResponse flushes the response and starts the AfterResponse hooks.
+func (s *Server) runAfterResponse(req *http.Request, rw *responseWriter, duration time.Duration) {
+	if !rw.wroteHeader {
+		// Make sure the client sees the final status before hooks run. The
+		// headers are still open, so keep the empty body from being chunked.
+		rw.Header().Set("Content-Length", "0")
+		rw.WriteHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(rw.ResponseWriter).Flush()
+
+	summary := ResponseSummary{
+		Method:   req.Method,
+		Path:     req.URL.Path,
+		Pattern:  req.Pattern,
+		Status:   rw.statusCode(),
+		Bytes:    rw.size,
+		Duration: duration,
+	}
+	ctx := context.WithoutCancel(req.Context())
+	hooks := s.afterResponse
+	s.goroutines.start(ctx, func() {
+		for _, hook := range hooks {
+			s.callAfterResponse(ctx, hook, summary)
+		}
+	}, "AfterResponse")
+}
+
+func (s *Server) callAfterResponse(ctx context.Context, hook func(context.Context, ResponseSummary), summary ResponseSummary) {
+	defer func() {
+		if err := recover(); err != nil && s.logger != nil {
+			s.logger.Errorf(ctx, "[http.after_response] hook panicked: %v", err)
+		}
+	}()
+	hook(ctx, summary)
+}
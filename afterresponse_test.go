@@ -0,0 +1,58 @@
+package shttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAfterResponse(t *testing.T) {
+	server := New(context.Background(), &Config{Addr: "127.0.0.1:0"})
+	server.POST("/orders/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+		return nil
+	})
+
+	summaries := make(chan ResponseSummary, 1)
+	server.AfterResponse(func(ctx context.Context, summary ResponseSummary) {
+		panic("broken hook")
+	})
+	server.AfterResponse(func(ctx context.Context, summary ResponseSummary) {
+		if ctx.Err() != nil {
+			t.Errorf("hook context is done: %v", ctx.Err())
+		}
+		summaries <- summary
+	})
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/orders/1", nil))
+	if !w.Flushed {
+		t.Error("response was not flushed before hooks ran")
+	}
+
+	select {
+	case got := <-summaries:
+		if got.Status != http.StatusCreated || got.Bytes != 7 || got.Pattern != "/orders/{id}" || got.Method != http.MethodPost {
+			t.Errorf("summary = %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("AfterResponse hook did not run")
+	}
+
+	server.DELETE("/orders/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/orders/1", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Length") != "0" {
+		t.Errorf("empty response = %d, Content-Length %q, want 200 and 0", w.Code, w.Header().Get("Content-Length"))
+	}
+	select {
+	case <-summaries:
+	case <-time.After(time.Second):
+		t.Fatal("AfterResponse hook did not run for the empty response")
+	}
+}
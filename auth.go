@@ -0,0 +1,78 @@
+package shttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// routeKey is the context key for the route serving a request.
+type routeKey struct{}
+
+// authRequirement is a route's declared authentication requirement.
+type authRequirement int
+
+const (
+	// No declaration: AuthMiddleware requires authentication
+	authDefault authRequirement = iota
+	authRequired
+	authPublic
+)
+
+// RequireAuth declares that the route requires authentication. It is what
+// AuthMiddleware enforces for undeclared routes too; declaring it keeps the
+// intent visible at the registration site.
+func RequireAuth() RouteOption {
+	return func(rt *route) {
+		rt.auth = authRequired
+	}
+}
+
+// Public declares that the route is served without authentication:
+// AuthMiddleware lets its requests through without calling the
+// authenticator. Public routes are listed by Router.PublicRoutes and
+// reported when the server starts.
+func Public() RouteOption {
+	return func(rt *route) {
+		rt.auth = authPublic
+	}
+}
+
+// Authenticator verifies the credentials of a request. It returns the
+// context to continue with, e.g. carrying the user ID under UserIDKey, or an
+// error when the request is not authenticated.
+type Authenticator func(ctx context.Context, r *http.Request) (context.Context, error)
+
+// AuthMiddleware authenticates every request with authenticate, except those
+// for routes registered with Public. Authentication failures are answered
+// with 401, unless authenticate returns an HTTPError (e.g. 403).
+func AuthMiddleware(authenticate Authenticator) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if rt, ok := ctx.Value(routeKey{}).(*route); ok && rt.auth == authPublic {
+				return next(ctx, w, r)
+			}
+			authCtx, err := authenticate(ctx, r)
+			if err != nil {
+				var httpErr HTTPError
+				if errors.As(err, &httpErr) {
+					return err
+				}
+				return NewHTTPError(http.StatusUnauthorized, "authentication required")
+			}
+			return next(authCtx, w, r)
+		}
+	}
+}
+
+// PublicRoutes returns the routes registered with Public, as "METHOD /path"
+// in registration order.
+func (r *Router) PublicRoutes() []string {
+	var public []string
+	for _, rt := range r.routeList() {
+		if rt.auth == authPublic {
+			public = append(public, rt.String())
+		}
+	}
+	return public
+}
@@ -0,0 +1,84 @@
+package shttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	authenticate := func(ctx context.Context, r *http.Request) (context.Context, error) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer good":
+			return context.WithValue(ctx, UserIDKey, "alice"), nil
+		case "Bearer banned":
+			return nil, NewHTTPError(http.StatusForbidden, "banned")
+		default:
+			return nil, errors.New("invalid token")
+		}
+	}
+	whoami := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Write([]byte("user=" + GetUserID(ctx)))
+		return nil
+	}
+
+	router := NewRouter()
+	router.Use(AuthMiddleware(authenticate))
+	router.GET("/health", whoami, Public())
+	router.GET("/me", whoami, RequireAuth())
+	router.GET("/orders", whoami)
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"public without credentials", "/health", "", http.StatusOK, "user="},
+		{"required with credentials", "/me", "Bearer good", http.StatusOK, "user=alice"},
+		{"required without credentials", "/me", "", http.StatusUnauthorized, ""},
+		{"undeclared defaults to required", "/orders", "", http.StatusUnauthorized, ""},
+		{"authenticator HTTPError kept", "/me", "Bearer banned", http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", tt.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+
+	t.Run("discovery is public", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/me", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+	})
+}
+
+func TestPublicRoutes(t *testing.T) {
+	router := NewRouter()
+	router.GET("/health", simpleHandler("ok"), Public())
+	router.GET("/me", simpleHandler("me"), RequireAuth())
+	router.Group("/docs").ANY("/", simpleHandler("docs"), Public())
+
+	want := []string{"GET /health", "ANY /docs"}
+	if got := router.PublicRoutes(); !slices.Equal(got, want) {
+		t.Errorf("PublicRoutes() = %v, want %v", got, want)
+	}
+}
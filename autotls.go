@@ -0,0 +1,144 @@
+package shttp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// ErrNoAutoTLS is returned by StartAutoTLS when Config.AutoTLS is not set.
+var ErrNoAutoTLS = errors.New("shttp: StartAutoTLS needs Config.AutoTLS")
+
+// CertManager obtains and renews certificates from an ACME certificate
+// authority such as Let's Encrypt. shttp does not bundle an ACME client;
+// golang.org/x/crypto/acme/autocert's *Manager satisfies this interface:
+//
+//	config.AutoTLS = func(domains []string, cacheDir string) shttp.CertManager {
+//		return &autocert.Manager{
+//			Prompt:     autocert.AcceptTOS,
+//			HostPolicy: autocert.HostWhitelist(domains...),
+//			Cache:      autocert.DirCache(cacheDir),
+//		}
+//	}
+type CertManager interface {
+	// GetCertificate returns the certificate for the TLS handshake,
+	// obtaining it first when needed
+	GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+
+	// HTTPHandler answers ACME HTTP-01 challenges and passes other
+	// requests to fallback
+	HTTPHandler(fallback http.Handler) http.Handler
+}
+
+// StartAutoTLS starts the server with TLS like StartTLS, with certificates
+// for domains obtained and renewed automatically by the CertManager built by
+// Config.AutoTLS, cached in Config.AutoTLSCacheDir. A plain HTTP listener on
+// Config.AutoTLSHTTPAddr (":80" by default, where certificate authorities
+// send HTTP-01 challenges) answers the challenges and redirects other GET
+// and HEAD requests to HTTPS. If that listener fails, the error is logged
+// and the server goes on with the certificates it can get otherwise, e.g.
+// from the cache.
+//
+// Shutdown stops the HTTP listener too.
+func (s *Server) StartAutoTLS(domains ...string) error {
+	if s.config.AutoTLS == nil {
+		return ErrNoAutoTLS
+	}
+	if len(domains) == 0 {
+		return errors.New("shttp: StartAutoTLS needs at least one domain")
+	}
+	cacheDir := s.autoTLSCacheDir()
+	if err := os.MkdirAll(cacheDir, 0o700); err != nil {
+		return fmt.Errorf("shttp: creating the certificate cache: %w", err)
+	}
+
+	manager := s.config.AutoTLS(domains, cacheDir)
+	tlsConfig := new(tls.Config)
+	if s.server.TLSConfig != nil {
+		// Keep the mutual TLS settings
+		tlsConfig = s.server.TLSConfig.Clone()
+	}
+	tlsConfig.GetCertificate = manager.GetCertificate
+	s.server.TLSConfig = tlsConfig
+
+	return s.start(nil, "TLS server with automatic certificates", func(l net.Listener) error {
+		_, port, err := net.SplitHostPort(l.Addr().String())
+		if err != nil {
+			l.Close()
+			return fmt.Errorf("shttp: automatic TLS needs a TCP listener: %w", err)
+		}
+		s.startChallengeServer(manager.HTTPHandler(httpsRedirect(port)))
+		return s.server.ServeTLS(l, "", "")
+	})
+}
+
+// autoTLSCacheDir returns the certificate cache directory of StartAutoTLS.
+func (s *Server) autoTLSCacheDir() string {
+	if s.config.AutoTLSCacheDir != "" {
+		return s.config.AutoTLSCacheDir
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "shttp-autotls")
+	}
+	return "shttp-autotls"
+}
+
+// startChallengeServer serves handler over plain HTTP on
+// Config.AutoTLSHTTPAddr.
+func (s *Server) startChallengeServer(handler http.Handler) {
+	addr := s.config.AutoTLSHTTPAddr
+	if addr == "" {
+		addr = ":80"
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		s.logger.Errorf(s.ctx, "[server.autotls] HTTP listener failed, HTTP-01 challenges cannot be answered: %v", err)
+		return
+	}
+	challenge := &http.Server{
+		Handler:      handler,
+		ReadTimeout:  s.config.ReadTimeout,
+		WriteTimeout: s.config.WriteTimeout,
+		IdleTimeout:  s.config.IdleTimeout,
+	}
+	s.challenge.Store(challenge)
+	s.logger.Infof(s.ctx, "[server.autotls] Answering HTTP-01 challenges on %s", l.Addr())
+	go challenge.Serve(l)
+}
+
+// shutdownChallengeServer stops the HTTP listener started by StartAutoTLS,
+// if any.
+func (s *Server) shutdownChallengeServer(ctx context.Context) error {
+	challenge := s.challenge.Load()
+	if challenge == nil {
+		return nil
+	}
+	if err := challenge.Shutdown(ctx); err != nil {
+		return errors.Join(err, challenge.Close())
+	}
+	return nil
+}
+
+// httpsRedirect redirects GET and HEAD requests to the same URL over HTTPS
+// on port, and refuses others, whose body was already sent in the clear.
+func httpsRedirect(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "use HTTPS", http.StatusBadRequest)
+			return
+		}
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
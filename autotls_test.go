@@ -0,0 +1,158 @@
+package shttp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andres-vara/slogr"
+)
+
+// fakeCertManager serves a fixed certificate and answers one challenge.
+type fakeCertManager struct {
+	cert tls.Certificate
+
+	mu          sync.Mutex
+	serverNames []string
+}
+
+func (m *fakeCertManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.serverNames = append(m.serverNames, hello.ServerName)
+	return &m.cert, nil
+}
+
+func (m *fakeCertManager) HTTPHandler(fallback http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/.well-known/acme-challenge/token" {
+			io.WriteString(w, "key-authorization")
+			return
+		}
+		fallback.ServeHTTP(w, r)
+	})
+}
+
+// freeAddr returns a loopback address with a port nothing listens on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestServerStartAutoTLS(t *testing.T) {
+	logger := slogr.New(io.Discard, slogr.DefaultOptions())
+	if err := New(context.Background(), &Config{Logger: logger}).StartAutoTLS("example.com"); !errors.Is(err, ErrNoAutoTLS) {
+		t.Errorf("StartAutoTLS() without Config.AutoTLS error = %v, want ErrNoAutoTLS", err)
+	}
+
+	certFile, keyFile := writeTestCert(t)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := &fakeCertManager{cert: cert}
+	var gotDomains []string
+	cacheDir := filepath.Join(t.TempDir(), "certs")
+	httpAddr := freeAddr(t)
+	server := New(context.Background(), &Config{
+		Addr:   "127.0.0.1:0",
+		Logger: logger,
+		AutoTLS: func(domains []string, dir string) CertManager {
+			if dir != cacheDir {
+				t.Errorf("cache directory = %q, want %q", dir, cacheDir)
+			}
+			gotDomains = domains
+			return manager
+		},
+		AutoTLSCacheDir: cacheDir,
+		AutoTLSHTTPAddr: httpAddr,
+	})
+	server.GET("/", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		_, err := io.WriteString(w, "secure")
+		return err
+	})
+
+	if err := server.StartAutoTLS(); err == nil {
+		t.Error("StartAutoTLS() without domains succeeded")
+	}
+	go server.StartAutoTLS("example.com", "www.example.com")
+	waitFor(t, func() bool { return server.Addr() != nil && server.challenge.Load() != nil })
+	defer server.Shutdown(context.Background())
+
+	if !slices.Equal(gotDomains, []string{"example.com", "www.example.com"}) {
+		t.Errorf("domains = %v", gotDomains)
+	}
+	if info, err := os.Stat(cacheDir); err != nil || !info.IsDir() {
+		t.Errorf("cache directory not created: %v", err)
+	}
+
+	tlsClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		InsecureSkipVerify: true,
+		ServerName:         "example.com",
+	}}}
+	resp, err := tlsClient.Get("https://" + server.Addr().String() + "/")
+	if err != nil {
+		t.Fatalf("GET over TLS: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "secure" {
+		t.Errorf("TLS response = %q, want secure", body)
+	}
+	manager.mu.Lock()
+	if !slices.Contains(manager.serverNames, "example.com") {
+		t.Errorf("certificates requested for %v, want example.com", manager.serverNames)
+	}
+	manager.mu.Unlock()
+
+	httpClient := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	get := func(path string) (*http.Response, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, "http://"+httpAddr+path, nil)
+		req.Host = "example.com"
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s over HTTP: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+	if _, body := get("/.well-known/acme-challenge/token"); body != "key-authorization" {
+		t.Errorf("challenge response = %q, want key-authorization", body)
+	}
+	_, port, _ := net.SplitHostPort(server.Addr().String())
+	resp, _ = get("/docs?page=2")
+	if want := "https://example.com:" + port + "/docs?page=2"; resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != want {
+		t.Errorf("redirect = %d %q, want 301 %q", resp.StatusCode, resp.Header.Get("Location"), want)
+	}
+	post, err := httpClient.Post("http://"+httpAddr+"/login", "text/plain", strings.NewReader("secret"))
+	if err != nil {
+		t.Fatalf("POST over HTTP: %v", err)
+	}
+	post.Body.Close()
+	if post.StatusCode != http.StatusBadRequest {
+		t.Errorf("POST over HTTP status = %d, want 400", post.StatusCode)
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if _, err := net.Dial("tcp", httpAddr); err == nil {
+		t.Error("HTTP listener still open after Shutdown")
+	}
+}
@@ -0,0 +1,204 @@
+package shttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchRequest is one sub-request of a batch.
+type BatchRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchResponse is the outcome of one sub-request. Body holds the response
+// as JSON when it is valid JSON, and as a JSON string otherwise.
+type BatchResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+
+	// Time spent serving the sub-request, in milliseconds
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// BatchOptions configures BatchWithOptions.
+type BatchOptions struct {
+	// Maximum number of sub-requests in one batch (default 20)
+	MaxRequests int
+
+	// Maximum number of sub-requests executed concurrently (default 4)
+	MaxConcurrency int
+}
+
+// Batch registers a POST endpoint at path that accepts a JSON array of
+// BatchRequest, dispatches each one through the router and responds with
+// the array of BatchResponse in the same order, each with its duration.
+// Sub-requests share the batch request's context and inherit its
+// authentication and identity headers (batchSharedHeaders, e.g.
+// Authorization), so they run with the caller's identity; their own headers
+// take precedence. Batches cannot be nested. The durations are also reported in a
+// Server-Timing header, one "batch-<i>" metric per sub-request, so browser
+// tooling shows which sub-call was slow.
+func (r *Router) Batch(path string) {
+	r.BatchWithOptions(path, BatchOptions{})
+}
+
+// BatchWithOptions is like Batch with explicit options.
+func (r *Router) BatchWithOptions(path string, opts BatchOptions) {
+	if opts.MaxRequests <= 0 {
+		opts.MaxRequests = 20
+	}
+	if opts.MaxConcurrency <= 0 {
+		opts.MaxConcurrency = 4
+	}
+	root := r.root()
+
+	r.POST(path, func(ctx context.Context, w http.ResponseWriter, req *http.Request) error {
+		if ctx.Value(batchKey{}) != nil {
+			// However the sub-request spelled the path
+			return NewHTTPError(http.StatusBadRequest, "nested batches are not allowed")
+		}
+		ctx = context.WithValue(ctx, batchKey{}, true)
+
+		var batch []BatchRequest
+		if err := json.NewDecoder(req.Body).Decode(&batch); err != nil {
+			return NewHTTPError(http.StatusBadRequest, "invalid batch: "+err.Error())
+		}
+		if len(batch) > opts.MaxRequests {
+			return NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("batch exceeds %d requests", opts.MaxRequests))
+		}
+
+		responses := make([]BatchResponse, len(batch))
+		sem := make(chan struct{}, opts.MaxConcurrency)
+		var wg sync.WaitGroup
+		for i, sub := range batch {
+			if sub.Method == "" {
+				sub.Method = http.MethodGet
+				batch[i].Method = sub.Method
+			}
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				responses[i] = dispatchBatch(ctx, root, req, sub)
+			}()
+		}
+		wg.Wait()
+
+		timings := make([]string, len(responses))
+		for i, resp := range responses {
+			timings[i] = fmt.Sprintf("batch-%d;desc=%q;dur=%.3f", i, batch[i].Method+" "+batch[i].Path, resp.DurationMs)
+		}
+		w.Header().Set("Server-Timing", strings.Join(timings, ", "))
+		return JSON(w, http.StatusOK, responses)
+	})
+}
+
+// batchKey is the context key marking requests served within a batch.
+type batchKey struct{}
+
+// batchSharedHeaders are the headers of the batch request that carry the
+// caller's authentication and identity into each sub-request. Others, such
+// as conditional, checksum or encoding headers, describe the batch body and
+// would apply to the wrong requests.
+var batchSharedHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"X-Request-ID",
+	"X-Forwarded-For",
+	"X-Forwarded-Proto",
+	"X-Real-IP",
+	DefaultGatewaySignatureHeader,
+}
+
+// dispatchBatch serves one sub-request through root.
+func dispatchBatch(ctx context.Context, root *Router, parent *http.Request, sub BatchRequest) BatchResponse {
+	req, err := http.NewRequestWithContext(ctx, sub.Method, sub.Path, bytes.NewReader(sub.Body))
+	if err != nil {
+		return batchError(http.StatusBadRequest, err.Error())
+	}
+	if req.URL.Scheme != "" || req.URL.Host != "" || !strings.HasPrefix(req.URL.Path, "/") {
+		return batchError(http.StatusBadRequest, "sub-request path must be an absolute path without host")
+	}
+	req.RemoteAddr = parent.RemoteAddr
+	req.Host = parent.Host
+	for _, k := range batchSharedHeaders {
+		if v := parent.Header.Values(k); len(v) > 0 {
+			req.Header[http.CanonicalHeaderKey(k)] = v
+		}
+	}
+	if len(sub.Body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range sub.Headers {
+		req.Header.Set(k, v)
+	}
+
+	rec := newResponseRecorder()
+	start := time.Now()
+	root.ServeHTTP(rec, req)
+	duration := time.Since(start)
+
+	resp := BatchResponse{
+		Status:     rec.status,
+		Headers:    make(map[string]string, len(rec.header)),
+		DurationMs: float64(duration.Microseconds()) / 1000,
+	}
+	for k := range rec.header {
+		resp.Headers[k] = rec.header.Get(k)
+	}
+	if body := bytes.TrimSpace(rec.body.Bytes()); len(body) > 0 {
+		if json.Valid(body) {
+			resp.Body = body
+		} else {
+			resp.Body, _ = json.Marshal(string(body))
+		}
+	}
+	return resp
+}
+
+// batchError builds a sub-response for a sub-request that was not dispatched.
+func batchError(status int, message string) BatchResponse {
+	body, _ := json.Marshal(message)
+	return BatchResponse{Status: status, Body: body}
+}
+
+// responseRecorder is an in-memory http.ResponseWriter used to run requests
+// through the router without a connection.
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: make(http.Header), status: http.StatusOK}
+}
+
+func (rec *responseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.status = status
+	rec.wroteHeader = true
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.body.Write(b)
+}
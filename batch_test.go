@@ -0,0 +1,149 @@
+package shttp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRouterBatch(t *testing.T) {
+	router := NewRouter()
+	router.GET("/users/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "application/json")
+		return json.NewEncoder(w).Encode(map[string]string{"id": PathValue(r, "id"), "auth": r.Header.Get("Authorization")})
+	})
+	router.POST("/echo", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+		return nil
+	})
+	router.GET("/text", simpleHandler("plain"))
+	router.POST("/headers", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return JSON(w, http.StatusOK, []string{r.Header.Get("Authorization"), r.Header.Get("If-Match"), r.Header.Get("Content-Encoding")})
+	})
+	router.Batch("/batch")
+
+	body := `[
+		{"method": "GET", "path": "/users/7"},
+		{"method": "POST", "path": "/echo", "body": {"x": 1}},
+		{"path": "/text"},
+		{"method": "GET", "path": "/missing"},
+		{"method": "POST", "path": "/batch", "body": []},
+		{"method": "POST", "path": "/batch?x=1", "body": []},
+		{"method": "POST", "path": "//batch/", "body": []},
+		{"method": "GET", "path": "http://other.example/users/7"},
+		{"method": "POST", "path": "/headers", "body": {}}
+	]`
+	req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("If-Match", `"v1"`)
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", w.Code, w.Body.String())
+	}
+	var got []BatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+
+	tests := []struct {
+		status int
+		body   string
+	}{
+		{http.StatusOK, `{"auth":"Bearer token","id":"7"}`},
+		{http.StatusOK, `{"x":1}`},
+		{http.StatusOK, `"plain"`},
+		{http.StatusNotFound, `"404 page not found"`},
+		{http.StatusBadRequest, `"nested batches are not allowed"`},
+		{http.StatusBadRequest, `"nested batches are not allowed"`},
+		{http.StatusBadRequest, `"sub-request path must be an absolute path without host"`},
+		{http.StatusBadRequest, `"sub-request path must be an absolute path without host"`},
+		{http.StatusOK, `["Bearer token","",""]`},
+	}
+	if len(got) != len(tests) {
+		t.Fatalf("got %d responses, want %d", len(got), len(tests))
+	}
+	for i, tt := range tests {
+		if got[i].Status != tt.status || string(got[i].Body) != tt.body {
+			t.Errorf("response %d = %d %s, want %d %s", i, got[i].Status, got[i].Body, tt.status, tt.body)
+		}
+	}
+
+	timing := w.Header().Get("Server-Timing")
+	if !strings.HasPrefix(timing, `batch-0;desc="GET /users/7";dur=`) || !strings.Contains(timing, `batch-2;desc="GET /text"`) {
+		t.Errorf("Server-Timing = %q", timing)
+	}
+}
+
+func TestRouterBatchTiming(t *testing.T) {
+	router := NewRouter()
+	router.GET("/slow", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+	router.GET("/fast", simpleHandler("ok"))
+	router.Batch("/batch")
+
+	req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`[{"path": "/slow"}, {"path": "/fast"}]`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var got []BatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if got[0].DurationMs < 20 {
+		t.Errorf("slow sub-request duration = %.3fms, want >= 20ms", got[0].DurationMs)
+	}
+	if got[1].DurationMs >= got[0].DurationMs {
+		t.Errorf("fast sub-request duration %.3fms not below slow %.3fms", got[1].DurationMs, got[0].DurationMs)
+	}
+}
+
+func TestRouterBatchLimits(t *testing.T) {
+	var inFlight, peak atomic.Int64
+	router := NewRouter()
+	router.GET("/slow", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	})
+	router.BatchWithOptions("/batch", BatchOptions{MaxRequests: 6, MaxConcurrency: 2})
+
+	serve := func(n int) int {
+		subs := make([]string, n)
+		for i := range subs {
+			subs[i] = `{"path": "/slow"}`
+		}
+		req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader("["+strings.Join(subs, ",")+"]"))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := serve(6); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("peak concurrency = %d, want <= 2", p)
+	}
+	if code := serve(7); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized batch status = %d, want 413", code)
+	}
+}
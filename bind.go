@@ -0,0 +1,177 @@
+package shttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// BindOptions controls how strictly BindWithOptions decodes JSON bodies.
+type BindOptions struct {
+	// Reject objects with fields the target type does not declare
+	DisallowUnknownFields bool
+
+	// Reject bodies with anything but whitespace after the JSON value
+	RejectTrailingData bool
+
+	// Reject requests whose Content-Type is not application/json with 415
+	RequireJSONContentType bool
+
+	// Maximum nesting depth of objects and arrays (0 for no limit). The body
+	// is checked before decoding, so deeply nested input is refused without
+	// being unmarshalled.
+	MaxDepth int
+
+	// Maximum body size in bytes (0 for no limit); larger bodies are
+	// refused with 413
+	MaxBodySize int64
+}
+
+// defaultMaxBodySize is the body size limit of StrictBindOptions.
+const defaultMaxBodySize = 1 << 20
+
+// StrictBindOptions returns the recommended options for untrusted input:
+// every check enabled, nesting capped at 32 levels and bodies at 1 MiB.
+func StrictBindOptions() BindOptions {
+	return BindOptions{
+		DisallowUnknownFields:  true,
+		RejectTrailingData:     true,
+		RequireJSONContentType: true,
+		MaxDepth:               32,
+		MaxBodySize:            defaultMaxBodySize,
+	}
+}
+
+// Validator is implemented by request types that check their own values
+// once decoded; see Bind.
+type Validator interface {
+	Validate() error
+}
+
+// Decode decodes the JSON request body into v with StrictBindOptions: the
+// Content-Type must be application/json, the body at most 1 MiB, and
+// unknown fields are rejected.
+func Decode(r *http.Request, v any) error {
+	return BindWithOptions(r, v, StrictBindOptions())
+}
+
+// Bind decodes the JSON request body into v like Decode, then validates v
+// when it implements Validator:
+//
+//	func (in CreateUser) Validate() error {
+//		if in.Email == "" {
+//			return errors.New("email is required")
+//		}
+//		return nil
+//	}
+//
+// Validation errors are reported as 422, unless Validate returns an
+// HTTPError.
+func Bind(r *http.Request, v any) error {
+	if err := Decode(r, v); err != nil {
+		return err
+	}
+	return Validate(v)
+}
+
+// Validate calls v's Validate method when v implements Validator, wrapping
+// a failure in a 422 HTTPError unless it already is an HTTPError.
+func Validate(v any) error {
+	validator, ok := v.(Validator)
+	if !ok {
+		return nil
+	}
+	if err := validator.Validate(); err != nil {
+		var httpErr HTTPError
+		if errors.As(err, &httpErr) {
+			return err
+		}
+		return NewHTTPError(http.StatusUnprocessableEntity, "invalid request: "+err.Error())
+	}
+	return nil
+}
+
+// BindWithOptions decodes the JSON request body into v, applying the checks
+// enabled in opts. Malformed or refused input is reported as an HTTPError
+// (400, or 415 for the content type) that handlers can return as is.
+func BindWithOptions(r *http.Request, v any, opts BindOptions) error {
+	if opts.RequireJSONContentType {
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			return NewHTTPError(http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+		}
+	}
+
+	var body io.Reader = r.Body
+	if opts.MaxBodySize > 0 {
+		errTooLarge := NewHTTPError(http.StatusRequestEntityTooLarge, "request body too large")
+		if r.ContentLength > opts.MaxBodySize {
+			return errTooLarge
+		}
+		body = &limitReader{r: body, remaining: opts.MaxBodySize, err: errTooLarge}
+	}
+	if opts.MaxDepth > 0 {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		if depth := jsonDepth(data); depth > opts.MaxDepth {
+			return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid JSON body: nesting depth exceeds %d", opts.MaxDepth))
+		}
+		body = bytes.NewReader(data)
+	}
+
+	dec := json.NewDecoder(body)
+	if opts.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return NewHTTPError(http.StatusBadRequest, "invalid JSON body: empty body")
+		}
+		var httpErr HTTPError
+		if errors.As(err, &httpErr) {
+			// Errors raised by the body itself (e.g. size or checksum limits)
+			return err
+		}
+		return NewHTTPError(http.StatusBadRequest, "invalid JSON body: "+err.Error())
+	}
+	if opts.RejectTrailingData {
+		if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+			return NewHTTPError(http.StatusBadRequest, "invalid JSON body: unexpected data after the JSON value")
+		}
+	}
+	return nil
+}
+
+// jsonDepth returns the maximum nesting depth of objects and arrays in data,
+// ignoring brackets inside strings. It does not validate the JSON.
+func jsonDepth(data []byte) int {
+	depth, maxDepth := 0, 0
+	inString, escaped := false, false
+	for _, c := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			switch c {
+			case '\\':
+				escaped = true
+			case '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+			maxDepth = max(maxDepth, depth)
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+	return maxDepth
+}
@@ -0,0 +1,104 @@
+package shttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBindWithOptions(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		Tags []any  `json:"tags"`
+	}
+	strict := StrictBindOptions()
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		opts        BindOptions
+		wantStatus  int
+	}{
+		{name: "Lenient accepts unknown fields", body: `{"name":"a","admin":true}`, wantStatus: http.StatusOK},
+		{name: "Strict valid body", body: `{"name":"a","tags":[1,[2]]}`, contentType: "application/json; charset=utf-8", opts: strict, wantStatus: http.StatusOK},
+		{name: "Unknown field", body: `{"name":"a","admin":true}`, contentType: "application/json", opts: strict, wantStatus: http.StatusBadRequest},
+		{name: "Trailing data", body: `{"name":"a"} {"name":"b"}`, contentType: "application/json", opts: strict, wantStatus: http.StatusBadRequest},
+		{name: "Trailing whitespace", body: "{\"name\":\"a\"}\n", contentType: "application/json", opts: strict, wantStatus: http.StatusOK},
+		{name: "Wrong content type", body: `{"name":"a"}`, contentType: "text/plain", opts: strict, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "Too deep", body: `{"tags":` + strings.Repeat("[", 40) + strings.Repeat("]", 40) + `}`, contentType: "application/json", opts: strict, wantStatus: http.StatusBadRequest},
+		{name: "Brackets in strings do not count", body: `{"name":"` + strings.Repeat("[", 40) + `"}`, contentType: "application/json", opts: strict, wantStatus: http.StatusOK},
+		{name: "Empty body", body: "", wantStatus: http.StatusBadRequest},
+		{name: "Malformed", body: `{"name":`, wantStatus: http.StatusBadRequest},
+		{name: "Too large", body: `{"name":"` + strings.Repeat("a", 64) + `"}`, contentType: "application/json", opts: BindOptions{MaxBodySize: 32}, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			var u user
+			status := http.StatusOK
+			if err := BindWithOptions(req, &u, tt.opts); err != nil {
+				status = statusFromError(err)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+type signup struct {
+	Email string `json:"email"`
+	Age   int    `json:"age"`
+}
+
+func (s signup) Validate() error {
+	if s.Email == "" {
+		return errors.New("email is required")
+	}
+	if s.Age < 13 {
+		return NewHTTPError(http.StatusForbidden, "too young")
+	}
+	return nil
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		wantStatus  int
+	}{
+		{"valid", `{"email":"a@example.com","age":30}`, "application/json", http.StatusOK},
+		{"validation error", `{"age":30}`, "application/json", http.StatusUnprocessableEntity},
+		{"validation HTTPError kept", `{"email":"a@example.com","age":9}`, "application/json", http.StatusForbidden},
+		{"unknown field", `{"email":"a@example.com","age":30,"admin":true}`, "application/json", http.StatusBadRequest},
+		{"missing content type", `{"email":"a@example.com","age":30}`, "", http.StatusUnsupportedMediaType},
+		{"too large", `{"email":"` + strings.Repeat("a", defaultMaxBodySize) + `"}`, "application/json", http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			var in signup
+			status := http.StatusOK
+			if err := Bind(req, &in); err != nil {
+				status = statusFromError(err)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
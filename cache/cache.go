@@ -0,0 +1,225 @@
+// Package cache is a small in-process key-value cache with per-entry
+// expiry, least-recently-used eviction and deduplicated loading. It backs
+// shttp.CachedJSON and can be used by handlers directly:
+//
+//	users := cache.New[string, *User](cache.Options{MaxEntries: 10000, TTL: time.Minute})
+//
+//	user, err := users.GetOrLoad(ctx, id, func(ctx context.Context) (*User, error) {
+//		return db.LoadUser(ctx, id)
+//	})
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// sweepInterval is the number of writes between two sweeps of the expired
+// entries of an unbounded cache; bounded caches evict them as they fill up.
+const sweepInterval = 1024
+
+// Options configures a Cache.
+type Options struct {
+	// Maximum number of entries; the least recently used entry is evicted
+	// to make room for a new one (0 for no limit)
+	MaxEntries int
+
+	// Lifetime of the entries stored with Set and GetOrLoad (0 for no
+	// expiry); SetWithTTL and GetOrLoadWithTTL override it
+	TTL time.Duration
+}
+
+// Cache is a key-value cache safe for concurrent use. The zero value is not
+// usable; create caches with New.
+type Cache[K comparable, V any] struct {
+	opts Options
+
+	mu      sync.Mutex
+	entries map[K]*list.Element
+	// Entries by recency of use, most recent first
+	lru    *list.List
+	writes int
+
+	// Loads in flight, by key
+	calls map[K]*call[V]
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// call is a GetOrLoad in flight; its waiters read val and err once done is
+// closed.
+type call[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
+}
+
+// New creates an empty Cache.
+func New[K comparable, V any](opts Options) *Cache[K, V] {
+	return &Cache[K, V]{
+		opts:    opts,
+		entries: make(map[K]*list.Element),
+		lru:     list.New(),
+		calls:   make(map[K]*call[V]),
+	}
+}
+
+// Get returns the value stored for key, if it has not expired.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(key, time.Now())
+}
+
+// get looks key up, dropping it if it expired; the caller holds the lock.
+func (c *Cache[K, V]) get(key K, now time.Time) (V, bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	e := elem.Value.(*entry[K, V])
+	if !e.expires.IsZero() && now.After(e.expires) {
+		c.remove(elem)
+		var zero V
+		return zero, false
+	}
+	c.lru.MoveToFront(elem)
+	return e.value, true
+}
+
+// Set stores value for key with the cache's TTL.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.opts.TTL)
+}
+
+// SetWithTTL stores value for key for ttl (0 for no expiry).
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, value, ttl, time.Now())
+}
+
+// set stores an entry, evicting or sweeping as needed; the caller holds the
+// lock.
+func (c *Cache[K, V]) set(key K, value V, ttl time.Duration, now time.Time) {
+	var expires time.Time
+	if ttl > 0 {
+		expires = now.Add(ttl)
+	}
+	if elem, ok := c.entries[key]; ok {
+		e := elem.Value.(*entry[K, V])
+		e.value, e.expires = value, expires
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	if c.writes++; c.opts.MaxEntries <= 0 && c.writes%sweepInterval == 0 {
+		c.sweep(now)
+	}
+	if c.opts.MaxEntries > 0 && c.lru.Len() >= c.opts.MaxEntries {
+		c.remove(c.lru.Back())
+	}
+	c.entries[key] = c.lru.PushFront(&entry[K, V]{key: key, value: value, expires: expires})
+}
+
+// sweep drops the expired entries; the caller holds the lock.
+func (c *Cache[K, V]) sweep(now time.Time) {
+	for _, elem := range c.entries {
+		if e := elem.Value.(*entry[K, V]); !e.expires.IsZero() && now.After(e.expires) {
+			c.remove(elem)
+		}
+	}
+}
+
+func (c *Cache[K, V]) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*entry[K, V]).key)
+}
+
+// Delete removes keys from the cache. Loads in flight for them still store
+// their result.
+func (c *Cache[K, V]) Delete(keys ...K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if elem, ok := c.entries[key]; ok {
+			c.remove(elem)
+		}
+	}
+}
+
+// Purge removes every entry.
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[K]*list.Element)
+	c.lru.Init()
+}
+
+// Len returns the number of entries, including expired ones not dropped yet.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// GetOrLoad returns the value stored for key or, on a miss, loads it with
+// load and stores it with the cache's TTL. Concurrent misses for the same
+// key share a single load, run with the context of the first caller; the
+// others wait for it until their own ctx is done. Errors are returned to
+// every waiting caller and not cached.
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, load func(ctx context.Context) (V, error)) (V, error) {
+	return c.GetOrLoadWithTTL(ctx, key, c.opts.TTL, load)
+}
+
+// GetOrLoadWithTTL is GetOrLoad storing the loaded value for ttl.
+func (c *Cache[K, V]) GetOrLoadWithTTL(ctx context.Context, key K, ttl time.Duration, load func(ctx context.Context) (V, error)) (V, error) {
+	c.mu.Lock()
+	if v, ok := c.get(key, time.Now()); ok {
+		c.mu.Unlock()
+		return v, nil
+	}
+	if cl, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-cl.done:
+			return cl.val, cl.err
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		}
+	}
+	cl := &call[V]{done: make(chan struct{})}
+	c.calls[key] = cl
+	c.mu.Unlock()
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			cl.err = fmt.Errorf("cache: load of %v panicked: %v", key, rec)
+			c.finish(key, cl, ttl, false)
+			panic(rec)
+		}
+	}()
+	cl.val, cl.err = load(ctx)
+	c.finish(key, cl, ttl, cl.err == nil)
+	return cl.val, cl.err
+}
+
+// finish stores the result of a load if ok and releases its waiters.
+func (c *Cache[K, V]) finish(key K, cl *call[V], ttl time.Duration, ok bool) {
+	c.mu.Lock()
+	if ok {
+		c.set(key, cl.val, ttl, time.Now())
+	}
+	delete(c.calls, key)
+	c.mu.Unlock()
+	close(cl.done)
+}
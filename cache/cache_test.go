@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheTTL(t *testing.T) {
+	c := New[string, int](Options{TTL: 20 * time.Millisecond})
+	c.Set("short", 1)
+	c.SetWithTTL("forever", 2, 0)
+
+	if v, ok := c.Get("short"); !ok || v != 1 {
+		t.Errorf("Get(short) = %d, %t; want 1, true", v, ok)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := c.Get("short"); ok {
+		t.Error("Get(short) found an expired entry")
+	}
+	if v, ok := c.Get("forever"); !ok || v != 2 {
+		t.Errorf("Get(forever) = %d, %t; want 2, true", v, ok)
+	}
+	if got := c.Len(); got != 1 {
+		t.Errorf("Len() = %d after the expired entry was read, want 1", got)
+	}
+}
+
+func TestCacheLRU(t *testing.T) {
+	c := New[string, int](Options{MaxEntries: 2})
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a") // b is now the least recently used
+	c.Set("c", 3)
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"c", true},
+	}
+	for _, tt := range tests {
+		if _, ok := c.Get(tt.key); ok != tt.want {
+			t.Errorf("Get(%s) found = %t, want %t", tt.key, ok, tt.want)
+		}
+	}
+
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok || c.Len() != 1 {
+		t.Errorf("after Delete: found a = %t, Len = %d", ok, c.Len())
+	}
+	c.Purge()
+	if c.Len() != 0 {
+		t.Errorf("Len() = %d after Purge, want 0", c.Len())
+	}
+}
+
+func TestCacheGetOrLoad(t *testing.T) {
+	c := New[string, string](Options{})
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (string, error) {
+		loads.Add(1)
+		<-release
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make(chan string, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad(context.Background(), "key", load)
+			if err != nil {
+				t.Errorf("GetOrLoad() error = %v", err)
+			}
+			results <- v
+		}()
+	}
+	// Let the callers pile up on the load in flight
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for v := range results {
+		if v != "value" {
+			t.Errorf("GetOrLoad() = %q, want value", v)
+		}
+	}
+	if got := loads.Load(); got != 1 {
+		t.Errorf("load ran %d times, want 1", got)
+	}
+}
+
+func TestCacheGetOrLoadErrors(t *testing.T) {
+	c := New[string, int](Options{})
+	errDown := errors.New("database down")
+	if _, err := c.GetOrLoad(context.Background(), "k", func(ctx context.Context) (int, error) {
+		return 0, errDown
+	}); !errors.Is(err, errDown) {
+		t.Errorf("GetOrLoad() error = %v, want the load error", err)
+	}
+	if _, ok := c.Get("k"); ok {
+		t.Error("failed load was cached")
+	}
+
+	// A waiter gives up when its context is done, the load goes on
+	release := make(chan struct{})
+	go c.GetOrLoad(context.Background(), "slow", func(ctx context.Context) (int, error) {
+		<-release
+		return 7, nil
+	})
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetOrLoad(ctx, "slow", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waiting GetOrLoad() error = %v, want DeadlineExceeded", err)
+	}
+	close(release)
+	time.Sleep(10 * time.Millisecond)
+	if v, ok := c.Get("slow"); !ok || v != 7 {
+		t.Errorf("Get(slow) = %d, %t; want 7, true", v, ok)
+	}
+}
@@ -0,0 +1,82 @@
+package shttp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andres-vara/shttp/cache"
+)
+
+// jsonCache is the in-process cache shared by CachedJSON.
+var jsonCache = cache.New[string, renderedJSON](cache.Options{})
+
+type renderedJSON struct {
+	body    []byte
+	etag    string
+	expires time.Time
+}
+
+// InvalidateCachedJSON removes the CachedJSON entries for keys, so the next
+// request renders them again.
+func InvalidateCachedJSON(keys ...string) {
+	jsonCache.Delete(keys...)
+}
+
+// CachedJSON writes the JSON encoding of fn's result with multi-layer
+// caching: the encoded body is kept in process under key for ttl, the
+// response carries a strong ETag and "Cache-Control: public, max-age=ttl"
+// for browsers and CDNs, and requests whose If-None-Match matches get a 304
+// without a body. fn only runs on a cache miss, once for concurrent misses
+// of the same key; its errors are returned unchanged and nothing is cached.
+func CachedJSON[T any](ctx context.Context, w http.ResponseWriter, r *http.Request, key string, ttl time.Duration, fn func(ctx context.Context) (T, error)) error {
+	now := time.Now()
+	entry, err := jsonCache.GetOrLoadWithTTL(ctx, key, ttl, func(ctx context.Context) (renderedJSON, error) {
+		v, err := fn(ctx)
+		if err != nil {
+			return renderedJSON{}, err
+		}
+		body, err := json.Marshal(v)
+		if err != nil {
+			return renderedJSON{}, err
+		}
+		sum := sha256.Sum256(body)
+		return renderedJSON{body: body, etag: `"` + hex.EncodeToString(sum[:8]) + `"`, expires: now.Add(ttl)}, nil
+	})
+	if err != nil {
+		return err
+	}
+	h := w.Header()
+	h.Set("ETag", entry.etag)
+	// Never promise more freshness than the in-process copy has left.
+	h.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(entry.expires.Sub(now)/time.Second)))
+	if etagMatches(r.Header.Get("If-None-Match"), entry.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	h.Set("Content-Type", "application/json")
+	_, err = w.Write(entry.body)
+	return err
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using
+// the weak comparison RFC 9110 requires for If-None-Match.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
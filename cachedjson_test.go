@@ -0,0 +1,86 @@
+package shttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCachedJSON(t *testing.T) {
+	const key = "test:cached-json"
+	defer InvalidateCachedJSON(key)
+
+	calls := 0
+	render := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		err := CachedJSON(req.Context(), w, req, key, time.Minute, func(ctx context.Context) ([]string, error) {
+			calls++
+			return []string{"a", "b"}, nil
+		})
+		if err != nil {
+			t.Fatalf("CachedJSON: %v", err)
+		}
+		return w
+	}
+
+	first := render("")
+	if first.Code != http.StatusOK || first.Body.String() != `["a","b"]` {
+		t.Fatalf("first response = %d %q", first.Code, first.Body.String())
+	}
+	etag := first.Header().Get("ETag")
+	if etag == "" || first.Header().Get("Cache-Control") != "public, max-age=60" {
+		t.Errorf("headers = ETag %q, Cache-Control %q", etag, first.Header().Get("Cache-Control"))
+	}
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		wantStatus  int
+	}{
+		{name: "Cached body", wantStatus: http.StatusOK},
+		{name: "Matching ETag", ifNoneMatch: etag, wantStatus: http.StatusNotModified},
+		{name: "Weak matching ETag in list", ifNoneMatch: `"other", W/` + etag, wantStatus: http.StatusNotModified},
+		{name: "Different ETag", ifNoneMatch: `"other"`, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := render(tt.ifNoneMatch)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+	if calls != 1 {
+		t.Errorf("fn called %d times, want 1", calls)
+	}
+
+	InvalidateCachedJSON(key)
+	render("")
+	if calls != 2 {
+		t.Errorf("fn called %d times after invalidation, want 2", calls)
+	}
+}
+
+func TestCachedJSONError(t *testing.T) {
+	const key = "test:cached-json-error"
+	defer InvalidateCachedJSON(key)
+
+	wantErr := errors.New("boom")
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	err := CachedJSON(req.Context(), httptest.NewRecorder(), req, key, time.Minute, func(ctx context.Context) (int, error) {
+		return 0, wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("err = %v, want %v", err, wantErr)
+	}
+	if _, ok := jsonCache.Get(key); ok {
+		t.Error("failed render was cached")
+	}
+}
@@ -0,0 +1,120 @@
+package shttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SetSurrogateControl sets the Surrogate-Control header, which CDNs such as
+// Fastly honor instead of Cache-Control and strip before the response
+// reaches the client. Extra directives (e.g. "stale-while-revalidate=60")
+// are appended after max-age.
+func SetSurrogateControl(w http.ResponseWriter, maxAge time.Duration, directives ...string) {
+	value := "max-age=" + strconv.Itoa(int(maxAge/time.Second))
+	for _, d := range directives {
+		value += ", " + d
+	}
+	w.Header().Set("Surrogate-Control", value)
+}
+
+// AddSurrogateKeys tags the response with cache keys so it can later be
+// invalidated with Purger.PurgeKeys. Keys are added to both Surrogate-Key
+// (space separated, Fastly) and Cache-Tag (comma separated, Cloudflare and
+// Akamai); calling it several times accumulates keys.
+func AddSurrogateKeys(w http.ResponseWriter, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	h := w.Header()
+	if existing := h.Get("Surrogate-Key"); existing != "" {
+		h.Set("Surrogate-Key", existing+" "+strings.Join(keys, " "))
+		h.Set("Cache-Tag", h.Get("Cache-Tag")+","+strings.Join(keys, ","))
+		return
+	}
+	h.Set("Surrogate-Key", strings.Join(keys, " "))
+	h.Set("Cache-Tag", strings.Join(keys, ","))
+}
+
+// Purger invalidates cached content on a CDN.
+type Purger interface {
+	// PurgeKeys invalidates every response tagged with one of keys.
+	PurgeKeys(ctx context.Context, keys ...string) error
+
+	// PurgeURLs invalidates the cached responses for the given URLs.
+	PurgeURLs(ctx context.Context, urls ...string) error
+}
+
+// FastlyPurger purges content through the Fastly API.
+type FastlyPurger struct {
+	// Fastly service ID. Required.
+	ServiceID string
+
+	// API token with purge permission. Required.
+	Token string
+
+	// Use hard purges, which remove the content from the cache, instead of
+	// the default soft purges, which mark it stale so it can still be served
+	// while revalidating or when the origin fails.
+	Hard bool
+
+	// API base URL (default "https://api.fastly.com").
+	Endpoint string
+
+	// HTTP client used for API calls (default http.DefaultClient).
+	Client *http.Client
+}
+
+// PurgeKeys implements Purger using Fastly's batch surrogate key purge.
+func (p *FastlyPurger) PurgeKeys(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	endpoint := p.Endpoint
+	if endpoint == "" {
+		endpoint = "https://api.fastly.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/service/"+p.ServiceID+"/purge", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Surrogate-Key", strings.Join(keys, " "))
+	return p.do(req)
+}
+
+// PurgeURLs implements Purger by sending a PURGE request to each URL.
+func (p *FastlyPurger) PurgeURLs(ctx context.Context, urls ...string) error {
+	for _, u := range urls {
+		req, err := http.NewRequestWithContext(ctx, "PURGE", u, nil)
+		if err != nil {
+			return err
+		}
+		if err := p.do(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *FastlyPurger) do(req *http.Request) error {
+	req.Header.Set("Fastly-Key", p.Token)
+	if !p.Hard {
+		req.Header.Set("Fastly-Soft-Purge", "1")
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("purge %s: unexpected status %s", req.URL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package shttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSurrogateHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+	SetSurrogateControl(w, 5*time.Minute, "stale-while-revalidate=30")
+	AddSurrogateKeys(w, "product-1", "catalog")
+	AddSurrogateKeys(w, "home")
+
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"Surrogate-Control", "max-age=300, stale-while-revalidate=30"},
+		{"Surrogate-Key", "product-1 catalog home"},
+		{"Cache-Tag", "product-1,catalog,home"},
+	}
+	for _, tt := range tests {
+		if got := w.Header().Get(tt.header); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestFastlyPurger(t *testing.T) {
+	var got []*http.Request
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r)
+		if r.Header.Get("Fastly-Key") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer api.Close()
+
+	var p Purger = &FastlyPurger{ServiceID: "svc", Token: "token", Endpoint: api.URL}
+	if err := p.PurgeKeys(context.Background(), "a", "b"); err != nil {
+		t.Fatalf("PurgeKeys: %v", err)
+	}
+	if err := p.PurgeURLs(context.Background(), api.URL+"/page"); err != nil {
+		t.Fatalf("PurgeURLs: %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("got %d API calls, want 2", len(got))
+	}
+	if got[0].URL.Path != "/service/svc/purge" || got[0].Header.Get("Surrogate-Key") != "a b" {
+		t.Errorf("key purge = %s %q", got[0].URL.Path, got[0].Header.Get("Surrogate-Key"))
+	}
+	if got[1].Method != "PURGE" || got[1].Header.Get("Fastly-Soft-Purge") != "1" {
+		t.Errorf("URL purge = %s soft=%q", got[1].Method, got[1].Header.Get("Fastly-Soft-Purge"))
+	}
+
+	bad := &FastlyPurger{ServiceID: "svc", Token: "wrong", Endpoint: api.URL}
+	if err := bad.PurgeKeys(context.Background(), "a"); err == nil {
+		t.Error("expected error for rejected purge")
+	}
+}
@@ -0,0 +1,161 @@
+package shttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+)
+
+// ErrChecksumMismatch is returned from request body reads when the body does
+// not match the checksum announced in its headers.
+var ErrChecksumMismatch = NewHTTPError(http.StatusBadRequest, "request body checksum mismatch")
+
+// checksumHeaders maps the supported checksum headers to their hash. All
+// values are base64 encoded digests, the crc variants in big-endian order.
+var checksumHeaders = []struct {
+	header string
+	hash   func() hash.Hash
+}{
+	{"Content-MD5", md5.New},
+	{"X-Amz-Checksum-Sha256", sha256.New},
+	{"X-Amz-Checksum-Sha1", sha1.New},
+	{"X-Amz-Checksum-Crc32", func() hash.Hash { return crc32.NewIEEE() }},
+	{"X-Amz-Checksum-Crc32c", func() hash.Hash { return crc32.New(crc32.MakeTable(crc32.Castagnoli)) }},
+}
+
+// ErrChecksumNotVerified is returned by the handlers of ChecksumMiddleware
+// that did not read the whole streamed body, so its checksum could not be
+// verified.
+var ErrChecksumNotVerified = NewHTTPError(http.StatusBadRequest, "request body checksum not verified: the body was not fully read")
+
+// ChecksumOptions configures ChecksumMiddlewareWithOptions.
+type ChecksumOptions struct {
+	// Bodies with a Content-Length up to this size are read and verified
+	// before the handler runs (default 4 MiB; negative to always stream)
+	MaxBufferSize int64
+}
+
+// ChecksumMiddleware creates a middleware that verifies the request body
+// against the Content-MD5 or x-amz-checksum-* (sha256, sha1, crc32, crc32c)
+// header and rejects mismatches with 400 (see
+// ChecksumMiddlewareWithOptions).
+func ChecksumMiddleware() Middleware {
+	return ChecksumMiddlewareWithOptions(ChecksumOptions{})
+}
+
+// ChecksumMiddlewareWithOptions is like ChecksumMiddleware with explicit
+// options. Bodies up to MaxBufferSize are buffered and verified before the
+// handler runs, so handlers only ever see verified data. Larger bodies are
+// verified while the handler streams them: the final read returns
+// ErrChecksumMismatch instead of io.EOF on a mismatch. As handlers may
+// stop reading early or drop that error (json.Decoder does), the middleware
+// checks once the handler returns, reading a short unread remainder (up to
+// 64 KiB) itself. A mismatch fails the request with ErrChecksumMismatch; a
+// body left unverified fails it with ErrChecksumNotVerified only when the
+// handler succeeded, so errors returned before reading the body (e.g. 401
+// or 404) reach the client. When the handler already responded, the
+// failure is logged. A malformed checksum header is rejected up front.
+func ChecksumMiddlewareWithOptions(opts ChecksumOptions) Middleware {
+	if opts.MaxBufferSize == 0 {
+		opts.MaxBufferSize = 4 << 20
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			for _, c := range checksumHeaders {
+				value := r.Header.Get(c.header)
+				if value == "" {
+					continue
+				}
+				want, err := base64.StdEncoding.DecodeString(value)
+				if err != nil {
+					return NewHTTPError(http.StatusBadRequest, "invalid "+c.header+" header")
+				}
+				if r.ContentLength >= 0 && r.ContentLength <= opts.MaxBufferSize {
+					return verifyBuffered(ctx, w, r, next, c.hash(), want)
+				}
+				return verifyStreamed(ctx, w, r, next, c.hash(), want)
+			}
+			return next(ctx, w, r)
+		}
+	}
+}
+
+// verifyBuffered reads the whole body, verifies it and only then calls next.
+func verifyBuffered(ctx context.Context, w http.ResponseWriter, r *http.Request, next Handler, h hash.Hash, want []byte) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, r.ContentLength+1))
+	r.Body.Close()
+	if err != nil {
+		return err
+	}
+	h.Write(body)
+	if !bytes.Equal(h.Sum(nil), want) {
+		return ErrChecksumMismatch
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return next(ctx, w, r)
+}
+
+// maxChecksumRemainder bounds how much of a streamed body the handler left
+// unread is read to complete its verification.
+const maxChecksumRemainder = 64 << 10
+
+// verifyStreamed verifies the body as next reads it, and checks the outcome
+// once next returns.
+func verifyStreamed(ctx context.Context, w http.ResponseWriter, r *http.Request, next Handler, h hash.Hash, want []byte) error {
+	body := &checksumReader{ReadCloser: r.Body, hash: h, want: want}
+	r.Body = body
+	err := next(ctx, w, r)
+	if body.read > 0 && !body.verified && !body.mismatch {
+		// Decoders stop at the end of their value, before the read
+		// reaching EOF; finish short remainders to complete the check
+		io.CopyN(io.Discard, body, maxChecksumRemainder)
+	}
+	var failure error
+	switch {
+	case body.mismatch:
+		failure = ErrChecksumMismatch
+	case !body.verified && err == nil:
+		failure = ErrChecksumNotVerified
+	default:
+		return err
+	}
+	if logger := GetLogger(ctx); logger != nil {
+		logger.Errorf(ctx, "[http.checksum] %s %s: %v", r.Method, r.URL.Path, failure)
+	}
+	return failure
+}
+
+// checksumReader hashes the body as it is read and checks the digest at EOF.
+type checksumReader struct {
+	io.ReadCloser
+	hash hash.Hash
+	want []byte
+
+	// Bytes read so far
+	read int64
+
+	// Set once EOF was reached, with the outcome
+	verified bool
+	mismatch bool
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.read += int64(n)
+	c.hash.Write(p[:n])
+	if err == io.EOF {
+		if !bytes.Equal(c.hash.Sum(nil), c.want) {
+			c.mismatch = true
+			return n, ErrChecksumMismatch
+		}
+		c.verified = true
+	}
+	return n, err
+}
@@ -0,0 +1,123 @@
+package shttp
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChecksumMiddleware(t *testing.T) {
+	const body = "hello checksum"
+	md5Sum := md5.Sum([]byte(body))
+	sha256Sum := sha256.Sum256([]byte(body))
+	crc := binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE([]byte(body)))
+	b64 := base64.StdEncoding.EncodeToString
+
+	tests := []struct {
+		name       string
+		header     string
+		value      string
+		wantStatus int
+	}{
+		{name: "No checksum header", wantStatus: http.StatusOK},
+		{name: "Matching Content-MD5", header: "Content-MD5", value: b64(md5Sum[:]), wantStatus: http.StatusOK},
+		{name: "Matching sha256", header: "x-amz-checksum-sha256", value: b64(sha256Sum[:]), wantStatus: http.StatusOK},
+		{name: "Matching crc32", header: "x-amz-checksum-crc32", value: b64(crc), wantStatus: http.StatusOK},
+		{name: "Mismatching Content-MD5", header: "Content-MD5", value: b64(sha256Sum[:16]), wantStatus: http.StatusBadRequest},
+		{name: "Malformed header", header: "Content-MD5", value: "not base64!", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/object", strings.NewReader(body))
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+
+			var got []byte
+			handler := ChecksumMiddleware()(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				var err error
+				got, err = io.ReadAll(r.Body)
+				return err
+			})
+
+			status := http.StatusOK
+			if err := handler(req.Context(), httptest.NewRecorder(), req); err != nil {
+				status = statusFromError(err)
+			}
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if status == http.StatusOK && string(got) != body {
+				t.Errorf("body = %q, want %q", got, body)
+			}
+		})
+	}
+}
+
+func TestChecksumMiddlewareFailsClosed(t *testing.T) {
+	const body = `{"name": "report.csv"}`
+	sum := md5.Sum([]byte(body))
+	wrong := md5.Sum([]byte("other"))
+	b64 := base64.StdEncoding.EncodeToString
+
+	decode := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		// json.Decoder stops at the end of the value, before the read
+		// that reports the checksum
+		var v map[string]string
+		return json.NewDecoder(r.Body).Decode(&v)
+	}
+	ignore := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	}
+	unauthorized := NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	refuse := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return unauthorized
+	}
+
+	tests := []struct {
+		name       string
+		opts       ChecksumOptions
+		checksum   []byte
+		handler    Handler
+		wantCalled bool
+		wantErr    error
+	}{
+		{name: "Buffered match", checksum: sum[:], handler: decode, wantCalled: true},
+		{name: "Buffered mismatch rejected before the handler", checksum: wrong[:], handler: decode, wantErr: ErrChecksumMismatch},
+		{name: "Streamed match", opts: ChecksumOptions{MaxBufferSize: -1}, checksum: sum[:], handler: decode, wantCalled: true},
+		{name: "Streamed mismatch dropped by the decoder", opts: ChecksumOptions{MaxBufferSize: -1}, checksum: wrong[:], handler: decode, wantCalled: true, wantErr: ErrChecksumMismatch},
+		{name: "Streamed body left unread", opts: ChecksumOptions{MaxBufferSize: -1}, checksum: sum[:], handler: ignore, wantCalled: true, wantErr: ErrChecksumNotVerified},
+		{name: "Handler error before reading is kept", opts: ChecksumOptions{MaxBufferSize: -1}, checksum: sum[:], handler: refuse, wantCalled: true, wantErr: unauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/files", strings.NewReader(body))
+			req.Header.Set("Content-MD5", b64(tt.checksum))
+			called := false
+			handler := ChecksumMiddlewareWithOptions(tt.opts)(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				called = true
+				return tt.handler(ctx, w, r)
+			})
+
+			err := handler(req.Context(), httptest.NewRecorder(), req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("handler called = %t, want %t", called, tt.wantCalled)
+			}
+		})
+	}
+}
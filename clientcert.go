@@ -0,0 +1,92 @@
+package shttp
+
+import (
+	"context"
+	"crypto/x509"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// clientCertKey is the context key for the verified client certificate.
+type clientCertKey struct{}
+
+// ClientCert is the identity of a client authenticated with mutual TLS.
+type ClientCert struct {
+	// Distinguished name of the subject, e.g. "CN=billing,O=Example"
+	Subject string
+
+	// Common name of the subject
+	CommonName string
+
+	// Subject alternative names
+	DNSNames       []string
+	EmailAddresses []string
+	URIs           []string
+
+	// SPIFFE ID of an X.509 SVID, e.g. "spiffe://example.org/billing";
+	// empty when the certificate is not one
+	SPIFFEID string
+
+	// Serial number in decimal and expiry, e.g. for audit logs
+	SerialNumber string
+	NotAfter     time.Time
+
+	// The verified leaf certificate
+	Certificate *x509.Certificate
+}
+
+// GetClientCert returns the client certificate identity ClientCertMiddleware
+// stored in the context, or nil when the client presented no verified
+// certificate.
+func GetClientCert(ctx context.Context) *ClientCert {
+	cert, _ := ctx.Value(clientCertKey{}).(*ClientCert)
+	return cert
+}
+
+// ClientCertMiddleware makes the identity of the client certificate verified
+// during the TLS handshake (see Config.ClientCAs) available to handlers with
+// GetClientCert, and adds its subject to the canonical log line. Requests
+// without a verified certificate, over plain HTTP or with
+// tls.VerifyClientCertIfGiven, pass with no identity; authorization decisions
+// must treat a nil GetClientCert as unauthenticated.
+func ClientCertMiddleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			ctx, _ = withClientCert(ctx, r)
+			return next(ctx, w, r)
+		}
+	}
+}
+
+// withClientCert returns ctx carrying the identity of the verified client
+// certificate of r, if any, and that identity.
+func withClientCert(ctx context.Context, r *http.Request) (context.Context, *ClientCert) {
+	if cert := GetClientCert(ctx); cert != nil {
+		return ctx, cert
+	}
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ctx, nil
+	}
+	cert := newClientCert(r.TLS.VerifiedChains[0][0])
+	AddLogAttrs(ctx, slog.String("client_cert", cert.Subject))
+	return context.WithValue(ctx, clientCertKey{}, cert), cert
+}
+
+func newClientCert(cert *x509.Certificate) *ClientCert {
+	uris := make([]string, len(cert.URIs))
+	for i, uri := range cert.URIs {
+		uris[i] = uri.String()
+	}
+	return &ClientCert{
+		Subject:        cert.Subject.String(),
+		CommonName:     cert.Subject.CommonName,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		URIs:           uris,
+		SPIFFEID:       spiffeID(cert),
+		SerialNumber:   cert.SerialNumber.String(),
+		NotAfter:       cert.NotAfter,
+		Certificate:    cert,
+	}
+}
@@ -0,0 +1,153 @@
+package shttp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/andres-vara/slogr"
+)
+
+// testCA issues client certificates for mutual TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue returns a client certificate for commonName with the given URI SANs.
+func (ca *testCA) issue(t *testing.T, commonName string, uris ...string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"Example"}},
+		DNSNames:     []string{commonName + ".internal"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, u := range uris {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template.URIs = append(template.URIs, parsed)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// startMTLS starts a TLS server trusting ca for client certificates and
+// returns its base URL.
+func startMTLS(t *testing.T, server *Server) string {
+	t.Helper()
+	certFile, keyFile := writeTestCert(t)
+	go server.StartTLS(certFile, keyFile)
+	t.Cleanup(func() { server.Shutdown(context.Background()) })
+	waitFor(t, func() bool { return server.Addr() != nil })
+	return "https://" + server.Addr().String()
+}
+
+// mtlsClient returns a client presenting certs that trusts any server.
+func mtlsClient(certs ...tls.Certificate) *http.Client {
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		InsecureSkipVerify: true,
+		Certificates:       certs,
+	}}}
+}
+
+func TestClientCertMiddleware(t *testing.T) {
+	ca := newTestCA(t)
+	newServer := func(clientAuth tls.ClientAuthType) *Server {
+		server := New(context.Background(), &Config{
+			Addr:       "127.0.0.1:0",
+			Logger:     slogr.New(io.Discard, slogr.DefaultOptions()),
+			ClientCAs:  ca.pool,
+			ClientAuth: clientAuth,
+		})
+		server.Use(ClientCertMiddleware())
+		server.GET("/", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			cert := GetClientCert(ctx)
+			if cert == nil {
+				_, err := io.WriteString(w, "anonymous")
+				return err
+			}
+			_, err := fmt.Fprintf(w, "%s|%s|%v", cert.Subject, cert.CommonName, cert.DNSNames)
+			return err
+		})
+		return server
+	}
+	get := func(client *http.Client, url string) (string, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	t.Run("required by default", func(t *testing.T) {
+		url := startMTLS(t, newServer(tls.NoClientCert))
+		got, err := get(mtlsClient(ca.issue(t, "billing")), url)
+		if want := "CN=billing,O=Example|billing|[billing.internal]"; err != nil || got != want {
+			t.Errorf("with certificate: body = %q, error = %v; want %q", got, err, want)
+		}
+		if _, err := get(mtlsClient(), url); err == nil {
+			t.Error("without certificate: request succeeded, want a handshake failure")
+		}
+		if _, err := get(mtlsClient(newTestCA(t).issue(t, "intruder")), url); err == nil {
+			t.Error("with an untrusted certificate: request succeeded, want a handshake failure")
+		}
+	})
+
+	t.Run("optional", func(t *testing.T) {
+		url := startMTLS(t, newServer(tls.VerifyClientCertIfGiven))
+		if got, err := get(mtlsClient(), url); err != nil || got != "anonymous" {
+			t.Errorf("without certificate: body = %q, error = %v; want anonymous", got, err)
+		}
+	})
+}
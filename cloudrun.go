@@ -0,0 +1,65 @@
+package shttp
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/andres-vara/slogr"
+)
+
+const (
+	// cloudRunRequestTimeout is the platform's request timeout.
+	cloudRunRequestTimeout = 15 * time.Minute
+
+	// cloudRunTimeoutMargin is how long before the platform timeout handlers
+	// see their deadline, leaving time to answer with a proper error.
+	cloudRunTimeoutMargin = 10 * time.Second
+
+	// cloudRunIdleTimeout keeps idle connections open longer than the
+	// platform frontend does (600s), so the frontend closes them first and
+	// never reuses a connection the server is closing.
+	cloudRunIdleTimeout = 620 * time.Second
+)
+
+// concurrencyKey is the context key for the in-flight request count
+// observed when a request arrived.
+type concurrencyKey struct{}
+
+// RequestConcurrency returns the number of requests the server was serving
+// when the current request arrived, itself included. On platforms that
+// scale on concurrency (Cloud Run, Knative) logging it per request shows how
+// close the instance was to its limit. It returns 0 outside of a Server.
+func RequestConcurrency(ctx context.Context) int64 {
+	n, _ := ctx.Value(concurrencyKey{}).(int64)
+	return n
+}
+
+// CloudRunConfig returns a configuration tuned for Cloud Run and similar
+// scale-to-zero container platforms. It listens on $PORT (default 8080),
+// logs JSON to stdout for the platform's log collector, keeps idle
+// connections open longer than the platform frontend so they are reused
+// instead of reset, and matches the 15 minute request timeout: the write
+// timeout equals it and handlers get a deadline 10 seconds earlier, so they
+// can still answer before the platform cuts the request off.
+//
+// Readiness is immediate on these platforms: the container receives traffic
+// as soon as it listens, so keep Module.OnStart hooks short.
+func CloudRunConfig() *Config {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	opts := slogr.DefaultOptions()
+	opts.HandlerType = slogr.HandlerTypeJSON
+
+	config := DefaultConfig()
+	config.Addr = ":" + port
+	config.ReadTimeout = cloudRunRequestTimeout
+	config.WriteTimeout = cloudRunRequestTimeout
+	config.IdleTimeout = cloudRunIdleTimeout
+	config.DefaultRequestTimeout = cloudRunRequestTimeout - cloudRunTimeoutMargin
+	config.Logger = slogr.New(os.Stdout, opts)
+	return config
+}
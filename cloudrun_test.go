@@ -0,0 +1,69 @@
+package shttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCloudRunConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		port     string
+		wantAddr string
+	}{
+		{name: "PORT set", port: "9090", wantAddr: ":9090"},
+		{name: "PORT unset", port: "", wantAddr: ":8080"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PORT", tt.port)
+			config := CloudRunConfig()
+			if config.Addr != tt.wantAddr {
+				t.Errorf("Addr = %q, want %q", config.Addr, tt.wantAddr)
+			}
+			if config.WriteTimeout != 15*time.Minute || config.DefaultRequestTimeout >= config.WriteTimeout {
+				t.Errorf("WriteTimeout = %s, DefaultRequestTimeout = %s", config.WriteTimeout, config.DefaultRequestTimeout)
+			}
+			if config.IdleTimeout <= 600*time.Second {
+				t.Errorf("IdleTimeout = %s, want above the frontend keep-alive", config.IdleTimeout)
+			}
+		})
+	}
+}
+
+func TestRequestConcurrency(t *testing.T) {
+	server := New(context.Background(), &Config{Addr: "127.0.0.1:0"})
+	release := make(chan struct{})
+	entered := make(chan int64, 2)
+	server.GET("/work", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		entered <- RequestConcurrency(ctx)
+		<-release
+		return nil
+	})
+
+	done := make(chan struct{})
+	for range 2 {
+		go func() {
+			server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/work", nil))
+			done <- struct{}{}
+		}()
+	}
+	seen := map[int64]bool{<-entered: true, <-entered: true}
+	if !seen[1] || !seen[2] {
+		t.Errorf("RequestConcurrency values = %v, want 1 and 2", seen)
+	}
+	if got := server.Stats().InFlightRequests; got != 2 {
+		t.Errorf("InFlightRequests = %d, want 2", got)
+	}
+
+	close(release)
+	<-done
+	<-done
+	if got := server.Stats().InFlightRequests; got != 0 {
+		t.Errorf("InFlightRequests after completion = %d, want 0", got)
+	}
+}
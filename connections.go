@@ -0,0 +1,281 @@
+package shttp
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andres-vara/slogr"
+)
+
+// CloseGoingAway is the WebSocket close code (1001) telling clients the
+// server is going away, e.g. shutting down; they are expected to reconnect.
+const CloseGoingAway = 1001
+
+// connRegistryKey is the context key for the server's connection registry.
+type connRegistryKey struct{}
+
+// connRegistry tracks long-lived connections (hijacked WebSockets, SSE
+// streams) so Shutdown can ask them to close before the process exits.
+// http.Server.Shutdown neither waits for hijacked connections nor
+// interrupts streaming responses.
+type connRegistry struct {
+	logger *slogr.Logger
+
+	mu    sync.Mutex
+	conns map[string]*trackedConn
+
+	// Closed when the last tracked connection ends, created by wait. A
+	// WaitGroup would not do: connections may be tracked while waiting.
+	idle chan struct{}
+
+	// Context passed to goingAway callbacks, set once draining started
+	drainCtx context.Context
+}
+
+// ConnectionInfo describes a connection registered with TrackConnection.
+type ConnectionInfo struct {
+	// Unique ID, used to close the connection with CloseConnection
+	ID string `json:"id"`
+
+	// User ID (see GetUserID) and request ID of the request that opened it
+	User      string `json:"user,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+
+	// Route serving it, as "METHOD /path"
+	Route string `json:"route,omitempty"`
+
+	Started time.Time `json:"started"`
+}
+
+// trackedConn is a connection registered with TrackConnection.
+type trackedConn struct {
+	info      ConnectionInfo
+	goingAway func(ctx context.Context) error
+	notify    sync.Once
+	untrack   sync.Once
+}
+
+func newConnRegistry(logger *slogr.Logger) *connRegistry {
+	return &connRegistry{logger: logger, conns: make(map[string]*trackedConn)}
+}
+
+// TrackConnection registers a long-lived connection served by the request of
+// ctx, such as a hijacked WebSocket or an SSE stream, so that Shutdown closes
+// it gracefully instead of dropping it. When the server starts draining,
+// goingAway is called once with the shutdown context; it should send the
+// client a close notice (a WebSocket close frame with CloseGoingAway, see
+// WriteWebSocketClose, or a final SSE event) and make the handler return.
+// Shutdown then waits for the tracked connections until its context is done.
+// goingAway is also how CloseConnection kicks a single connection.
+//
+// The returned function must be called when the connection ends:
+//
+//	untrack := server.TrackConnection(ctx, func(ctx context.Context) error {
+//		return shttp.WriteWebSocketClose(conn, shttp.CloseGoingAway, "server shutting down")
+//	})
+//	defer untrack()
+//
+// Connections tracked once draining started are notified right away. The
+// user, request ID and route recorded in ConnectionInfo are taken from ctx.
+func (s *Server) TrackConnection(ctx context.Context, goingAway func(ctx context.Context) error) (untrack func()) {
+	return s.conns.track(ctx, goingAway)
+}
+
+// TrackConnection registers a connection with the Server handling the request
+// carried by ctx (see Server.TrackConnection). Outside of a Server it does
+// nothing.
+func TrackConnection(ctx context.Context, goingAway func(ctx context.Context) error) (untrack func()) {
+	if r, ok := ctx.Value(connRegistryKey{}).(*connRegistry); ok {
+		return r.track(ctx, goingAway)
+	}
+	return func() {}
+}
+
+func (r *connRegistry) track(ctx context.Context, goingAway func(ctx context.Context) error) func() {
+	c := &trackedConn{
+		info: ConnectionInfo{
+			ID:        generateRequestID(),
+			User:      GetUserID(ctx),
+			RequestID: GetRequestID(ctx),
+			Started:   time.Now(),
+		},
+		goingAway: goingAway,
+	}
+	if rt, ok := ctx.Value(routeKey{}).(*route); ok {
+		c.info.Route = rt.String()
+	}
+	r.mu.Lock()
+	r.conns[c.info.ID] = c
+	drainCtx := r.drainCtx
+	r.mu.Unlock()
+
+	if drainCtx != nil {
+		r.notify(drainCtx, c)
+	}
+	return func() {
+		c.untrack.Do(func() {
+			r.mu.Lock()
+			delete(r.conns, c.info.ID)
+			if len(r.conns) == 0 && r.idle != nil {
+				close(r.idle)
+				r.idle = nil
+			}
+			r.mu.Unlock()
+		})
+	}
+}
+
+// len returns the number of tracked connections.
+func (r *connRegistry) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.conns)
+}
+
+// goingAway starts draining: every tracked connection is notified
+// concurrently, so a slow client does not delay the others.
+func (r *connRegistry) goingAway(ctx context.Context) {
+	r.mu.Lock()
+	r.drainCtx = ctx
+	conns := make([]*trackedConn, 0, len(r.conns))
+	for _, c := range r.conns {
+		conns = append(conns, c)
+	}
+	r.mu.Unlock()
+
+	for _, c := range conns {
+		go r.notify(ctx, c)
+	}
+}
+
+func (r *connRegistry) notify(ctx context.Context, c *trackedConn) {
+	c.notify.Do(func() {
+		if err := c.goingAway(ctx); err != nil && r.logger != nil {
+			r.logger.Errorf(ctx, "[server.connections] Closing connection failed: %v", err)
+		}
+	})
+}
+
+// list returns the tracked connections, oldest first.
+func (r *connRegistry) list() []ConnectionInfo {
+	r.mu.Lock()
+	infos := make([]ConnectionInfo, 0, len(r.conns))
+	for _, c := range r.conns {
+		infos = append(infos, c.info)
+	}
+	r.mu.Unlock()
+	slices.SortFunc(infos, func(a, b ConnectionInfo) int {
+		if c := a.Started.Compare(b.Started); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return infos
+}
+
+// close asks the connection with the given ID to close. It does not wait
+// for the connection to end.
+func (r *connRegistry) close(ctx context.Context, id string) bool {
+	r.mu.Lock()
+	c, ok := r.conns[id]
+	r.mu.Unlock()
+	if ok {
+		r.notify(ctx, c)
+	}
+	return ok
+}
+
+// wait blocks until every tracked connection ended or ctx is done.
+// Connections tracked meanwhile, by requests still in flight, are waited for
+// too; they were notified right away.
+func (r *connRegistry) wait(ctx context.Context) error {
+	for {
+		r.mu.Lock()
+		if len(r.conns) == 0 {
+			r.mu.Unlock()
+			return nil
+		}
+		if r.idle == nil {
+			r.idle = make(chan struct{})
+		}
+		idle := r.idle
+		r.mu.Unlock()
+
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			open := r.len()
+			if r.logger != nil {
+				r.logger.Warn(ctx, "[server.shutdown] Connections still open after the shutdown deadline", "connections", open)
+			}
+			return fmt.Errorf("shttp: %d connections still open: %w", open, ctx.Err())
+		}
+	}
+}
+
+// Connections returns the connections registered with TrackConnection that
+// are still open, oldest first.
+func (s *Server) Connections() []ConnectionInfo {
+	return s.conns.list()
+}
+
+// CloseConnection asks the tracked connection with the given ID to close by
+// calling its goingAway callback, e.g. to kick a session. It reports whether
+// the connection was found; it does not wait for the connection to end.
+func (s *Server) CloseConnection(ctx context.Context, id string) bool {
+	return s.conns.close(ctx, id)
+}
+
+// ConnectionsHandler is an admin endpoint for the tracked connections:
+//
+//	GET    lists them as JSON, filtered by ?user= when given
+//	DELETE closes the one given by ?id=
+//
+// Mount it on a protected route:
+//
+//	admin.ANY("/debug/connections", server.ConnectionsHandler())
+func (s *Server) ConnectionsHandler() Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		switch r.Method {
+		case http.MethodGet:
+			conns := s.Connections()
+			if user := r.URL.Query().Get("user"); user != "" {
+				conns = slices.DeleteFunc(conns, func(c ConnectionInfo) bool { return c.User != user })
+			}
+			return JSON(w, http.StatusOK, conns)
+		case http.MethodDelete:
+			if !s.CloseConnection(ctx, r.URL.Query().Get("id")) {
+				return NewHTTPError(http.StatusNotFound, "connection not found")
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		default:
+			w.Header().Set("Allow", "GET, DELETE")
+			return NewHTTPError(http.StatusMethodNotAllowed, "method not allowed")
+		}
+	}
+}
+
+// WriteWebSocketClose writes a WebSocket close frame with code and reason
+// (truncated to fit a control frame) to w, typically a hijacked connection.
+// Server frames are not masked.
+func WriteWebSocketClose(w io.Writer, code int, reason string) error {
+	// Control frame payloads are limited to 125 bytes, 2 of which hold the code
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	frame := make([]byte, 4, 4+len(reason))
+	frame[0] = 0x88 // FIN + close opcode
+	frame[1] = byte(2 + len(reason))
+	binary.BigEndian.PutUint16(frame[2:], uint16(code))
+	frame = append(frame, reason...)
+	_, err := w.Write(frame)
+	return err
+}
@@ -0,0 +1,206 @@
+package shttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andres-vara/slogr"
+)
+
+func TestServerShutdownClosesTrackedConnections(t *testing.T) {
+	server := New(context.Background(), &Config{Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	server.GET("/events", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		closing := make(chan struct{})
+		defer TrackConnection(ctx, func(ctx context.Context) error {
+			close(closing)
+			return nil
+		})()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		<-closing
+		_, err := io.WriteString(w, "event: close\ndata: going away\n\n")
+		return err
+	}, NoTimeout())
+
+	ts := httptest.NewUnstartedServer(server)
+	ts.Config.BaseContext = server.HTTPServer().BaseContext
+	ts.Start()
+	defer ts.Close()
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(ts.URL + "/events")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		body <- string(data)
+	}()
+	waitFor(t, func() bool { return server.Stats().TrackedConnections == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if got := <-body; !strings.Contains(got, "event: close") {
+		t.Errorf("stream body = %q, want the close event", got)
+	}
+	if got := server.Stats().TrackedConnections; got != 0 {
+		t.Errorf("TrackedConnections = %d after Shutdown, want 0", got)
+	}
+}
+
+func TestServerShutdownConnectionDeadline(t *testing.T) {
+	server := New(context.Background(), &Config{Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+
+	notified := make(chan struct{})
+	untrack := server.TrackConnection(context.Background(), func(ctx context.Context) error {
+		close(notified)
+		return nil
+	})
+	defer untrack()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := server.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want DeadlineExceeded", err)
+	}
+	select {
+	case <-notified:
+	default:
+		t.Error("tracked connection was not notified")
+	}
+
+	// Connections tracked while draining are notified right away.
+	late := false
+	server.TrackConnection(context.Background(), func(ctx context.Context) error {
+		late = true
+		return nil
+	})
+	if !late {
+		t.Error("connection tracked while draining was not notified")
+	}
+}
+
+func TestConnRegistryTrackWhileWaiting(t *testing.T) {
+	r := newConnRegistry(nil)
+	notified := make(chan string, 2)
+	goingAway := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			notified <- name
+			return nil
+		}
+	}
+	untrackFirst := r.track(context.Background(), goingAway("first"))
+	r.goingAway(context.Background())
+
+	done := make(chan error, 1)
+	go func() { done <- r.wait(context.Background()) }()
+	// A request still in flight tracks a connection during the drain
+	untrackLate := r.track(context.Background(), goingAway("late"))
+	untrackFirst()
+	for range 2 {
+		select {
+		case <-notified:
+		case <-time.After(time.Second):
+			t.Fatal("tracked connection not notified")
+		}
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("wait() returned %v with a connection still open", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	untrackLate()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("wait() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("wait() did not return once every connection ended")
+	}
+}
+
+func TestTrackConnectionOutsideServer(t *testing.T) {
+	untrack := TrackConnection(context.Background(), func(ctx context.Context) error {
+		t.Error("goingAway called outside of a server")
+		return nil
+	})
+	untrack()
+}
+
+func TestWriteWebSocketClose(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteWebSocketClose(&buf, CloseGoingAway, "bye"); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x88, 5, 0x03, 0xe9, 'b', 'y', 'e'}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("frame = % x, want % x", buf.Bytes(), want)
+	}
+
+	buf.Reset()
+	WriteWebSocketClose(&buf, CloseGoingAway, strings.Repeat("x", 200))
+	if buf.Len() != 127 || buf.Bytes()[1] != 125 {
+		t.Errorf("long reason frame is %d bytes with length %d, want 127 and 125", buf.Len(), buf.Bytes()[1])
+	}
+}
+
+func TestServerConnectionsRegistry(t *testing.T) {
+	server := New(context.Background(), &Config{Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	server.ANY("/admin/connections", server.ConnectionsHandler())
+
+	kicked := make(map[string]bool)
+	track := func(user string) {
+		ctx := context.WithValue(context.Background(), UserIDKey, user)
+		ctx = context.WithValue(ctx, routeKey{}, &route{method: http.MethodGet, pattern: "/ws"})
+		server.TrackConnection(ctx, func(ctx context.Context) error {
+			kicked[user] = true
+			return nil
+		})
+	}
+	track("alice")
+	track("bob")
+
+	w := httptest.NewRecorder()
+	server.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/connections?user=bob", nil))
+	var conns []ConnectionInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &conns); err != nil {
+		t.Fatalf("decoding %s: %v", w.Body.String(), err)
+	}
+	if len(conns) != 1 || conns[0].User != "bob" || conns[0].Route != "GET /ws" || conns[0].Started.IsZero() {
+		t.Fatalf("connections of bob = %+v", conns)
+	}
+
+	w = httptest.NewRecorder()
+	server.Router().ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/connections?id="+conns[0].ID, nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("DELETE status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if !kicked["bob"] || kicked["alice"] {
+		t.Errorf("kicked = %v, want only bob", kicked)
+	}
+
+	w = httptest.NewRecorder()
+	server.Router().ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/connections?id=unknown", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("DELETE unknown status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if got := len(server.Connections()); got != 2 {
+		t.Errorf("len(Connections()) = %d, want 2 until the connections are untracked", got)
+	}
+}
@@ -0,0 +1,197 @@
+package shttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DegradationState is the state of a feature registered with Degraded.
+type DegradationState struct {
+	Feature  string `json:"feature"`
+	Degraded bool   `json:"degraded"`
+
+	// Why the feature is degraded: the reason given to Degrade, or the
+	// error of the failing dependency check
+	Reason string `json:"reason,omitempty"`
+
+	// When the feature entered its current state
+	Since time.Time `json:"since"`
+}
+
+// degradations holds the degraded-mode switches of a server's features.
+type degradations struct {
+	mu       sync.RWMutex
+	features map[string]*featureState
+}
+
+// featureState tracks the two independent causes of degradation.
+type featureState struct {
+	manual    string
+	degraded  bool
+	unhealthy error
+	since     time.Time
+}
+
+func newDegradations() *degradations {
+	return &degradations{features: make(map[string]*featureState)}
+}
+
+// Degraded returns a handler serving primary, or fallback while feature is
+// degraded, e.g. a read-only or cached variant of an endpoint. A feature is
+// degraded while switched with Degrade or while a dependency watched with
+// WatchDependency is failing, so incident response can flip features
+// centrally:
+//
+//	server.GET("/feed", server.Degraded("feed", liveFeed, cachedFeed))
+//	server.Degrade("feed", "database failover")
+func (s *Server) Degraded(feature string, primary, fallback Handler) Handler {
+	s.degradations.state(feature)
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if s.degradations.degraded(feature) {
+			return fallback(ctx, w, r)
+		}
+		return primary(ctx, w, r)
+	}
+}
+
+// Degrade switches feature to its fallback handlers until Restore is
+// called. It is safe to call while serving.
+func (s *Server) Degrade(feature, reason string) {
+	s.degradations.update(feature, func(st *featureState) { st.manual, st.degraded = reason, true })
+	s.logger.Warn(s.ctx, "[server.degraded] Feature degraded", "feature", feature, "reason", reason)
+}
+
+// Restore switches feature back to its primary handlers, unless a watched
+// dependency is failing.
+func (s *Server) Restore(feature string) {
+	s.degradations.update(feature, func(st *featureState) { st.manual, st.degraded = "", false })
+	s.logger.Info(s.ctx, "[server.degraded] Feature restored", "feature", feature)
+}
+
+// WatchDependency runs check every interval and keeps feature degraded while
+// it fails, e.g. with a database ping. It blocks until ctx is done, then
+// clears the degradation it caused. A non-positive interval is an error.
+func (s *Server) WatchDependency(ctx context.Context, feature string, interval time.Duration, check func(ctx context.Context) error) error {
+	if interval <= 0 {
+		return errors.New("shttp: the dependency check interval must be positive")
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer s.degradations.update(feature, func(st *featureState) { st.unhealthy = nil })
+	for {
+		err := check(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		changed := false
+		s.degradations.update(feature, func(st *featureState) {
+			changed = (err == nil) != (st.unhealthy == nil)
+			st.unhealthy = err
+		})
+		if changed && err != nil {
+			s.logger.Errorf(ctx, "[server.degraded] Dependency of %s failing, serving the fallback: %v", feature, err)
+		} else if changed {
+			s.logger.Infof(ctx, "[server.degraded] Dependency of %s healthy again", feature)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Degradations returns the state of every feature registered with Degraded,
+// Degrade or WatchDependency, by name.
+func (s *Server) Degradations() []DegradationState {
+	return s.degradations.list()
+}
+
+// DegradationsHandler is an admin endpoint for the degraded-mode switches:
+//
+//	GET    lists the features and their state as JSON
+//	POST   degrades ?feature= with the optional ?reason=
+//	DELETE restores ?feature=
+func (s *Server) DegradationsHandler() Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		feature := r.URL.Query().Get("feature")
+		switch r.Method {
+		case http.MethodGet:
+			return JSON(w, http.StatusOK, s.Degradations())
+		case http.MethodPost, http.MethodDelete:
+			if feature == "" {
+				return NewHTTPError(http.StatusBadRequest, "feature is required")
+			}
+			if r.Method == http.MethodPost {
+				s.Degrade(feature, r.URL.Query().Get("reason"))
+			} else {
+				s.Restore(feature)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			return NewHTTPError(http.StatusMethodNotAllowed, "method not allowed")
+		}
+	}
+}
+
+// state returns the state of feature, registering it on first use. The
+// caller must not hold the lock.
+func (d *degradations) state(feature string) *featureState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	st, ok := d.features[feature]
+	if !ok {
+		st = &featureState{since: time.Now()}
+		d.features[feature] = st
+	}
+	return st
+}
+
+// update changes the state of feature, recording when it flips.
+func (d *degradations) update(feature string, fn func(st *featureState)) {
+	st := d.state(feature)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	was := st.isDegraded()
+	fn(st)
+	if st.isDegraded() != was {
+		st.since = time.Now()
+	}
+}
+
+func (d *degradations) degraded(feature string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	st, ok := d.features[feature]
+	return ok && st.isDegraded()
+}
+
+func (d *degradations) list() []DegradationState {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	states := make([]DegradationState, 0, len(d.features))
+	for feature, st := range d.features {
+		state := DegradationState{Feature: feature, Degraded: st.isDegraded(), Since: st.since}
+		switch {
+		case st.degraded:
+			state.Reason = st.manual
+		case st.unhealthy != nil:
+			state.Reason = st.unhealthy.Error()
+		}
+		states = append(states, state)
+	}
+	slices.SortFunc(states, func(a, b DegradationState) int { return strings.Compare(a.Feature, b.Feature) })
+	return states
+}
+
+func (st *featureState) isDegraded() bool {
+	return st.degraded || st.unhealthy != nil
+}
@@ -0,0 +1,113 @@
+package shttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andres-vara/slogr"
+)
+
+func TestServerDegraded(t *testing.T) {
+	server := New(context.Background(), &Config{Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	text := func(body string) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			_, err := io.WriteString(w, body)
+			return err
+		}
+	}
+	server.GET("/feed", server.Degraded("feed", text("live"), text("cached")))
+	server.Handle(http.MethodGet, "/admin/degradations", server.DegradationsHandler())
+	server.Handle(http.MethodPost, "/admin/degradations", server.DegradationsHandler())
+	server.Handle(http.MethodDelete, "/admin/degradations", server.DegradationsHandler())
+
+	do := func(method, target string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+	feed := func(want string) {
+		t.Helper()
+		if got := do(http.MethodGet, "/feed").Body.String(); got != want {
+			t.Errorf("GET /feed = %q, want %q", got, want)
+		}
+	}
+
+	feed("live")
+	if rec := do(http.MethodPost, "/admin/degradations?feature=feed&reason=failover"); rec.Code != http.StatusNoContent {
+		t.Fatalf("POST status = %d, want 204", rec.Code)
+	}
+	feed("cached")
+
+	var states []DegradationState
+	if err := json.Unmarshal(do(http.MethodGet, "/admin/degradations").Body.Bytes(), &states); err != nil {
+		t.Fatalf("decoding states: %v", err)
+	}
+	if len(states) != 1 || states[0].Feature != "feed" || !states[0].Degraded || states[0].Reason != "failover" {
+		t.Errorf("states = %+v, want feed degraded for failover", states)
+	}
+
+	do(http.MethodDelete, "/admin/degradations?feature=feed")
+	feed("live")
+	if rec := do(http.MethodPost, "/admin/degradations"); rec.Code != http.StatusBadRequest {
+		t.Errorf("POST without feature status = %d, want 400", rec.Code)
+	}
+}
+
+func TestServerWatchDependency(t *testing.T) {
+	server := New(context.Background(), &Config{Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	var failing atomic.Bool
+	failing.Store(true)
+	check := func(ctx context.Context) error {
+		if failing.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.WatchDependency(ctx, "search", time.Millisecond, check) }()
+
+	degraded := func() bool {
+		states := server.Degradations()
+		return len(states) == 1 && states[0].Degraded
+	}
+	waitFor(t, degraded)
+	if got := server.Degradations()[0].Reason; got != "connection refused" {
+		t.Errorf("Reason = %q, want the check error", got)
+	}
+
+	// A manual degradation outlives the dependency recovering
+	server.Degrade("search", "reindexing")
+	failing.Store(false)
+	time.Sleep(10 * time.Millisecond)
+	if !degraded() {
+		t.Error("feature restored while manually degraded")
+	}
+	server.Restore("search")
+	if degraded() {
+		t.Error("feature still degraded after Restore with a healthy dependency")
+	}
+
+	failing.Store(true)
+	waitFor(t, degraded)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("WatchDependency() error = %v, want Canceled", err)
+	}
+	if degraded() {
+		t.Error("feature still degraded after the watch ended")
+	}
+
+	if err := server.WatchDependency(context.Background(), "search", 0, check); err == nil {
+		t.Error("WatchDependency() with a zero interval succeeded")
+	}
+}
@@ -0,0 +1,68 @@
+package shttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// methodDiscovery describes one method of a route in an OPTIONS response.
+type methodDiscovery struct {
+	Method   string            `json:"method"`
+	Auth     string            `json:"auth"`
+	Consumes []string          `json:"consumes,omitempty"`
+	Docs     string            `json:"docs,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// routeDiscovery is the body of an OPTIONS discovery response.
+type routeDiscovery struct {
+	Path    string            `json:"path"`
+	Methods []methodDiscovery `json:"methods"`
+}
+
+// discoveryHandler answers OPTIONS requests for a pattern that has no
+// explicit OPTIONS route with the allowed methods and the metadata declared
+// at registration (Consumes, Docs, WithMetadata), and whether each method
+// requires authentication ("required" or "public", as AuthMiddleware
+// enforces it). The Allow header lists the methods and, when documentation
+// is declared, a Link header points to it.
+func discoveryHandler(r *Router, pr *pathRoutes) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, req *http.Request) error {
+		body := routeDiscovery{Path: pr.pattern}
+		var docs string
+
+		// Copy the routes, and write the response without holding the lock
+		r.mu.RLock()
+		allow := make([]string, 0, len(pr.order)+1)
+		for _, method := range pr.order {
+			rt := pr.methods[method]
+			allow = append(allow, method)
+			auth := "required"
+			if rt.auth == authPublic {
+				auth = "public"
+			}
+			body.Methods = append(body.Methods, methodDiscovery{
+				Method:   method,
+				Auth:     auth,
+				Consumes: rt.consumes,
+				Docs:     rt.docs,
+				Metadata: rt.metadata,
+			})
+			if docs == "" {
+				docs = rt.docs
+			}
+		}
+		r.mu.RUnlock()
+		allow = append(allow, http.MethodOptions)
+
+		if docs != "" {
+			w.Header().Set("Link", "<"+docs+">; rel=\"help\"")
+		}
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		return json.NewEncoder(w).Encode(body)
+	}
+}
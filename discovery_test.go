@@ -0,0 +1,96 @@
+package shttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouterOptionsDiscovery(t *testing.T) {
+	router := NewRouter()
+	router.GET("/users/{id}", simpleHandler("user"), Docs("https://docs.example.com/users"), Public())
+	router.PUT("/users/{id}", simpleHandler("updated"), Consumes("application/json"), WithMetadata("auth", "bearer"))
+
+	req := httptest.NewRequest(http.MethodOptions, "/users/42", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status code = %v, want %v", w.Code, http.StatusOK)
+	}
+	wantHeaders := map[string]string{
+		"Allow":        "GET, PUT, OPTIONS",
+		"Link":         `<https://docs.example.com/users>; rel="help"`,
+		"Content-Type": "application/json",
+	}
+	for k, v := range wantHeaders {
+		if got := w.Header().Get(k); got != v {
+			t.Errorf("Header %q = %q, want %q", k, got, v)
+		}
+	}
+
+	var body routeDiscovery
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if body.Path != "/users/{id}" || len(body.Methods) != 2 {
+		t.Fatalf("unexpected body: %+v", body)
+	}
+	get, put := body.Methods[0], body.Methods[1]
+	if get.Method != http.MethodGet || get.Auth != "public" {
+		t.Errorf("unexpected GET entry: %+v", get)
+	}
+	if put.Method != http.MethodPut || put.Auth != "required" || put.Consumes[0] != "application/json" || put.Metadata["auth"] != "bearer" {
+		t.Errorf("unexpected PUT entry: %+v", put)
+	}
+}
+
+func TestRouterOptionsDiscoveryPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(*Router)
+		wantBody string
+	}{
+		{
+			name: "Explicit OPTIONS route wins",
+			setup: func(r *Router) {
+				r.GET("/test", simpleHandler("get"))
+				r.Handle(http.MethodOptions, "/test", simpleHandler("custom"))
+			},
+			wantBody: "custom",
+		},
+		{
+			name: "Middleware can answer preflight first",
+			setup: func(r *Router) {
+				r.Use(func(next Handler) Handler {
+					return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+						if r.Method == http.MethodOptions {
+							w.Write([]byte("preflight"))
+							return nil
+						}
+						return next(ctx, w, r)
+					}
+				})
+				r.GET("/test", simpleHandler("get"))
+			},
+			wantBody: "preflight",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewRouter()
+			tt.setup(router)
+
+			req := httptest.NewRequest(http.MethodOptions, "/test", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("Body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
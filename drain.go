@@ -0,0 +1,50 @@
+package shttp
+
+import (
+	"net"
+	"net/http"
+	"sync"
+)
+
+// connStates tracks the state of the server's client connections, so
+// Shutdown can report how many it drained and how many it had to close.
+type connStates struct {
+	mu     sync.Mutex
+	states map[net.Conn]http.ConnState
+}
+
+func newConnStates() *connStates {
+	return &connStates{states: make(map[net.Conn]http.ConnState)}
+}
+
+// hook returns an http.Server.ConnState callback recording the states,
+// then calling next if set.
+func (c *connStates) hook(next func(net.Conn, http.ConnState)) func(net.Conn, http.ConnState) {
+	return func(conn net.Conn, state http.ConnState) {
+		c.mu.Lock()
+		switch state {
+		case http.StateHijacked, http.StateClosed:
+			// Hijacked connections are tracked by TrackConnection, if at all
+			delete(c.states, conn)
+		default:
+			c.states[conn] = state
+		}
+		c.mu.Unlock()
+		if next != nil {
+			next(conn, state)
+		}
+	}
+}
+
+// counts returns the number of open connections, and how many of them are
+// serving a request.
+func (c *connStates) counts() (open, active int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, state := range c.states {
+		if state == http.StateActive {
+			active++
+		}
+	}
+	return len(c.states), active
+}
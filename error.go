@@ -1,9 +1,24 @@
 package shttp
 
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// defaultRetryAfter is the Retry-After hint sent with 502/503/504 responses
+// when the error does not specify one.
+const defaultRetryAfter = time.Second
+
 // HTTPError represents an HTTP error with a message and status code
 type HTTPError struct {
 	Message    string
 	StatusCode int
+
+	// RetryAfter overrides the Retry-After hint sent with 502, 503 and 504
+	// responses, rounded up to whole seconds. Zero uses the default of one
+	// second.
+	RetryAfter time.Duration
 }
 
 // Error implements the error interface
@@ -18,3 +33,37 @@ func NewHTTPError(statusCode int, message string) error {
 		StatusCode: statusCode,
 	}
 }
+
+// statusFromError returns the status code the router responds with for err.
+func statusFromError(err error) int {
+	if httpErr, ok := err.(HTTPError); ok {
+		return httpErr.StatusCode
+	}
+	return http.StatusInternalServerError
+}
+
+// ErrorHandler writes the response for an error returned by a handler that
+// has not written a response itself.
+type ErrorHandler func(ctx context.Context, w http.ResponseWriter, r *http.Request, err error)
+
+// DefaultErrorHandler answers with the status and message of an HTTPError,
+// or with 500 and the error text for any other error, as plain text.
+func DefaultErrorHandler(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
+	if httpErr, ok := err.(HTTPError); ok {
+		http.Error(w, httpErr.Message, httpErr.StatusCode)
+	} else {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// SetErrorHandler replaces DefaultErrorHandler for every route of the
+// router, e.g. to map domain errors to JSON bodies. Retry-After hints are
+// still set on 502, 503 and 504 responses. nil restores the default. It is
+// safe to call while serving.
+func (r *Router) SetErrorHandler(h ErrorHandler) {
+	if h == nil {
+		r.root().errorHandler.Store(nil)
+		return
+	}
+	r.root().errorHandler.Store(&h)
+}
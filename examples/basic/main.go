@@ -6,10 +6,7 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"os/signal"
 	"strings"
-	"syscall"
-	"time"
 
 	"github.com/andres-vara/shttp"
 	"github.com/andres-vara/slogr"
@@ -34,29 +31,10 @@ func main() {
 	server.GET("/users/{id}", userHandler)
 	server.GET("/test/{param1}", testHandler)
 
-	// Set up a channel to handle shutdown signals
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	// Start the server in a goroutine
-	go func() {
-		log.Println("Starting server at http://localhost:8080")
-		if err := server.Start(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
-		}
-	}()
-
-	// Wait for interrupt signal
-	<-done
-	log.Println("Server is shutting down...")
-
-	// Create a deadline for the shutdown
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer shutdownCancel()
-
-	// Attempt graceful shutdown
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Fatalf("Server shutdown failed: %v", err)
+	// Serve until interrupted, then drain in-flight requests
+	log.Println("Starting server at http://localhost:8080")
+	if err := server.Run(ctx); err != nil {
+		log.Fatalf("Server error: %v", err)
 	}
 
 	log.Println("Server gracefully stopped")
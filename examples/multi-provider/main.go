@@ -117,9 +117,7 @@ func (a *App) handleCreateUser(p Provider, ctx context.Context, w http.ResponseW
 	if l != nil {
 		l.Infof(ctx, "created user id=%s name=%s", created.ID, created.Name)
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	return json.NewEncoder(w).Encode(created)
+	return shttp.JSON(w, http.StatusCreated, created)
 }
 
 // handleUpdateUser updates a user for any provider.
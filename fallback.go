@@ -0,0 +1,86 @@
+package shttp
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// fallbackMargin is the fraction of the remaining time reserved for the
+// fallback when WithFallback is called.
+const fallbackMargin = 0.2
+
+// fallbackKey is the context key for the request's fallback slot.
+type fallbackKey struct{}
+
+// fallbackSlot holds the fallback registered by a handler.
+type fallbackSlot struct {
+	mu   sync.Mutex
+	fn   func(ctx context.Context, w http.ResponseWriter) error
+	soft context.Context
+}
+
+// WithFallback registers fn as the degraded response of the current request
+// and returns a context with a soft deadline, set so that 20% of the time
+// remaining before the request deadline is left for fn. The handler should
+// use the returned context for its work: if the soft deadline passes and the
+// handler returns an error without having written a response, the router
+// calls fn (with the original context) instead of reporting the error, so
+// the client gets e.g. cached or partial data rather than a timeout.
+//
+// Requests without a deadline (see Config.DefaultRequestTimeout and the
+// Timeout option) never run fn and get ctx back unchanged. Registering a new
+// fallback replaces the previous one.
+func WithFallback(ctx context.Context, fn func(ctx context.Context, w http.ResponseWriter) error) context.Context {
+	slot, ok := ctx.Value(fallbackKey{}).(*fallbackSlot)
+	if !ok {
+		return ctx
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx
+	}
+	margin := time.Duration(float64(time.Until(deadline)) * fallbackMargin)
+	soft, cancel := context.WithDeadline(ctx, deadline.Add(-margin))
+	// Release the soft deadline's resources together with the request.
+	context.AfterFunc(ctx, cancel)
+
+	slot.mu.Lock()
+	slot.fn = fn
+	slot.soft = soft
+	slot.mu.Unlock()
+	return soft
+}
+
+// ready returns the fallback if its soft deadline has passed.
+func (s *fallbackSlot) ready() func(ctx context.Context, w http.ResponseWriter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fn == nil || s.soft.Err() == nil {
+		return nil
+	}
+	return s.fn
+}
+
+// fallbackRescue wraps a route handler so a fallback registered with
+// WithFallback replaces the handler's error once the soft deadline passed.
+// It runs inside the middleware chain, so middleware observes the fallback's
+// outcome rather than the original failure.
+func fallbackRescue(next Handler) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if _, ok := ctx.Deadline(); !ok {
+			return next(ctx, w, r)
+		}
+		slot := &fallbackSlot{}
+		rw := wrapResponseWriter(w)
+		err := next(context.WithValue(ctx, fallbackKey{}, slot), rw, r)
+		if err == nil || rw.wroteHeader {
+			return err
+		}
+		if fn := slot.ready(); fn != nil {
+			return fn(ctx, rw)
+		}
+		return err
+	}
+}
@@ -0,0 +1,75 @@
+package shttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithFallback(t *testing.T) {
+	cached := func(ctx context.Context, w http.ResponseWriter) error {
+		if ctx.Err() != nil {
+			t.Errorf("fallback context already done: %v", ctx.Err())
+		}
+		w.Header().Set("X-Degraded", "true")
+		w.Write([]byte("cached"))
+		return nil
+	}
+
+	tests := []struct {
+		name         string
+		opts         []RouteOption
+		work         time.Duration
+		wantStatus   int
+		wantBody     string
+		wantDegraded bool
+	}{
+		{name: "Fast handler", opts: []RouteOption{Timeout(time.Second)}, work: 0, wantStatus: http.StatusOK, wantBody: "fresh"},
+		{name: "Slow handler falls back", opts: []RouteOption{Timeout(50 * time.Millisecond)}, work: time.Second, wantStatus: http.StatusOK, wantBody: "cached", wantDegraded: true},
+		{name: "No deadline", opts: []RouteOption{NoTimeout()}, work: 0, wantStatus: http.StatusOK, wantBody: "fresh"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewRouter()
+			router.GET("/feed", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				ctx = WithFallback(ctx, cached)
+				select {
+				case <-time.After(tt.work):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				w.Write([]byte("fresh"))
+				return nil
+			}, tt.opts...)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/feed", nil))
+			if w.Code != tt.wantStatus || w.Body.String() != tt.wantBody {
+				t.Errorf("response = %d %q, want %d %q", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+			if got := w.Header().Get("X-Degraded") == "true"; got != tt.wantDegraded {
+				t.Errorf("degraded = %v, want %v", got, tt.wantDegraded)
+			}
+		})
+	}
+}
+
+func TestWithFallbackOtherErrors(t *testing.T) {
+	router := NewRouter()
+	router.GET("/feed", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		WithFallback(ctx, func(ctx context.Context, w http.ResponseWriter) error {
+			t.Error("fallback ran before the soft deadline")
+			return nil
+		})
+		return NewHTTPError(http.StatusBadRequest, "bad input")
+	}, Timeout(time.Second))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/feed", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
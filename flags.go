@@ -0,0 +1,63 @@
+package shttp
+
+import (
+	"context"
+	"net/http"
+)
+
+// FlagProvider resolves the feature flags that apply to a request, typically
+// based on the user or tenant found in ctx.
+type FlagProvider interface {
+	Flags(ctx context.Context, r *http.Request) (map[string]bool, error)
+}
+
+// FlagProviderFunc adapts a function to the FlagProvider interface.
+type FlagProviderFunc func(ctx context.Context, r *http.Request) (map[string]bool, error)
+
+// Flags implements FlagProvider.
+func (f FlagProviderFunc) Flags(ctx context.Context, r *http.Request) (map[string]bool, error) {
+	return f(ctx, r)
+}
+
+// flagsKey is the context key for the resolved feature flags.
+type flagsKey struct{}
+
+// WithFlags returns a new context carrying the given feature flag state.
+// It is mostly useful in tests; in servers FeatureFlagMiddleware sets it.
+func WithFlags(ctx context.Context, flags map[string]bool) context.Context {
+	return context.WithValue(ctx, flagsKey{}, flags)
+}
+
+// FlagEnabled reports whether the feature flag is enabled for the current
+// request. Unknown flags and requests without resolved flags report false.
+func FlagEnabled(ctx context.Context, flag string) bool {
+	flags, _ := ctx.Value(flagsKey{}).(map[string]bool)
+	return flags[flag]
+}
+
+// FeatureFlagMiddleware resolves the request's feature flags once using
+// provider and caches them in the context for FlagEnabled. It should run
+// after the middleware that identifies the user (e.g. UserContextMiddleware).
+// If the provider fails, the error is logged and all flags read as disabled.
+func FeatureFlagMiddleware(provider FlagProvider) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if provider == nil {
+				return next(ctx, w, r)
+			}
+			flags, err := provider.Flags(ctx, r)
+			if err != nil {
+				if logger := GetLogger(ctx); logger != nil {
+					logger.Errorf(ctx, "[http.flags] Resolving feature flags failed: %v", err)
+				}
+				flags = nil
+			}
+			return next(WithFlags(ctx, flags), w, r)
+		}
+	}
+}
+
+// FeatureFlagMiddleware returns a FeatureFlagMiddleware using Config.FlagProvider.
+func (s *Server) FeatureFlagMiddleware() Middleware {
+	return FeatureFlagMiddleware(s.config.FlagProvider)
+}
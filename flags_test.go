@@ -0,0 +1,51 @@
+package shttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFeatureFlagMiddleware(t *testing.T) {
+	byUser := FlagProviderFunc(func(ctx context.Context, r *http.Request) (map[string]bool, error) {
+		return map[string]bool{"new-checkout": GetUserID(ctx) == "beta-user"}, nil
+	})
+	failing := FlagProviderFunc(func(ctx context.Context, r *http.Request) (map[string]bool, error) {
+		return nil, errors.New("flag service down")
+	})
+
+	tests := []struct {
+		name     string
+		provider FlagProvider
+		userID   string
+		want     string
+	}{
+		{name: "Enabled for beta user", provider: byUser, userID: "beta-user", want: "true"},
+		{name: "Disabled for other users", provider: byUser, userID: "someone", want: "false"},
+		{name: "Provider error disables flags", provider: failing, userID: "beta-user", want: "false"},
+		{name: "Nil provider", provider: nil, userID: "beta-user", want: "false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req = req.WithContext(context.WithValue(req.Context(), UserIDKey, tt.userID))
+
+			handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				if FlagEnabled(ctx, "new-checkout") {
+					w.Write([]byte("true"))
+				} else {
+					w.Write([]byte("false"))
+				}
+				return nil
+			}
+			w := executeMiddlewareTest(t, FeatureFlagMiddleware(tt.provider), handler, req)
+
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("FlagEnabled = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package shttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGatewaySignatureMiddleware(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	oldSecret, newSecret := []byte("old-secret"), []byte("new-secret")
+	mw := GatewaySignatureMiddleware(GatewaySignatureOptions{
+		Secrets:   [][]byte{newSecret, oldSecret},
+		SkipPaths: []string{"/healthz"},
+		Now:       func() time.Time { return now },
+	})
+	handler := mw(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		_, err := w.Write([]byte(GetRequestID(ctx)))
+		return err
+	})
+
+	tests := []struct {
+		name      string
+		path      string
+		requestID string
+		signature string
+		wantOK    bool
+	}{
+		{name: "valid", path: "/", requestID: "req-1", signature: GatewaySignature(newSecret, "req-1", now), wantOK: true},
+		{name: "previous secret", path: "/", requestID: "req-1", signature: GatewaySignature(oldSecret, "req-1", now), wantOK: true},
+		{name: "within skew", path: "/", requestID: "req-1", signature: GatewaySignature(newSecret, "req-1", now.Add(-4*time.Minute)), wantOK: true},
+		{name: "skipped path", path: "/healthz", wantOK: true},
+		{name: "no signature", path: "/", requestID: "req-1"},
+		{name: "no request ID", path: "/", signature: GatewaySignature(newSecret, "", now)},
+		{name: "other request ID", path: "/", requestID: "req-2", signature: GatewaySignature(newSecret, "req-1", now)},
+		{name: "unknown secret", path: "/", requestID: "req-1", signature: GatewaySignature([]byte("guess"), "req-1", now)},
+		{name: "expired", path: "/", requestID: "req-1", signature: GatewaySignature(newSecret, "req-1", now.Add(-6*time.Minute))},
+		{name: "from the future", path: "/", requestID: "req-1", signature: GatewaySignature(newSecret, "req-1", now.Add(6*time.Minute))},
+		{name: "malformed", path: "/", requestID: "req-1", signature: "t=abc,sig=zz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.requestID != "" {
+				req.Header.Set("X-Request-ID", tt.requestID)
+			}
+			if tt.signature != "" {
+				req.Header.Set(DefaultGatewaySignatureHeader, tt.signature)
+			}
+			w := httptest.NewRecorder()
+			err := handler(context.Background(), w, req)
+
+			if !tt.wantOK {
+				var httpErr HTTPError
+				if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusForbidden {
+					t.Errorf("error = %v, want 403", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got := w.Body.String(); got != tt.requestID {
+				t.Errorf("request ID in context = %q, want %q", got, tt.requestID)
+			}
+			if got := w.Header().Get("X-Request-ID"); got != tt.requestID {
+				t.Errorf("X-Request-ID = %q, want %q", got, tt.requestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package shttp
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/andres-vara/slogr"
+)
+
+// defaultGoroutineLeakThreshold is used when Config.GoroutineLeakThreshold is zero.
+const defaultGoroutineLeakThreshold = 30 * time.Second
+
+// goroutineTrackerKey is the context key for the server's goroutine tracker.
+type goroutineTrackerKey struct{}
+
+// goroutineTracker counts goroutines started through Server.Go / Go and
+// reports the ones that outlive their request.
+type goroutineTracker struct {
+	threshold time.Duration
+	logger    *slogr.Logger
+
+	running atomic.Int64
+	leaked  atomic.Int64
+}
+
+// Go runs fn in a new goroutine tracked by the server. ctx is usually the
+// request context: if fn is still running Config.GoroutineLeakThreshold after
+// ctx is done, the goroutine is logged as leaked and counted in Stats until it
+// returns.
+func (s *Server) Go(ctx context.Context, fn func()) {
+	s.goroutines.start(ctx, fn, callerSite())
+}
+
+// Go runs fn in a new goroutine tracked by the Server handling the request
+// carried by ctx (see Server.Go). Outside of a Server it simply starts fn.
+func Go(ctx context.Context, fn func()) {
+	if t, ok := ctx.Value(goroutineTrackerKey{}).(*goroutineTracker); ok {
+		t.start(ctx, fn, callerSite())
+		return
+	}
+	go fn()
+}
+
+func (t *goroutineTracker) start(ctx context.Context, fn func(), site string) {
+	done := make(chan struct{})
+	t.running.Add(1)
+	go func() {
+		defer close(done)
+		defer t.running.Add(-1)
+		fn()
+	}()
+
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+
+		timer := time.NewTimer(t.threshold)
+		defer timer.Stop()
+		select {
+		case <-done:
+			return
+		case <-timer.C:
+		}
+
+		t.leaked.Add(1)
+		if t.logger != nil {
+			t.logger.Errorf(ctx, "[server.goroutine_leak] goroutine started at %s still running %s after its request finished, request_id: %s", site, t.threshold, GetRequestID(ctx))
+		}
+		<-done
+		t.leaked.Add(-1)
+	}()
+}
+
+// callerSite returns the file:line of the function that called Go.
+func callerSite() string {
+	if _, file, line, ok := runtime.Caller(2); ok {
+		return fmt.Sprintf("%s:%d", file, line)
+	}
+	return "unknown"
+}
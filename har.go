@@ -0,0 +1,393 @@
+package shttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// HAROptions configures a HARRecorder.
+type HAROptions struct {
+	// Maximum number of entries kept; the oldest are dropped (default 1000)
+	MaxEntries int
+
+	// Maximum number of request and response body bytes captured per entry
+	// (default 64 KiB); longer bodies are truncated
+	MaxBodySize int64
+
+	// Headers whose values are replaced by "[redacted]" (default
+	// Authorization, Proxy-Authorization, Cookie and Set-Cookie)
+	RedactHeaders []string
+}
+
+// HARRecorder captures full request/response pairs during a recording
+// window and exports them as a HAR 1.2 file, for analysis in browser
+// devtools or HAR viewers. Recording is off until Start is called. Add
+// Middleware to the router and mount Handler on an admin route:
+//
+//	rec := shttp.NewHARRecorder(shttp.HAROptions{})
+//	server.Use(rec.Middleware())
+//	admin.ANY("/debug/har", rec.Handler())
+//
+// Captured bodies may contain sensitive data; protect the admin route.
+type HARRecorder struct {
+	opts HAROptions
+
+	mu      sync.Mutex
+	until   time.Time
+	entries []harEntry
+}
+
+// NewHARRecorder creates a recorder that is not recording yet.
+func NewHARRecorder(opts HAROptions) *HARRecorder {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 1000
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 64 << 10
+	}
+	if opts.RedactHeaders == nil {
+		opts.RedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+	}
+	for i, name := range opts.RedactHeaders {
+		opts.RedactHeaders[i] = http.CanonicalHeaderKey(name)
+	}
+	return &HARRecorder{opts: opts}
+}
+
+// Start records requests for the next d, extending or shortening a window
+// in progress.
+func (h *HARRecorder) Start(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.until = time.Now().Add(d)
+}
+
+// Stop ends the recording window. Captured entries are kept.
+func (h *HARRecorder) Stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.until = time.Time{}
+}
+
+// Reset discards the captured entries.
+func (h *HARRecorder) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = nil
+}
+
+// Recording reports whether a recording window is in progress.
+func (h *HARRecorder) Recording() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return time.Now().Before(h.until)
+}
+
+// harCaptureKey is the context key of the capture of a recorded request.
+type harCaptureKey struct{}
+
+// harCapture lets the recorder's own Handler opt its requests out.
+type harCapture struct {
+	skip bool
+}
+
+// Middleware captures the requests served while recording. Requests to the
+// recorder's Handler are never captured.
+func (h *HARRecorder) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if !h.Recording() {
+				return next(ctx, w, r)
+			}
+
+			start := time.Now()
+			capture := &harCapture{}
+			ctx = context.WithValue(ctx, harCaptureKey{}, capture)
+			reqBody := &capturingBody{ReadCloser: r.Body, limit: h.opts.MaxBodySize}
+			recorded := *r
+			recorded.Body = reqBody
+			hw := &harWriter{ResponseWriter: w, limit: h.opts.MaxBodySize}
+
+			err := next(ctx, hw, &recorded)
+			if !capture.skip {
+				h.add(h.entry(start, r, reqBody, hw, err))
+			}
+			return err
+		}
+	}
+}
+
+// add stores an entry, dropping the oldest past MaxEntries.
+func (h *HARRecorder) add(e harEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = append(h.entries, e)
+	if over := len(h.entries) - h.opts.MaxEntries; over > 0 {
+		h.entries = slices.Delete(h.entries, 0, over)
+	}
+}
+
+// entry builds the HAR entry of a served request. When the handler failed
+// without writing a response, the entry shows the router's default error
+// response.
+func (h *HARRecorder) entry(start time.Time, r *http.Request, reqBody *capturingBody, hw *harWriter, err error) harEntry {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	u := *r.URL
+	u.Scheme, u.Host = scheme, r.Host
+
+	req := harRequest{
+		Method:      r.Method,
+		URL:         u.String(),
+		HTTPVersion: r.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     h.headers(r.Header),
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    reqBody.size,
+	}
+	for name, values := range r.URL.Query() {
+		for _, v := range values {
+			req.QueryString = append(req.QueryString, harNameValue{Name: name, Value: v})
+		}
+	}
+	if reqBody.size > 0 {
+		req.PostData = &harPostData{MimeType: r.Header.Get("Content-Type"), Text: reqBody.buf.String()}
+	}
+
+	status, body, size := hw.status, hw.buf.String(), hw.size
+	if !hw.wroteHeader {
+		status = http.StatusOK
+		if err != nil {
+			status = statusFromError(err)
+			body = err.Error()
+			if httpErr, ok := err.(HTTPError); ok {
+				body = httpErr.Message
+			}
+			size = int64(len(body))
+		}
+	}
+	resp := harResponse{
+		Status:      status,
+		StatusText:  http.StatusText(status),
+		HTTPVersion: r.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     h.headers(hw.Header()),
+		Content:     harContent{Size: size, MimeType: hw.Header().Get("Content-Type"), Text: body},
+		HeadersSize: -1,
+		BodySize:    size,
+	}
+
+	elapsed := float64(time.Since(start).Microseconds()) / 1000
+	return harEntry{
+		StartedDateTime: start.UTC().Format(time.RFC3339Nano),
+		Time:            elapsed,
+		Request:         req,
+		Response:        resp,
+		Cache:           struct{}{},
+		Timings:         harTimings{Send: 0, Wait: elapsed, Receive: 0},
+	}
+}
+
+// headers converts headers to HAR name/value pairs, redacting the
+// configured ones.
+func (h *HARRecorder) headers(header http.Header) []harNameValue {
+	pairs := []harNameValue{}
+	for _, name := range slices.Sorted(maps.Keys(header)) {
+		for _, v := range header[name] {
+			if slices.Contains(h.opts.RedactHeaders, name) {
+				v = "[redacted]"
+			}
+			pairs = append(pairs, harNameValue{Name: name, Value: v})
+		}
+	}
+	return pairs
+}
+
+// HAR returns the captured entries as a HAR 1.2 document.
+func (h *HARRecorder) HAR() ([]byte, error) {
+	h.mu.Lock()
+	entries := slices.Clone(h.entries)
+	h.mu.Unlock()
+	if entries == nil {
+		entries = []harEntry{}
+	}
+	var doc harDocument
+	doc.Log.Version = "1.2"
+	doc.Log.Creator.Name = "shttp"
+	doc.Log.Creator.Version = "1"
+	doc.Log.Entries = entries
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// Handler is the admin endpoint of the recorder:
+//
+//	GET    downloads the captured entries as a HAR file
+//	POST   starts recording for ?duration= (default 1m)
+//	DELETE stops recording and discards the entries
+func (h *HARRecorder) Handler() Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if capture, ok := ctx.Value(harCaptureKey{}).(*harCapture); ok {
+			capture.skip = true
+		}
+		switch r.Method {
+		case http.MethodGet:
+			data, err := h.HAR()
+			if err != nil {
+				return err
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Disposition", `attachment; filename="shttp.har"`)
+			_, err = w.Write(data)
+			return err
+		case http.MethodPost:
+			d := time.Minute
+			if raw := r.URL.Query().Get("duration"); raw != "" {
+				parsed, err := time.ParseDuration(raw)
+				if err != nil || parsed <= 0 {
+					return NewHTTPError(http.StatusBadRequest, "invalid duration")
+				}
+				d = parsed
+			}
+			h.Start(d)
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		case http.MethodDelete:
+			h.Stop()
+			h.Reset()
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			return NewHTTPError(http.StatusMethodNotAllowed, "method not allowed")
+		}
+	}
+}
+
+// capturingBody keeps up to limit bytes of what the handler reads from a
+// request body.
+type capturingBody struct {
+	io.ReadCloser
+	limit int64
+	buf   bytes.Buffer
+	size  int64
+}
+
+func (b *capturingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if room := b.limit - int64(b.buf.Len()); room > 0 {
+		b.buf.Write(p[:min(int64(n), room)])
+	}
+	return n, err
+}
+
+// harWriter keeps the status and up to limit bytes of a response.
+type harWriter struct {
+	http.ResponseWriter
+	limit       int64
+	status      int
+	wroteHeader bool
+	buf         bytes.Buffer
+	size        int64
+}
+
+func (w *harWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *harWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.size += int64(n)
+	if room := w.limit - int64(w.buf.Len()); room > 0 {
+		w.buf.Write(p[:min(int64(n), room)])
+	}
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *harWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// HAR 1.2 document structure (http://www.softwareishard.com/blog/har-12-spec/).
+type harDocument struct {
+	Log struct {
+		Version string `json:"version"`
+		Creator struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
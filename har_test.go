@@ -0,0 +1,82 @@
+package shttp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHARRecorder(t *testing.T) {
+	rec := NewHARRecorder(HAROptions{MaxEntries: 2, MaxBodySize: 8})
+	router := NewRouter()
+	router.Use(rec.Middleware())
+	router.ANY("/debug/har", rec.Handler())
+	router.POST("/echo", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write(body)
+		return nil
+	})
+	router.GET("/fail", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return NewHTTPError(http.StatusConflict, "conflict")
+	})
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	do(http.MethodPost, "/echo", "before recording")
+	if w := do(http.MethodPost, "/debug/har?duration=1m", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("start: status = %d", w.Code)
+	}
+	do(http.MethodPost, "/echo?x=1", "dropped as oldest")
+	do(http.MethodPost, "/echo?x=2", "0123456789")
+	do(http.MethodGet, "/fail", "")
+
+	w := do(http.MethodGet, "/debug/har", "")
+	var har harDocument
+	if err := json.Unmarshal(w.Body.Bytes(), &har); err != nil {
+		t.Fatalf("invalid HAR: %v", err)
+	}
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 2 {
+		t.Fatalf("got version %q with %d entries, want 1.2 with 2", har.Log.Version, len(har.Log.Entries))
+	}
+
+	echo := har.Log.Entries[0]
+	if echo.Request.URL != "http://example.com/echo?x=2" {
+		t.Errorf("request URL = %q", echo.Request.URL)
+	}
+	if echo.Request.PostData == nil || echo.Request.PostData.Text != "01234567" || echo.Request.BodySize != 10 {
+		t.Errorf("request body = %+v, size %d, want truncated to 8 of 10 bytes", echo.Request.PostData, echo.Request.BodySize)
+	}
+	if echo.Response.Status != http.StatusOK || echo.Response.Content.Text != "01234567" || echo.Response.Content.MimeType != "text/plain" {
+		t.Errorf("response = %+v", echo.Response)
+	}
+	for _, h := range echo.Request.Headers {
+		if h.Name == "Authorization" && h.Value != "[redacted]" {
+			t.Errorf("Authorization = %q, want redacted", h.Value)
+		}
+	}
+
+	fail := har.Log.Entries[1]
+	if fail.Response.Status != http.StatusConflict || fail.Response.Content.Text != "conflict" {
+		t.Errorf("error response = %d %q, want 409 conflict", fail.Response.Status, fail.Response.Content.Text)
+	}
+
+	do(http.MethodDelete, "/debug/har", "")
+	do(http.MethodPost, "/echo", "after stop")
+	if rec.Recording() {
+		t.Error("still recording after DELETE")
+	}
+	if data, _ := rec.HAR(); !strings.Contains(string(data), `"entries": []`) {
+		t.Errorf("entries not discarded: %s", data)
+	}
+}
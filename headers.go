@@ -0,0 +1,82 @@
+package shttp
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// HeaderSetter is implemented by response types that set their own response
+// headers; see SetResponseHeaders.
+type HeaderSetter interface {
+	SetHeaders(h http.Header)
+}
+
+// SetResponseHeaders sets the response headers declared by v, a response
+// value about to be written: fields tagged `header:"Name"` are set from
+// their value, then v's SetHeaders method is called if it implements
+// HeaderSetter. Tagged fields with a zero value are skipped. Supported
+// field types are strings, integers, booleans, time.Time (formatted as an
+// HTTP date), []string (one header value each) and fmt.Stringer. Tag the
+// fields `json:"-"` too to keep them out of the body.
+func SetResponseHeaders(w http.ResponseWriter, v any) {
+	h := w.Header()
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Struct {
+		setTaggedHeaders(h, rv)
+	}
+	if setter, ok := v.(HeaderSetter); ok {
+		setter.SetHeaders(h)
+	}
+}
+
+// setTaggedHeaders sets the headers of the tagged fields of the struct rv,
+// including those of embedded structs.
+func setTaggedHeaders(h http.Header, rv reflect.Value) {
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		field := rt.Field(i)
+		fv := rv.Field(i)
+		name, tagged := field.Tag.Lookup("header")
+		if !tagged {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				setTaggedHeaders(h, fv)
+			}
+			continue
+		}
+		if name == "" || name == "-" || !field.IsExported() || fv.IsZero() {
+			continue
+		}
+		for _, value := range headerValues(fv) {
+			h.Add(name, value)
+		}
+	}
+}
+
+// headerValues formats a field value as header values.
+func headerValues(fv reflect.Value) []string {
+	switch v := fv.Interface().(type) {
+	case time.Time:
+		return []string{v.UTC().Format(http.TimeFormat)}
+	case []string:
+		return v
+	case fmt.Stringer:
+		return []string{v.String()}
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		return []string{fv.String()}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return []string{strconv.FormatInt(fv.Int(), 10)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return []string{strconv.FormatUint(fv.Uint(), 10)}
+	case reflect.Bool:
+		return []string{strconv.FormatBool(fv.Bool())}
+	}
+	return nil
+}
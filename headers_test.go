@@ -0,0 +1,60 @@
+package shttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type pageMeta struct {
+	Total int `header:"X-Total-Count" json:"-"`
+}
+
+type itemsResponse struct {
+	pageMeta
+	ETag     string    `header:"ETag" json:"-"`
+	Modified time.Time `header:"Last-Modified" json:"-"`
+	Links    []string  `header:"Link" json:"-"`
+	Items    []string  `json:"items"`
+	Skipped  string    `header:"X-Skipped" json:"-"`
+}
+
+func (r itemsResponse) SetHeaders(h http.Header) {
+	h.Set("Cache-Control", "private")
+}
+
+func TestSetResponseHeaders(t *testing.T) {
+	resp := &itemsResponse{
+		pageMeta: pageMeta{Total: 42},
+		ETag:     `"v1"`,
+		Modified: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Links:    []string{`</items?page=2>; rel="next"`, `</items?page=5>; rel="last"`},
+	}
+	w := httptest.NewRecorder()
+	SetResponseHeaders(w, resp)
+
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"X-Total-Count", []string{"42"}},
+		{"ETag", []string{`"v1"`}},
+		{"Last-Modified", []string{"Tue, 02 Jan 2024 03:04:05 GMT"}},
+		{"Link", resp.Links},
+		{"Cache-Control", []string{"private"}},
+		{"X-Skipped", nil},
+	}
+	for _, tt := range tests {
+		got := w.Header().Values(tt.header)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s = %q, want %q", tt.header, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s = %q, want %q", tt.header, got, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package shttp
+
+import (
+	"github.com/andres-vara/shttp/health"
+)
+
+// EnableHealth serves the liveness probe of checker at /healthz and its
+// readiness probe at /readyz (health.Default when checker is nil), as public
+// routes. Shutdown makes the readiness probe fail, then keeps serving for
+// Config.ShutdownDelay before closing the listeners, so load balancers
+// polling /readyz stop routing new requests to the instance first; without
+// a delay they only see the instance disappear. The probes keep answering
+// in maintenance mode, which must not get instances restarted or taken
+// out of rotation.
+func (s *Server) EnableHealth(checker *health.Checker) {
+	if checker == nil {
+		checker = health.Default
+	}
+	s.healthCheckers = append(s.healthCheckers, checker)
+	s.probePaths = append(s.probePaths, "/healthz", "/readyz")
+	s.GET("/healthz", WrapHTTPHandler(checker.LivenessHandler()), Public(), Name("health.liveness"))
+	s.GET("/readyz", WrapHTTPHandler(checker.ReadinessHandler()), Public(), Name("health.readiness"))
+}
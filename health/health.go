@@ -0,0 +1,295 @@
+// Package health runs named health checks and serves their results as
+// liveness and readiness probes:
+//
+//	health.Register("db", func(ctx context.Context) error {
+//		return db.PingContext(ctx)
+//	})
+//
+//	server.EnableHealth(nil) // serves health.Default at /healthz and /readyz
+//
+// Each check runs with a timeout, and its result is cached briefly so
+// frequent probes from several load balancers do not hammer dependencies.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/andres-vara/shttp/cache"
+)
+
+const (
+	// StatusOK reports a passing check or probe.
+	StatusOK = "ok"
+
+	// StatusFailing reports a failing check or probe.
+	StatusFailing = "failing"
+)
+
+const (
+	defaultTimeout  = 2 * time.Second
+	defaultCacheTTL = time.Second
+)
+
+// CheckFunc reports whether a dependency is healthy; a non-nil error marks
+// the check as failing.
+type CheckFunc func(ctx context.Context) error
+
+// Options configures a Checker.
+type Options struct {
+	// Maximum duration of a single check (default 2s)
+	Timeout time.Duration
+
+	// How long a check result is reused by the following probes (default
+	// 1s, negative to run the checks on every probe)
+	CacheTTL time.Duration
+}
+
+// CheckResult is the outcome of one check.
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+
+	// Time spent running the check, in milliseconds
+	DurationMs float64 `json:"duration_ms"`
+
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report is the outcome of a probe.
+type Report struct {
+	Status string `json:"status"`
+
+	// Why the probe fails regardless of its checks, e.g. a shutdown
+	Reason string `json:"reason,omitempty"`
+
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// OK reports whether the probe passed.
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+// check is a registered check.
+type check struct {
+	fn CheckFunc
+
+	// Whether the check is part of the liveness probe too
+	liveness bool
+}
+
+// Checker holds a set of named checks. It is safe for concurrent use; the
+// zero value is not usable, create checkers with New.
+type Checker struct {
+	opts Options
+
+	mu     sync.RWMutex
+	checks map[string]check
+
+	// Recent results, by check name
+	results *cache.Cache[string, CheckResult]
+
+	shuttingDown atomic.Bool
+}
+
+// Default is the Checker used by the package-level functions.
+var Default = New(Options{})
+
+// New creates a Checker without checks.
+func New(opts Options) *Checker {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.CacheTTL == 0 {
+		opts.CacheTTL = defaultCacheTTL
+	}
+	return &Checker{
+		opts:    opts,
+		checks:  make(map[string]check),
+		results: cache.New[string, CheckResult](cache.Options{TTL: opts.CacheTTL}),
+	}
+}
+
+// Register adds a readiness check to the Default checker.
+func Register(name string, fn CheckFunc) {
+	Default.Register(name, fn)
+}
+
+// RegisterLiveness adds a liveness check to the Default checker.
+func RegisterLiveness(name string, fn CheckFunc) {
+	Default.RegisterLiveness(name, fn)
+}
+
+// Register adds a readiness check, replacing any check with the same name.
+// A failing readiness check tells the load balancer to stop sending traffic
+// to the instance, without restarting it: use it for dependencies the
+// instance cannot serve without (database, downstream services).
+func (c *Checker) Register(name string, fn CheckFunc) {
+	c.register(name, check{fn: fn})
+}
+
+// RegisterLiveness adds a liveness check, replacing any check with the same
+// name. A failing liveness check gets the instance restarted, so only use
+// it for states a restart fixes (a deadlocked worker, a corrupted cache),
+// never for external dependencies. Liveness checks are part of the
+// readiness probe too.
+func (c *Checker) RegisterLiveness(name string, fn CheckFunc) {
+	c.register(name, check{fn: fn, liveness: true})
+}
+
+func (c *Checker) register(name string, chk check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = chk
+	c.results.Delete(name)
+}
+
+// Unregister removes the named check.
+func (c *Checker) Unregister(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.checks, name)
+	c.results.Delete(name)
+}
+
+// SetShuttingDown makes the readiness probe fail regardless of the checks
+// while enabled, so load balancers stop routing new requests to an instance
+// that is draining. Server.Shutdown enables it on the checkers mounted with
+// Server.EnableHealth.
+func (c *Checker) SetShuttingDown(enabled bool) {
+	c.shuttingDown.Store(enabled)
+}
+
+// Liveness runs the liveness checks.
+func (c *Checker) Liveness(ctx context.Context) Report {
+	return c.run(ctx, true)
+}
+
+// Readiness runs every check; it fails while the checker is shutting down.
+func (c *Checker) Readiness(ctx context.Context) Report {
+	report := c.run(ctx, false)
+	if c.shuttingDown.Load() {
+		report.Status = StatusFailing
+		report.Reason = "shutting down"
+	}
+	return report
+}
+
+// run runs the selected checks concurrently, reusing the cached results.
+func (c *Checker) run(ctx context.Context, livenessOnly bool) Report {
+	c.mu.RLock()
+	selected := make(map[string]CheckFunc, len(c.checks))
+	for name, chk := range c.checks {
+		if chk.liveness || !livenessOnly {
+			selected[name] = chk.fn
+		}
+	}
+	c.mu.RUnlock()
+
+	report := Report{Status: StatusOK}
+	if len(selected) == 0 {
+		return report
+	}
+	report.Checks = make(map[string]CheckResult, len(selected))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, fn := range selected {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := c.result(ctx, name, fn)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusFailing
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// result returns the cached result of a check, running it on a miss.
+func (c *Checker) result(ctx context.Context, name string, fn CheckFunc) CheckResult {
+	if c.opts.CacheTTL < 0 {
+		return c.runCheck(ctx, fn)
+	}
+	// The result is shared with concurrent probes, so a probe going away
+	// must not cancel the check
+	result, _ := c.results.GetOrLoad(ctx, name, func(ctx context.Context) (CheckResult, error) {
+		return c.runCheck(context.WithoutCancel(ctx), fn), nil
+	})
+	if result.Status == "" {
+		// This probe gave up waiting for a check run by another one
+		return CheckResult{Status: StatusFailing, Error: ctx.Err().Error(), CheckedAt: time.Now()}
+	}
+	return result
+}
+
+// runCheck runs a check under the checker's timeout.
+func (c *Checker) runCheck(ctx context.Context, fn CheckFunc) (result CheckResult) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	defer func() {
+		result.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+		result.CheckedAt = start
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- fmt.Errorf("check panicked: %v", rec)
+			}
+		}()
+		done <- fn(ctx)
+	}()
+
+	// A check ignoring its context still fails at the timeout
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		return CheckResult{Status: StatusFailing, Error: err.Error()}
+	}
+	return CheckResult{Status: StatusOK}
+}
+
+// LivenessHandler serves the liveness probe as JSON, with status 200 when it
+// passes and 503 otherwise.
+func (c *Checker) LivenessHandler() http.Handler {
+	return reportHandler(c.Liveness)
+}
+
+// ReadinessHandler serves the readiness probe as JSON, with status 200 when
+// it passes and 503 otherwise.
+func (c *Checker) ReadinessHandler() http.Handler {
+	return reportHandler(c.Readiness)
+}
+
+func reportHandler(probe func(ctx context.Context) Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := probe(r.Context())
+		status := http.StatusOK
+		if !report.OK() {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(report)
+	})
+}
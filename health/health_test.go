@@ -0,0 +1,108 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCheckerProbes(t *testing.T) {
+	c := New(Options{Timeout: 20 * time.Millisecond})
+	c.RegisterLiveness("workers", func(ctx context.Context) error { return nil })
+	c.Register("db", func(ctx context.Context) error { return errors.New("connection refused") })
+	c.Register("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second) // ignores its context
+		return nil
+	})
+	c.Register("panics", func(ctx context.Context) error { panic("boom") })
+
+	if report := c.Liveness(context.Background()); !report.OK() || len(report.Checks) != 1 {
+		t.Errorf("Liveness() = %+v, want ok with the liveness check only", report)
+	}
+
+	report := c.Readiness(context.Background())
+	if report.OK() {
+		t.Error("Readiness() passed with failing checks")
+	}
+	tests := []struct {
+		check     string
+		want      string
+		wantError string
+	}{
+		{"workers", StatusOK, ""},
+		{"db", StatusFailing, "connection refused"},
+		{"slow", StatusFailing, context.DeadlineExceeded.Error()},
+		{"panics", StatusFailing, "check panicked: boom"},
+	}
+	for _, tt := range tests {
+		got := report.Checks[tt.check]
+		if got.Status != tt.want || got.Error != tt.wantError {
+			t.Errorf("check %s = %s %q, want %s %q", tt.check, got.Status, got.Error, tt.want, tt.wantError)
+		}
+	}
+}
+
+func TestCheckerCaching(t *testing.T) {
+	var runs atomic.Int32
+	check := func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}
+
+	c := New(Options{CacheTTL: time.Minute})
+	c.Register("db", check)
+	for range 3 {
+		c.Readiness(context.Background())
+	}
+	if got := runs.Load(); got != 1 {
+		t.Errorf("cached check ran %d times, want 1", got)
+	}
+
+	runs.Store(0)
+	uncached := New(Options{CacheTTL: -1})
+	uncached.Register("db", check)
+	for range 3 {
+		uncached.Readiness(context.Background())
+	}
+	if got := runs.Load(); got != 3 {
+		t.Errorf("uncached check ran %d times, want 3", got)
+	}
+}
+
+func TestCheckerHandlers(t *testing.T) {
+	c := New(Options{})
+	c.Register("db", func(ctx context.Context) error { return nil })
+
+	tests := []struct {
+		name         string
+		handler      http.Handler
+		shuttingDown bool
+		want         int
+		wantStatus   string
+	}{
+		{name: "Liveness", handler: c.LivenessHandler(), want: http.StatusOK, wantStatus: StatusOK},
+		{name: "Readiness", handler: c.ReadinessHandler(), want: http.StatusOK, wantStatus: StatusOK},
+		{name: "Readiness while shutting down", handler: c.ReadinessHandler(), shuttingDown: true, want: http.StatusServiceUnavailable, wantStatus: StatusFailing},
+		{name: "Liveness while shutting down", handler: c.LivenessHandler(), shuttingDown: true, want: http.StatusOK, wantStatus: StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.SetShuttingDown(tt.shuttingDown)
+			w := httptest.NewRecorder()
+			tt.handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			var report Report
+			if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+				t.Fatalf("decoding %q: %v", w.Body.String(), err)
+			}
+			if w.Code != tt.want || report.Status != tt.wantStatus {
+				t.Errorf("got %d %s, want %d %s", w.Code, report.Status, tt.want, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package shttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/andres-vara/shttp/health"
+	"github.com/andres-vara/slogr"
+)
+
+func TestServerEnableHealth(t *testing.T) {
+	server := New(context.Background(), &Config{Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	server.Use(AuthMiddleware(func(ctx context.Context, r *http.Request) (context.Context, error) {
+		return nil, errors.New("no credentials")
+	}))
+	checker := health.New(health.Options{})
+	checker.Register("db", func(ctx context.Context) error { return nil })
+	server.EnableHealth(checker)
+
+	probe := func(path string) int {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if got := probe(path); got != http.StatusOK {
+			t.Errorf("GET %s = %d, want %d", path, got, http.StatusOK)
+		}
+	}
+
+	// Maintenance mode must not fail the probes
+	server.ApplyConfig(&Config{MaintenanceMode: true})
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if got := probe(path); got != http.StatusOK {
+			t.Errorf("GET %s in maintenance mode = %d, want %d", path, got, http.StatusOK)
+		}
+	}
+	if got := probe("/orders"); got != http.StatusServiceUnavailable {
+		t.Errorf("GET /orders in maintenance mode = %d, want %d", got, http.StatusServiceUnavailable)
+	}
+	server.ApplyConfig(&Config{})
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if got := probe("/readyz"); got != http.StatusServiceUnavailable {
+		t.Errorf("GET /readyz after Shutdown = %d, want %d", got, http.StatusServiceUnavailable)
+	}
+	if got := probe("/healthz"); got != http.StatusOK {
+		t.Errorf("GET /healthz after Shutdown = %d, want %d", got, http.StatusOK)
+	}
+}
+
+func TestServerShutdownDelay(t *testing.T) {
+	server := New(context.Background(), &Config{
+		Addr:          "127.0.0.1:0",
+		Logger:        slogr.New(io.Discard, slogr.DefaultOptions()),
+		ShutdownDelay: 300 * time.Millisecond,
+	})
+	server.EnableHealth(health.New(health.Options{}))
+	server.GET("/orders", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+	if _, err := server.StartAsync(); err != nil {
+		t.Fatal(err)
+	}
+	base := "http://" + server.Addr().String()
+	get := func(path string) int {
+		resp, err := http.Get(base + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- server.Shutdown(context.Background()) }()
+
+	// Load balancers polling during the delay see the failing probe, while
+	// the requests they still route are served
+	waitFor(t, func() bool { return get("/readyz") == http.StatusServiceUnavailable })
+	if got := get("/orders"); got != http.StatusOK {
+		t.Errorf("GET /orders during the delay = %d, want %d", got, http.StatusOK)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("Shutdown returned after %v, before ShutdownDelay", elapsed)
+	}
+}
@@ -0,0 +1,85 @@
+package shttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// ErrNoHTTP3 is returned by StartQUIC when Config.HTTP3 is not set.
+var ErrNoHTTP3 = errors.New("shttp: StartQUIC needs Config.HTTP3")
+
+// altSvcMaxAge is how long, in seconds, clients may remember that the
+// server speaks HTTP/3.
+const altSvcMaxAge = 86400
+
+// HTTP3Server is an HTTP/3 server implementation. shttp does not bundle a
+// QUIC stack; quic-go's *http3.Server satisfies this interface:
+//
+//	config.HTTP3 = func(addr string, handler http.Handler) shttp.HTTP3Server {
+//		return &http3.Server{Addr: addr, Handler: handler}
+//	}
+type HTTP3Server interface {
+	// ListenAndServeTLS listens on UDP and serves until Close or Shutdown
+	ListenAndServeTLS(certFile, keyFile string) error
+
+	// Close stops the server immediately
+	Close() error
+}
+
+// StartQUIC starts the server with TLS like StartTLS, and serves the same
+// routes over HTTP/3 on the UDP port of the TCP listener, using the
+// implementation built by Config.HTTP3. Responses over TCP advertise
+// HTTP/3 with an Alt-Svc header, so clients switch to QUIC for the
+// following requests. If the HTTP/3 listener fails, the error is logged,
+// the advertisement withdrawn, and the server goes on over TCP.
+//
+// Shutdown stops the HTTP/3 server too, gracefully when it has a
+// Shutdown(ctx) error method (as quic-go's does).
+func (s *Server) StartQUIC(certFile, keyFile string) error {
+	if s.config.HTTP3 == nil {
+		return ErrNoHTTP3
+	}
+	return s.start(nil, "TLS server with HTTP/3", func(l net.Listener) error {
+		host, _, _ := net.SplitHostPort(s.config.Addr)
+		_, port, err := net.SplitHostPort(l.Addr().String())
+		if err != nil {
+			l.Close()
+			return fmt.Errorf("shttp: HTTP/3 needs a TCP listener: %w", err)
+		}
+
+		h3 := s.config.HTTP3(net.JoinHostPort(host, port), s.http3Handler())
+		s.http3.Store(&h3)
+		s.altSvc.Store(fmt.Sprintf(`h3=":%s"; ma=%d`, port, altSvcMaxAge))
+		go func() {
+			if err := h3.ListenAndServeTLS(certFile, keyFile); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.altSvc.Store("")
+				s.logger.Errorf(s.ctx, "[server.http3] HTTP/3 listener failed, serving over TCP only: %v", err)
+			}
+		}()
+		return s.server.ServeTLS(l, certFile, keyFile)
+	})
+}
+
+// http3Handler serves HTTP/3 requests, whose contexts lack the values
+// http.Server's BaseContext puts in those of TCP requests.
+func (s *Server) http3Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.ServeHTTP(w, r.WithContext(s.withBaseValues(r.Context())))
+	})
+}
+
+// shutdownHTTP3 stops the HTTP/3 server started by StartQUIC, if any.
+func (s *Server) shutdownHTTP3(ctx context.Context) error {
+	h3 := s.http3.Load()
+	if h3 == nil {
+		return nil
+	}
+	s.altSvc.Store("")
+	if graceful, ok := (*h3).(interface{ Shutdown(context.Context) error }); ok {
+		return graceful.Shutdown(ctx)
+	}
+	return (*h3).Close()
+}
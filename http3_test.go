@@ -0,0 +1,161 @@
+package shttp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andres-vara/slogr"
+)
+
+// writeTestCert writes a self-signed certificate for 127.0.0.1 and its key,
+// and returns their paths.
+func writeTestCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile
+}
+
+// fakeHTTP3 records how StartQUIC sets it up and serves until closed.
+type fakeHTTP3 struct {
+	addr    string
+	handler http.Handler
+	fail    error
+
+	// Closed once StartQUIC built it, and on Close
+	ready  chan struct{}
+	closed chan struct{}
+}
+
+func newFakeHTTP3(fail error) *fakeHTTP3 {
+	return &fakeHTTP3{fail: fail, ready: make(chan struct{}), closed: make(chan struct{})}
+}
+
+func (f *fakeHTTP3) ListenAndServeTLS(certFile, keyFile string) error {
+	if f.fail != nil {
+		return f.fail
+	}
+	<-f.closed
+	return http.ErrServerClosed
+}
+
+func (f *fakeHTTP3) Close() error {
+	close(f.closed)
+	return nil
+}
+
+func TestServerStartQUIC(t *testing.T) {
+	certFile, keyFile := writeTestCert(t)
+	newServer := func(h3 *fakeHTTP3) *Server {
+		server := New(context.Background(), &Config{
+			Addr:   "127.0.0.1:0",
+			Logger: slogr.New(io.Discard, slogr.DefaultOptions()),
+			HTTP3: func(addr string, handler http.Handler) HTTP3Server {
+				h3.addr, h3.handler = addr, handler
+				close(h3.ready)
+				return h3
+			},
+		})
+		server.GET("/", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			// Server-wide registries must reach HTTP/3 requests too
+			untrack := TrackConnection(ctx, func(context.Context) error { return nil })
+			defer untrack()
+			_, err := fmt.Fprintf(w, "%s|%s|%d", r.Proto, w.Header().Get("Alt-Svc"), len(server.Connections()))
+			return err
+		})
+		return server
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	get := func(t *testing.T, url string) string {
+		t.Helper()
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("GET %s: %v", url, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	if err := New(context.Background(), &Config{Logger: slogr.New(io.Discard, slogr.DefaultOptions())}).StartQUIC(certFile, keyFile); !errors.Is(err, ErrNoHTTP3) {
+		t.Errorf("StartQUIC() without Config.HTTP3 error = %v, want ErrNoHTTP3", err)
+	}
+
+	t.Run("serves HTTP/3 and advertises it", func(t *testing.T) {
+		h3 := newFakeHTTP3(nil)
+		server := newServer(h3)
+		go server.StartQUIC(certFile, keyFile)
+		<-h3.ready
+		_, port, _ := net.SplitHostPort(server.Addr().String())
+
+		if got, want := get(t, "https://"+server.Addr().String()+"/"), "HTTP/1.1|h3=\":"+port+"\"; ma=86400|1"; got != want {
+			t.Errorf("TCP response = %q, want %q", got, want)
+		}
+		if h3.addr != "127.0.0.1:"+port {
+			t.Errorf("HTTP/3 address = %q, want the TCP port", h3.addr)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Proto, req.ProtoMajor, req.ProtoMinor = "HTTP/3.0", 3, 0
+		w := httptest.NewRecorder()
+		h3.handler.ServeHTTP(w, req)
+		if want := "HTTP/3.0||1"; w.Body.String() != want {
+			t.Errorf("HTTP/3 response = %q, want %q", w.Body.String(), want)
+		}
+
+		if err := server.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown() error = %v", err)
+		}
+		select {
+		case <-h3.closed:
+		default:
+			t.Error("Shutdown did not close the HTTP/3 server")
+		}
+	})
+
+	t.Run("falls back to TCP when HTTP/3 fails", func(t *testing.T) {
+		h3 := newFakeHTTP3(errors.New("udp port in use"))
+		server := newServer(h3)
+		go server.StartQUIC(certFile, keyFile)
+		defer server.Shutdown(context.Background())
+		<-h3.ready
+		waitFor(t, func() bool { return strings.HasPrefix(get(t, "https://"+server.Addr().String()+"/"), "HTTP/1.1||") })
+	})
+}
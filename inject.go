@@ -0,0 +1,61 @@
+package shttp
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// providersKey is the context key for the server's provider registry.
+type providersKey struct{}
+
+// providers holds the values registered with Provide, keyed by type.
+type providers struct {
+	mu     sync.RWMutex
+	values map[reflect.Type]any
+}
+
+func (p *providers) get(t reflect.Type) (any, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	v, ok := p.values[t]
+	return v, ok
+}
+
+// Provide registers v as the value handlers receive from Get[T] / MustGet[T].
+// Registering a second value of the same type replaces the first. T is
+// usually an interface or pointer type (e.g. *sql.DB, UserStore).
+func Provide[T any](s *Server, v T) {
+	s.providers.mu.Lock()
+	defer s.providers.mu.Unlock()
+	if s.providers.values == nil {
+		s.providers.values = make(map[reflect.Type]any)
+	}
+	s.providers.values[reflect.TypeFor[T]()] = v
+}
+
+// Get returns the value of type T registered with Provide on the server
+// handling the request carried by ctx.
+func Get[T any](ctx context.Context) (T, bool) {
+	var zero T
+	p, ok := ctx.Value(providersKey{}).(*providers)
+	if !ok {
+		return zero, false
+	}
+	v, ok := p.get(reflect.TypeFor[T]())
+	if !ok {
+		return zero, false
+	}
+	return v.(T), true
+}
+
+// MustGet is like Get but panics when no value of type T was provided. A
+// missing provider is a wiring bug, which RecoveryMiddleware turns into a 500.
+func MustGet[T any](ctx context.Context) T {
+	v, ok := Get[T](ctx)
+	if !ok {
+		panic(fmt.Sprintf("shttp: no provider registered for %s", reflect.TypeFor[T]()))
+	}
+	return v
+}
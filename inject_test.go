@@ -0,0 +1,51 @@
+package shttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andres-vara/slogr"
+)
+
+// greeter is a service resolved by handlers through the provider registry.
+type greeter interface {
+	Greet(name string) string
+}
+
+type englishGreeter struct{}
+
+func (englishGreeter) Greet(name string) string { return "hello " + name }
+
+func TestProvideAndGet(t *testing.T) {
+	server := New(context.Background(), &Config{Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	Provide[greeter](server, englishGreeter{})
+
+	server.GET("/greet/{name}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		g := MustGet[greeter](ctx)
+		if _, ok := Get[*http.Client](ctx); ok {
+			t.Error("Get[*http.Client] found a value that was never provided")
+		}
+		w.Write([]byte(g.Greet(PathValue(r, "name"))))
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/greet/ada", nil)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	if w.Body.String() != "hello ada" {
+		t.Errorf("Body = %q, want %q", w.Body.String(), "hello ada")
+	}
+}
+
+func TestMustGetPanicsWithoutProvider(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustGet did not panic for a missing provider")
+		}
+	}()
+	MustGet[greeter](context.Background())
+}
@@ -0,0 +1,185 @@
+package shttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andres-vara/slogr"
+)
+
+// PanicIsolationOptions configures a PanicIsolation.
+type PanicIsolationOptions struct {
+	// Number of panics within Window that disable a route (default 5 in 1m)
+	Threshold int
+	Window    time.Duration
+
+	// How long a route stays disabled (default 5m); Enable re-enables it
+	// earlier
+	DisableFor time.Duration
+
+	// Called, in its own goroutine, when a route gets disabled, e.g. to page
+	// the on-call
+	OnDisable func(ctx context.Context, incident RouteIncident)
+
+	// Logs recovered panics and disabled routes when set
+	Logger *slogr.Logger
+}
+
+// RouteIncident describes a route disabled by PanicIsolation.
+type RouteIncident struct {
+	// Reported to clients in the X-Incident-ID header of the 503 responses
+	ID string
+
+	// Disabled route, as "METHOD /path"
+	Route string
+
+	// Panics that triggered the incident, and the last panic value
+	Panics    int
+	LastPanic string
+
+	DisabledAt time.Time
+	Until      time.Time
+}
+
+// PanicIsolation recovers handler panics and keeps a crash-looping route
+// from consuming the error budget of the whole service: once a route panics
+// Threshold times within Window, its requests are answered with 503 and an
+// incident ID, without running the handler, for DisableFor. Add Middleware
+// to the router:
+//
+//	isolation := shttp.NewPanicIsolation(shttp.PanicIsolationOptions{Logger: logger})
+//	server.Use(isolation.Middleware())
+type PanicIsolation struct {
+	opts PanicIsolationOptions
+
+	mu        sync.Mutex
+	panics    map[string][]time.Time
+	incidents map[string]RouteIncident
+}
+
+// NewPanicIsolation creates a PanicIsolation with no disabled route.
+func NewPanicIsolation(opts PanicIsolationOptions) *PanicIsolation {
+	if opts.Threshold <= 0 {
+		opts.Threshold = 5
+	}
+	if opts.Window <= 0 {
+		opts.Window = time.Minute
+	}
+	if opts.DisableFor <= 0 {
+		opts.DisableFor = 5 * time.Minute
+	}
+	return &PanicIsolation{
+		opts:      opts,
+		panics:    make(map[string][]time.Time),
+		incidents: make(map[string]RouteIncident),
+	}
+}
+
+// Middleware recovers panics, answering them with 500, and answers the
+// requests of disabled routes with 503.
+func (p *PanicIsolation) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) (err error) {
+			name := r.Method + " " + r.URL.Path
+			if rt, ok := ctx.Value(routeKey{}).(*route); ok {
+				name = rt.String()
+			}
+			if incident, ok := p.disabled(name); ok {
+				w.Header().Set("X-Incident-ID", incident.ID)
+				return HTTPError{
+					Message:    fmt.Sprintf("temporarily disabled after repeated failures (incident %s)", incident.ID),
+					StatusCode: http.StatusServiceUnavailable,
+					RetryAfter: time.Until(incident.Until),
+				}
+			}
+
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if p.opts.Logger != nil {
+					p.opts.Logger.Errorf(ctx, "[http.panic] Recovered from panic in %s: %v, request_id: %s", name, rec, GetRequestID(ctx))
+				}
+				p.recordPanic(ctx, name, rec)
+				err = NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+			}()
+			return next(ctx, w, r)
+		}
+	}
+}
+
+// disabled returns the incident of a disabled route, dropping expired ones.
+func (p *PanicIsolation) disabled(name string) (RouteIncident, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	incident, ok := p.incidents[name]
+	if ok && time.Now().After(incident.Until) {
+		delete(p.incidents, name)
+		return RouteIncident{}, false
+	}
+	return incident, ok
+}
+
+// recordPanic counts a panic of the route and disables it at the threshold.
+func (p *PanicIsolation) recordPanic(ctx context.Context, name string, rec any) {
+	now := time.Now()
+	p.mu.Lock()
+	recent := slices.DeleteFunc(p.panics[name], func(t time.Time) bool { return now.Sub(t) > p.opts.Window })
+	recent = append(recent, now)
+	if len(recent) < p.opts.Threshold {
+		p.panics[name] = recent
+		p.mu.Unlock()
+		return
+	}
+	delete(p.panics, name)
+	incident := RouteIncident{
+		ID:         generateRequestID(),
+		Route:      name,
+		Panics:     len(recent),
+		LastPanic:  fmt.Sprint(rec),
+		DisabledAt: now,
+		Until:      now.Add(p.opts.DisableFor),
+	}
+	p.incidents[name] = incident
+	p.mu.Unlock()
+
+	if p.opts.Logger != nil {
+		p.opts.Logger.Errorf(ctx, "[http.panic] Route %s disabled for %s after %d panics, incident: %s", name, p.opts.DisableFor, incident.Panics, incident.ID)
+	}
+	if p.opts.OnDisable != nil {
+		go p.opts.OnDisable(context.WithoutCancel(ctx), incident)
+	}
+}
+
+// Incidents returns the routes currently disabled, by route.
+func (p *PanicIsolation) Incidents() []RouteIncident {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var incidents []RouteIncident
+	for _, incident := range p.incidents {
+		if now.Before(incident.Until) {
+			incidents = append(incidents, incident)
+		}
+	}
+	slices.SortFunc(incidents, func(a, b RouteIncident) int { return strings.Compare(a.Route, b.Route) })
+	return incidents
+}
+
+// Enable re-enables a disabled route, given as "METHOD /path" (see
+// RouteIncident.Route), e.g. once a fix is deployed. It reports whether the
+// route was disabled.
+func (p *PanicIsolation) Enable(route string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.incidents[route]
+	delete(p.incidents, route)
+	delete(p.panics, route)
+	return ok
+}
@@ -0,0 +1,100 @@
+package shttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPanicIsolation(t *testing.T) {
+	alerts := make(chan RouteIncident, 1)
+	isolation := NewPanicIsolation(PanicIsolationOptions{
+		Threshold:  2,
+		DisableFor: time.Minute,
+		OnDisable:  func(ctx context.Context, incident RouteIncident) { alerts <- incident },
+	})
+	router := NewRouter()
+	router.Use(isolation.Middleware())
+	router.GET("/crash/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		panic("nil map")
+	})
+	router.GET("/ok", simpleHandler("ok"))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	for i := range 2 {
+		if w := serve("/crash/" + string(rune('a'+i))); w.Code != http.StatusInternalServerError {
+			t.Fatalf("panic %d: status = %d, want 500", i, w.Code)
+		}
+	}
+
+	var incident RouteIncident
+	select {
+	case incident = <-alerts:
+	case <-time.After(time.Second):
+		t.Fatal("OnDisable was not called")
+	}
+	if incident.Route != "GET /crash/{id}" || incident.Panics != 2 || incident.LastPanic != "nil map" {
+		t.Errorf("incident = %+v", incident)
+	}
+
+	w := serve("/crash/c")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("X-Incident-ID") != incident.ID {
+		t.Errorf("disabled route: %d, X-Incident-ID %q, want 503 and %q", w.Code, w.Header().Get("X-Incident-ID"), incident.ID)
+	}
+	if !strings.Contains(w.Body.String(), incident.ID) || w.Header().Get("Retry-After") == "" {
+		t.Errorf("disabled route body %q, Retry-After %q", w.Body.String(), w.Header().Get("Retry-After"))
+	}
+	if w := serve("/ok"); w.Code != http.StatusOK {
+		t.Errorf("other route: %d, want 200", w.Code)
+	}
+	if got := isolation.Incidents(); len(got) != 1 || got[0].ID != incident.ID {
+		t.Errorf("Incidents() = %+v", got)
+	}
+
+	if !isolation.Enable("GET /crash/{id}") {
+		t.Error("Enable() = false for a disabled route")
+	}
+	if w := serve("/crash/d"); w.Code != http.StatusInternalServerError {
+		t.Errorf("after Enable: %d, want the handler to run again", w.Code)
+	}
+}
+
+func TestPanicIsolationWindow(t *testing.T) {
+	isolation := NewPanicIsolation(PanicIsolationOptions{Threshold: 2, Window: 10 * time.Millisecond, DisableFor: 20 * time.Millisecond})
+	router := NewRouter()
+	router.Use(isolation.Middleware())
+	router.GET("/crash", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		panic("boom")
+	})
+	serve := func() int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/crash", nil))
+		return w.Code
+	}
+
+	// Panics further apart than Window do not add up
+	serve()
+	time.Sleep(20 * time.Millisecond)
+	serve()
+	if got := isolation.Incidents(); len(got) != 0 {
+		t.Fatalf("Incidents() = %+v, want the route still enabled", got)
+	}
+	serve()
+	if got := serve(); got != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want the route disabled", got)
+	}
+
+	// Disabled routes come back after DisableFor
+	time.Sleep(30 * time.Millisecond)
+	if got := serve(); got != http.StatusInternalServerError {
+		t.Errorf("status = %d after DisableFor, want the handler to run", got)
+	}
+}
@@ -0,0 +1,79 @@
+package shttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+)
+
+// JSON writes v as a JSON response with the given status. The response
+// headers declared by v are set first (see SetResponseHeaders). v is
+// encoded before anything is written, so an encoding error is returned
+// while the router can still answer with an error response.
+func JSON(w http.ResponseWriter, status int, v any) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+	SetResponseHeaders(w, v)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Versioned is implemented by resources that carry their own version, such
+// as a revision counter or an update timestamp. JSONWithETag derives their
+// ETag from it without encoding them.
+type Versioned interface {
+	ResourceVersion() string
+}
+
+// JSONWithETag writes v as a 200 JSON response like JSON, with an ETag so
+// clients and caches can revalidate it. The ETag is a strong one derived
+// from v.ResourceVersion() when v is Versioned, which clients can send back
+// in If-Match (see CheckIfMatchVersion), and a weak hash of the encoded body
+// otherwise. GET and HEAD requests whose If-None-Match matches get a 304
+// without a body; for a Versioned v, v is not even encoded.
+func JSONWithETag(w http.ResponseWriter, r *http.Request, v any) error {
+	var body []byte
+	var etag string
+	if versioned, ok := v.(Versioned); ok {
+		etag = versionETag(versioned.ResourceVersion())
+	} else {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(v); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+		sum := sha256.Sum256(body)
+		etag = `W/"` + hex.EncodeToString(sum[:8]) + `"`
+	}
+
+	w.Header().Set("ETag", etag)
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	if body == nil {
+		return JSON(w, http.StatusOK, v)
+	}
+	SetResponseHeaders(w, v)
+	w.Header().Set("Content-Type", "application/json")
+	_, err := w.Write(body)
+	return err
+}
+
+// versionETag builds a strong ETag from a resource version, hashing versions
+// with characters an entity tag cannot hold.
+func versionETag(version string) string {
+	for i := 0; i < len(version); i++ {
+		if c := version[i]; c == '"' || c <= ' ' || c == 0x7f {
+			sum := sha256.Sum256([]byte(version))
+			return `"` + hex.EncodeToString(sum[:8]) + `"`
+		}
+	}
+	return `"` + version + `"`
+}
@@ -0,0 +1,104 @@
+package shttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	type order struct {
+		ID       int    `json:"id"`
+		Location string `json:"-" header:"Location"`
+	}
+
+	t.Run("writes status, headers and body", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		if err := JSON(w, http.StatusCreated, order{ID: 7, Location: "/orders/7"}); err != nil {
+			t.Fatalf("JSON() error = %v", err)
+		}
+		if w.Code != http.StatusCreated {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusCreated)
+		}
+		if got := w.Header().Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", got)
+		}
+		if got := w.Header().Get("Location"); got != "/orders/7" {
+			t.Errorf("Location = %q, want /orders/7", got)
+		}
+		if got := w.Body.String(); got != "{\"id\":7}\n" {
+			t.Errorf("body = %q", got)
+		}
+	})
+
+	t.Run("encoding error writes nothing", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		if err := JSON(w, http.StatusOK, map[string]any{"f": func() {}}); err == nil {
+			t.Fatal("JSON() error = nil, want encoding error")
+		}
+		if w.Body.Len() != 0 || w.Header().Get("Content-Type") != "" {
+			t.Errorf("response written despite the encoding error: %q", w.Body.String())
+		}
+	})
+}
+
+type versionedDoc struct {
+	ID      int `json:"id"`
+	Version int `json:"version"`
+}
+
+func (d versionedDoc) ResourceVersion() string { return strconv.Itoa(d.Version) }
+
+func TestJSONWithETag(t *testing.T) {
+	plain := map[string]int{"id": 7}
+	w := httptest.NewRecorder()
+	if err := JSONWithETag(w, httptest.NewRequest(http.MethodGet, "/", nil), plain); err != nil {
+		t.Fatalf("JSONWithETag() error = %v", err)
+	}
+	plainETag := w.Header().Get("ETag")
+	if !strings.HasPrefix(plainETag, `W/"`) || w.Body.String() != "{\"id\":7}\n" {
+		t.Fatalf("JSONWithETag() = ETag %q, body %q", plainETag, w.Body.String())
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		v           any
+		ifNoneMatch string
+		want        int
+		wantETag    string
+	}{
+		{name: "Hashed body matches", method: http.MethodGet, v: plain, ifNoneMatch: plainETag, want: http.StatusNotModified, wantETag: plainETag},
+		{name: "Strong form matches too", method: http.MethodGet, v: plain, ifNoneMatch: strings.TrimPrefix(plainETag, "W/"), want: http.StatusNotModified, wantETag: plainETag},
+		{name: "Changed body", method: http.MethodGet, v: map[string]int{"id": 8}, ifNoneMatch: plainETag, want: http.StatusOK},
+		{name: "Version matches", method: http.MethodGet, v: versionedDoc{ID: 1, Version: 3}, ifNoneMatch: `"3"`, want: http.StatusNotModified, wantETag: `"3"`},
+		{name: "Weak form of the version matches", method: http.MethodGet, v: versionedDoc{ID: 1, Version: 3}, ifNoneMatch: `W/"3"`, want: http.StatusNotModified, wantETag: `"3"`},
+		{name: "Version changed", method: http.MethodGet, v: versionedDoc{ID: 1, Version: 4}, ifNoneMatch: `"3"`, want: http.StatusOK, wantETag: `"4"`},
+		{name: "Not a GET", method: http.MethodPut, v: versionedDoc{ID: 1, Version: 3}, ifNoneMatch: `"3"`, want: http.StatusOK, wantETag: `"3"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			w := httptest.NewRecorder()
+			if err := JSONWithETag(w, req, tt.v); err != nil {
+				t.Fatalf("JSONWithETag() error = %v", err)
+			}
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.wantETag != "" && w.Header().Get("ETag") != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", w.Header().Get("ETag"), tt.wantETag)
+			}
+			if tt.want == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("304 with a body: %q", w.Body.String())
+			}
+		})
+	}
+
+	if got := versionETag(`2024-01-01 "x"`); !strings.HasPrefix(got, `"`) || strings.Count(got, `"`) != 2 {
+		t.Errorf("versionETag() = %s, want a hashed tag", got)
+	}
+}
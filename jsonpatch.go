@@ -0,0 +1,400 @@
+package shttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSONPatchOperation is one operation of a JSON Patch document (RFC 6902).
+type JSONPatchOperation struct {
+	// "add", "remove", "replace", "move", "copy" or "test"
+	Op string `json:"op"`
+
+	// JSON Pointer (RFC 6901) to the target location, e.g. "/tags/0"
+	Path string `json:"path"`
+
+	// Source location of move and copy
+	From string `json:"from,omitempty"`
+
+	// Value of add, replace and test; nil when absent, "null" for null
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch is a JSON Patch document: operations applied in order, all or
+// nothing.
+type JSONPatch []JSONPatchOperation
+
+// BindJSONPatch decodes a JSON Patch document from the request body, whose
+// Content-Type must be application/json-patch+json, and checks that its
+// operations are well-formed. Refused input is reported as an HTTPError
+// (400, 413 or 415).
+func BindJSONPatch(r *http.Request) (JSONPatch, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json-patch+json" {
+		return nil, NewHTTPError(http.StatusUnsupportedMediaType, "Content-Type must be application/json-patch+json")
+	}
+
+	// Operations may carry members the RFC says to ignore
+	opts := StrictBindOptions()
+	opts.RequireJSONContentType = false
+	opts.DisallowUnknownFields = false
+	var patch JSONPatch
+	if err := BindWithOptions(r, &patch, opts); err != nil {
+		return nil, err
+	}
+	if err := patch.Validate(); err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+// Validate checks that every operation is well-formed: a known op, valid
+// pointers, and the members the op requires. Errors are 400 HTTPErrors.
+func (p JSONPatch) Validate() error {
+	if p == nil {
+		return NewHTTPError(http.StatusBadRequest, "invalid JSON patch: the document must be an array of operations")
+	}
+	for i, op := range p {
+		invalid := func(msg string) error {
+			return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid JSON patch: operation %d: %s", i, msg))
+		}
+		if _, err := parseJSONPointer(op.Path); err != nil {
+			return invalid(err.Error())
+		}
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return invalid(fmt.Sprintf("%q needs a value", op.Op))
+			}
+		case "move", "copy":
+			if _, err := parseJSONPointer(op.From); err != nil {
+				return invalid("from: " + err.Error())
+			}
+			if op.Op == "move" && strings.HasPrefix(op.Path, op.From+"/") {
+				return invalid("cannot move a value into itself")
+			}
+		case "remove":
+		default:
+			return invalid(fmt.Sprintf("unknown op %q", op.Op))
+		}
+	}
+	return nil
+}
+
+// Apply applies the patch to target, a pointer to the value to patch (a
+// struct, map or any). The operations run on the JSON encoding of target,
+// which is then decoded into a zeroed value, so fields without a JSON
+// representation (unexported, or tagged "-") are reset: patch transfer
+// types, not storage types. Nothing changes unless every operation
+// succeeds. A failed "test" is reported as 409 Conflict, a path that does
+// not exist or a result that does not fit target's type as 422.
+func (p JSONPatch) Apply(target any) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("shttp: JSONPatch.Apply needs a non-nil pointer, got %T", target)
+	}
+	data, err := json.Marshal(target)
+	if err != nil {
+		return err
+	}
+	patched, err := p.ApplyJSON(data)
+	if err != nil {
+		return err
+	}
+	result := reflect.New(rv.Elem().Type())
+	if err := json.Unmarshal(patched, result.Interface()); err != nil {
+		return NewHTTPError(http.StatusUnprocessableEntity, "JSON patch result is invalid: "+err.Error())
+	}
+	rv.Elem().Set(result.Elem())
+	return nil
+}
+
+// ApplyJSON applies the patch to a JSON document and returns the patched
+// document, with the errors of Apply.
+func (p JSONPatch) ApplyJSON(data []byte) ([]byte, error) {
+	doc, err := decodeJSONValue(data)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range p {
+		if doc, err = op.apply(doc); err != nil {
+			var httpErr HTTPError
+			if errors.As(err, &httpErr) {
+				httpErr.Message = fmt.Sprintf("JSON patch operation %d (%s %s): %s", i, op.Op, op.Path, httpErr.Message)
+				return nil, httpErr
+			}
+			return nil, err
+		}
+	}
+	return json.Marshal(doc)
+}
+
+// decodeJSONValue decodes data into maps, slices and json.Numbers.
+func decodeJSONValue(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// apply runs one operation on doc and returns the new document.
+func (op JSONPatchOperation) apply(doc any) (any, error) {
+	path, err := parseJSONPointer(op.Path)
+	if err != nil {
+		return nil, NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	var value any
+	if op.Value != nil {
+		if value, err = decodeJSONValue(op.Value); err != nil {
+			return nil, NewHTTPError(http.StatusBadRequest, "invalid value: "+err.Error())
+		}
+	}
+
+	switch op.Op {
+	case "add":
+		return jsonAdd(doc, path, value)
+	case "remove":
+		doc, _, err := jsonRemove(doc, path)
+		return doc, err
+	case "replace":
+		if doc, _, err = jsonRemove(doc, path); err != nil {
+			return nil, err
+		}
+		return jsonAdd(doc, path, value)
+	case "move", "copy":
+		from, err := parseJSONPointer(op.From)
+		if err != nil {
+			return nil, NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		var moved any
+		if op.Op == "move" {
+			doc, moved, err = jsonRemove(doc, from)
+		} else {
+			moved, err = jsonGet(doc, from)
+			moved = copyJSONValue(moved)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return jsonAdd(doc, path, moved)
+	case "test":
+		current, err := jsonGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equalJSONValues(current, value) {
+			return nil, NewHTTPError(http.StatusConflict, "test failed: the value differs")
+		}
+		return doc, nil
+	}
+	return nil, NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unknown op %q", op.Op))
+}
+
+// parseJSONPointer splits a JSON Pointer into its unescaped reference
+// tokens.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func pathNotFound(token string) error {
+	return NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("path not found at %q", token))
+}
+
+// arrayIndex parses an array index token; "-" (past the end) is only
+// accepted when allowEnd is set.
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return length, nil
+	}
+	// Digits only, without leading zeros
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+		return 0, pathNotFound(token)
+	}
+	i, err := strconv.Atoi(token)
+	limit := length
+	if allowEnd {
+		limit++
+	}
+	if err != nil || i < 0 || i >= limit {
+		return 0, pathNotFound(token)
+	}
+	return i, nil
+}
+
+// jsonGet returns the value at path.
+func jsonGet(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			v, ok := node[token]
+			if !ok {
+				return nil, pathNotFound(token)
+			}
+			doc = v
+		case []any:
+			i, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, pathNotFound(token)
+		}
+	}
+	return doc, nil
+}
+
+// jsonUpdate calls fn on the container holding the last token of path and
+// returns the document with the container fn returned in its place.
+func jsonUpdate(doc any, path []string, fn func(container any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	child, err := jsonGet(doc, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	if child, err = jsonUpdate(child, path[1:], fn); err != nil {
+		return nil, err
+	}
+	switch node := doc.(type) {
+	case map[string]any:
+		node[path[0]] = child
+	case []any:
+		i, _ := arrayIndex(path[0], len(node), false)
+		node[i] = child
+	}
+	return doc, nil
+}
+
+// jsonAdd adds value at path: a member is set, an array element inserted.
+func jsonAdd(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return jsonUpdate(doc, path, func(container any, token string) (any, error) {
+		switch node := container.(type) {
+		case map[string]any:
+			node[token] = value
+			return node, nil
+		case []any:
+			i, err := arrayIndex(token, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		return nil, pathNotFound(token)
+	})
+}
+
+// jsonRemove removes the value at path, which must exist, and returns it.
+func jsonRemove(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+	var removed any
+	doc, err := jsonUpdate(doc, path, func(container any, token string) (any, error) {
+		switch node := container.(type) {
+		case map[string]any:
+			v, ok := node[token]
+			if !ok {
+				return nil, pathNotFound(token)
+			}
+			removed = v
+			delete(node, token)
+			return node, nil
+		case []any:
+			i, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = node[i]
+			return append(node[:i], node[i+1:]...), nil
+		}
+		return nil, pathNotFound(token)
+	})
+	return doc, removed, err
+}
+
+// copyJSONValue deep-copies a decoded JSON value.
+func copyJSONValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for k, e := range v {
+			c[k] = copyJSONValue(e)
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for i, e := range v {
+			c[i] = copyJSONValue(e)
+		}
+		return c
+	}
+	return v
+}
+
+// equalJSONValues compares decoded JSON values, numbers by value.
+func equalJSONValues(a, b any) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, errA := a.Float64()
+		bf, errB := b.Float64()
+		if errA != nil || errB != nil {
+			return a == b
+		}
+		return af == bf
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if w, ok := b[k]; !ok || !equalJSONValues(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equalJSONValues(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
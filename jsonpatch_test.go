@@ -0,0 +1,109 @@
+package shttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type patchDoc struct {
+	Name  string            `json:"name"`
+	Tags  []string          `json:"tags"`
+	Attrs map[string]string `json:"attrs,omitempty"`
+}
+
+func TestJSONPatchApply(t *testing.T) {
+	base := func() patchDoc {
+		return patchDoc{Name: "widget", Tags: []string{"a", "b"}, Attrs: map[string]string{"color": "red", "a/b": "slash"}}
+	}
+	tests := []struct {
+		name       string
+		patch      JSONPatch
+		want       patchDoc
+		wantStatus int
+	}{
+		{
+			name:  "add and remove",
+			patch: JSONPatch{{Op: "add", Path: "/tags/1", Value: []byte(`"x"`)}, {Op: "add", Path: "/tags/-", Value: []byte(`"z"`)}, {Op: "remove", Path: "/attrs/a~1b"}},
+			want:  patchDoc{Name: "widget", Tags: []string{"a", "x", "b", "z"}, Attrs: map[string]string{"color": "red"}},
+		},
+		{
+			name:  "replace guarded by test",
+			patch: JSONPatch{{Op: "test", Path: "/name", Value: []byte(`"widget"`)}, {Op: "replace", Path: "/name", Value: []byte(`"gadget"`)}},
+			want:  patchDoc{Name: "gadget", Tags: []string{"a", "b"}, Attrs: map[string]string{"color": "red", "a/b": "slash"}},
+		},
+		{
+			name:  "move and copy",
+			patch: JSONPatch{{Op: "move", From: "/attrs/color", Path: "/attrs/colour"}, {Op: "copy", From: "/tags/0", Path: "/tags/-"}},
+			want:  patchDoc{Name: "widget", Tags: []string{"a", "b", "a"}, Attrs: map[string]string{"colour": "red", "a/b": "slash"}},
+		},
+		{
+			name:       "failed test",
+			patch:      JSONPatch{{Op: "replace", Path: "/name", Value: []byte(`"gadget"`)}, {Op: "test", Path: "/name", Value: []byte(`"widget"`)}},
+			wantStatus: http.StatusConflict,
+		},
+		{name: "missing member", patch: JSONPatch{{Op: "remove", Path: "/attrs/size"}}, wantStatus: http.StatusUnprocessableEntity},
+		{name: "index out of range", patch: JSONPatch{{Op: "replace", Path: "/tags/5", Value: []byte(`"x"`)}}, wantStatus: http.StatusUnprocessableEntity},
+		{name: "invalid index", patch: JSONPatch{{Op: "add", Path: "/tags/01", Value: []byte(`"x"`)}}, wantStatus: http.StatusUnprocessableEntity},
+		{name: "wrong type", patch: JSONPatch{{Op: "replace", Path: "/name", Value: []byte(`42`)}}, wantStatus: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := base()
+			err := tt.patch.Apply(&doc)
+			if tt.wantStatus != 0 {
+				var httpErr HTTPError
+				if !errors.As(err, &httpErr) || httpErr.StatusCode != tt.wantStatus {
+					t.Fatalf("Apply() error = %v, want status %d", err, tt.wantStatus)
+				}
+				if !reflect.DeepEqual(doc, base()) {
+					t.Errorf("failed patch modified the target: %+v", doc)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if !reflect.DeepEqual(doc, tt.want) {
+				t.Errorf("patched = %+v, want %+v", doc, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindJSONPatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{name: "valid", contentType: "application/json-patch+json", body: `[{"op": "add", "path": "/a", "value": null, "comment": "ignored"}]`},
+		{name: "wrong content type", contentType: "application/json", body: `[]`, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "not an array", contentType: "application/json-patch+json", body: `{"op": "add"}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown op", contentType: "application/json-patch+json", body: `[{"op": "merge", "path": "/a"}]`, wantStatus: http.StatusBadRequest},
+		{name: "missing value", contentType: "application/json-patch+json", body: `[{"op": "replace", "path": "/a"}]`, wantStatus: http.StatusBadRequest},
+		{name: "invalid pointer", contentType: "application/json-patch+json", body: `[{"op": "remove", "path": "a"}]`, wantStatus: http.StatusBadRequest},
+		{name: "move into itself", contentType: "application/json-patch+json", body: `[{"op": "move", "from": "/a", "path": "/a/b"}]`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			patch, err := BindJSONPatch(req)
+			if tt.wantStatus == 0 {
+				if err != nil || len(patch) != 1 || string(patch[0].Value) != "null" {
+					t.Errorf("BindJSONPatch() = %+v, %v", patch, err)
+				}
+				return
+			}
+			var httpErr HTTPError
+			if !errors.As(err, &httpErr) || httpErr.StatusCode != tt.wantStatus {
+				t.Errorf("BindJSONPatch() error = %v, want status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package shttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TimeFormat is the layout Time is encoded with: RFC 3339 in UTC with
+// millisecond precision, e.g. "2024-05-01T12:00:00.000Z".
+const TimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Time is a time.Time with a uniform JSON encoding: TimeFormat in UTC, and
+// null for the zero time. Use it for the timestamps of response types so
+// every service formats them the same way:
+//
+//	type Order struct {
+//		ID        string      `json:"id"`
+//		CreatedAt shttp.Time  `json:"created_at"`
+//		ShippedAt shttp.Time  `json:"shipped_at,omitzero"`
+//	}
+//
+// Decoding accepts any RFC 3339 timestamp, and null.
+type Time struct {
+	time.Time
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(`"` + t.UTC().Format(TimeFormat) + `"`), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *Time) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		t.Time = time.Time{}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("shttp: time must be an RFC 3339 string: %w", err)
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return fmt.Errorf("shttp: time must be an RFC 3339 string: %w", err)
+	}
+	t.Time = parsed
+	return nil
+}
+
+// Duration is a time.Duration encoded in JSON as a number of milliseconds,
+// with a fractional part below the millisecond (1500 for 1.5s, 0.25 for
+// 250µs). Decoding accepts such numbers and Go duration strings ("1.5s").
+type Duration time.Duration
+
+// Std returns d as a time.Duration.
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+// String formats d like time.Duration.
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	ms := float64(d) / float64(time.Millisecond)
+	return strconv.AppendFloat(nil, ms, 'f', -1, 64), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("shttp: invalid duration %q", s)
+		}
+		*d = Duration(parsed)
+		return nil
+	}
+	ms, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("shttp: duration must be a number of milliseconds or a duration string, got %s", data)
+	}
+	*d = Duration(ms * float64(time.Millisecond))
+	return nil
+}
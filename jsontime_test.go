@@ -0,0 +1,74 @@
+package shttp
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimeJSON(t *testing.T) {
+	paris := time.FixedZone("CEST", 2*60*60)
+	tests := []struct {
+		name string
+		in   Time
+		want string
+	}{
+		{name: "UTC with milliseconds", in: Time{time.Date(2024, 5, 1, 14, 0, 0, 123456789, paris)}, want: `"2024-05-01T12:00:00.123Z"`},
+		{name: "whole seconds", in: Time{time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}, want: `"2024-05-01T12:00:00.000Z"`},
+		{name: "zero", in: Time{}, want: "null"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.in)
+			if err != nil || string(got) != tt.want {
+				t.Fatalf("Marshal() = %s, %v; want %s", got, err, tt.want)
+			}
+			var back Time
+			if err := json.Unmarshal(got, &back); err != nil {
+				t.Fatalf("Unmarshal(%s) error = %v", got, err)
+			}
+			if !back.Equal(tt.in.Truncate(time.Millisecond)) {
+				t.Errorf("round trip = %v, want %v", back, tt.in)
+			}
+		})
+	}
+
+	var v Time
+	if err := json.Unmarshal([]byte(`"2024-05-01T14:00:00+02:00"`), &v); err != nil || !v.Equal(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unmarshal(offset) = %v, %v", v, err)
+	}
+	if err := json.Unmarshal([]byte(`"yesterday"`), &v); err == nil {
+		t.Error("Unmarshal(yesterday) error = nil")
+	}
+}
+
+func TestDurationJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Duration
+	}{
+		{in: `1500`, want: Duration(1500 * time.Millisecond)},
+		{in: `0.25`, want: Duration(250 * time.Microsecond)},
+		{in: `"2m"`, want: Duration(2 * time.Minute)},
+	}
+	for _, tt := range tests {
+		var got Duration
+		if err := json.Unmarshal([]byte(tt.in), &got); err != nil || got != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{`"soon"`, `true`} {
+		var d Duration
+		if err := json.Unmarshal([]byte(bad), &d); err == nil {
+			t.Errorf("Unmarshal(%s) error = nil", bad)
+		}
+	}
+
+	got, err := json.Marshal(struct {
+		Elapsed Duration `json:"elapsed_ms"`
+		Short   Duration `json:"short_ms"`
+	}{Duration(1500 * time.Millisecond), Duration(250 * time.Microsecond)})
+	if want := `{"elapsed_ms":1500,"short_ms":0.25}`; err != nil || string(got) != want {
+		t.Errorf("Marshal() = %s, %v; want %s", got, err, want)
+	}
+}
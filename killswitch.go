@@ -0,0 +1,162 @@
+package shttp
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KillSwitchFlagPrefix prefixes the feature flags that kill routes: a route
+// registered with KillSwitch("checkout") is disabled for the requests whose
+// "kill:checkout" flag is enabled (see FeatureFlagMiddleware).
+const KillSwitchFlagPrefix = "kill:"
+
+// defaultKillMessage answers requests to killed routes unless Kill is given
+// a message.
+const defaultKillMessage = "This feature is temporarily disabled"
+
+// KillSwitchState describes a kill switch turned on with Kill.
+type KillSwitchState struct {
+	Key string `json:"key"`
+
+	// Status and message answering the requests of the routes it disables
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+
+	Since time.Time `json:"since"`
+}
+
+// KillSwitch attaches the route to the kill switch key, shared by every
+// route of a feature. While the switch is on (see Router.Kill), or the
+// request's "kill:<key>" feature flag is enabled, the route answers without
+// running its handler. The check runs after the router's middleware, so
+// killed requests are logged and flags resolved by FeatureFlagMiddleware
+// apply.
+func KillSwitch(key string) RouteOption {
+	return func(rt *route) {
+		rt.killSwitch = key
+	}
+}
+
+// Kill turns the kill switch key on: the routes registered with
+// KillSwitch(key) answer with status (503 when 0; 410 Gone tells clients
+// the feature is not coming back) and message (a default explanation when
+// empty) until Revive is called. It is safe to call while serving.
+func (r *Router) Kill(key string, status int, message string) {
+	if status == 0 {
+		status = http.StatusServiceUnavailable
+	}
+	if message == "" {
+		message = defaultKillMessage
+	}
+	state := KillSwitchState{Key: key, Status: status, Message: message, Since: time.Now()}
+	r.updateKillSwitches(func(m map[string]KillSwitchState) { m[key] = state })
+}
+
+// Revive turns the kill switch key off.
+func (r *Router) Revive(key string) {
+	r.updateKillSwitches(func(m map[string]KillSwitchState) { delete(m, key) })
+}
+
+// KillSwitches returns the kill switches turned on, by key.
+func (r *Router) KillSwitches() []KillSwitchState {
+	var states []KillSwitchState
+	if m := r.root().killed.Load(); m != nil {
+		states = slices.Collect(maps.Values(*m))
+	}
+	slices.SortFunc(states, func(a, b KillSwitchState) int { return strings.Compare(a.Key, b.Key) })
+	return states
+}
+
+// updateKillSwitches replaces the kill switches with a modified copy, so
+// requests read them without locking.
+func (r *Router) updateKillSwitches(fn func(map[string]KillSwitchState)) {
+	root := r.root()
+	for {
+		old := root.killed.Load()
+		m := make(map[string]KillSwitchState)
+		if old != nil {
+			maps.Copy(m, *old)
+		}
+		fn(m)
+		if root.killed.CompareAndSwap(old, &m) {
+			return
+		}
+	}
+}
+
+// killSwitchGuard wraps the handler of a route attached to kill switch key.
+func (r *Router) killSwitchGuard(key string, handler Handler) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, req *http.Request) error {
+		if m := r.killed.Load(); m != nil {
+			if state, ok := (*m)[key]; ok {
+				return NewHTTPError(state.Status, state.Message)
+			}
+		}
+		if FlagEnabled(ctx, KillSwitchFlagPrefix+key) {
+			return NewHTTPError(http.StatusServiceUnavailable, defaultKillMessage)
+		}
+		return handler(ctx, w, req)
+	}
+}
+
+// Kill turns a kill switch on (see Router.Kill).
+func (s *Server) Kill(key string, status int, message string) {
+	s.Router().Kill(key, status, message)
+	s.logger.Warn(s.ctx, "[server.killswitch] Kill switch turned on", "key", key, "status", status, "message", message)
+}
+
+// Revive turns a kill switch off (see Router.Revive).
+func (s *Server) Revive(key string) {
+	s.Router().Revive(key)
+	s.logger.Info(s.ctx, "[server.killswitch] Kill switch turned off", "key", key)
+}
+
+// KillSwitchesHandler is an admin endpoint for the kill switches:
+//
+//	GET    lists the switches turned on as JSON
+//	POST   turns ?key= on, with the optional ?status= (503 or 410) and ?message=
+//	DELETE turns ?key= off
+func (s *Server) KillSwitchesHandler() Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		query := r.URL.Query()
+		key := query.Get("key")
+		switch r.Method {
+		case http.MethodGet:
+			states := s.Router().KillSwitches()
+			if states == nil {
+				states = []KillSwitchState{}
+			}
+			return JSON(w, http.StatusOK, states)
+		case http.MethodPost:
+			if key == "" {
+				return NewHTTPError(http.StatusBadRequest, "key is required")
+			}
+			status := http.StatusServiceUnavailable
+			if v := query.Get("status"); v != "" {
+				var err error
+				status, err = strconv.Atoi(v)
+				if err != nil || (status != http.StatusServiceUnavailable && status != http.StatusGone) {
+					return NewHTTPError(http.StatusBadRequest, "status must be 503 or 410")
+				}
+			}
+			s.Kill(key, status, query.Get("message"))
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		case http.MethodDelete:
+			if key == "" {
+				return NewHTTPError(http.StatusBadRequest, "key is required")
+			}
+			s.Revive(key)
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			return NewHTTPError(http.StatusMethodNotAllowed, "method not allowed")
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package shttp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andres-vara/slogr"
+)
+
+func TestServerKillSwitch(t *testing.T) {
+	server := New(context.Background(), &Config{
+		Logger: slogr.New(io.Discard, slogr.DefaultOptions()),
+		FlagProvider: FlagProviderFunc(func(ctx context.Context, r *http.Request) (map[string]bool, error) {
+			return map[string]bool{"kill:checkout": r.Header.Get("X-Tenant") == "beta"}, nil
+		}),
+	})
+	server.Use(server.FeatureFlagMiddleware())
+	server.POST("/checkout", simpleHandler("paid"), KillSwitch("checkout"))
+	server.GET("/cart", simpleHandler("cart"), KillSwitch("checkout"))
+	server.GET("/catalog", simpleHandler("catalog"))
+	admin := server.KillSwitchesHandler()
+	server.Handle(http.MethodGet, "/admin/kill", admin)
+	server.Handle(http.MethodPost, "/admin/kill", admin)
+	server.Handle(http.MethodDelete, "/admin/kill", admin)
+
+	do := func(method, target string, header http.Header) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name     string
+		setup    string
+		method   string
+		path     string
+		header   http.Header
+		want     int
+		wantBody string
+	}{
+		{name: "Switch off", method: http.MethodPost, path: "/checkout", want: http.StatusOK, wantBody: "paid"},
+		{name: "Flag on for the tenant", method: http.MethodPost, path: "/checkout", header: http.Header{"X-Tenant": {"beta"}}, want: http.StatusServiceUnavailable, wantBody: defaultKillMessage},
+		{name: "Killed with 410", setup: "POST /admin/kill?key=checkout&status=410&message=Checkout+moved", method: http.MethodGet, path: "/cart", want: http.StatusGone, wantBody: "Checkout moved"},
+		{name: "Other routes unaffected", method: http.MethodGet, path: "/catalog", want: http.StatusOK, wantBody: "catalog"},
+		{name: "Revived", setup: "DELETE /admin/kill?key=checkout", method: http.MethodPost, path: "/checkout", want: http.StatusOK, wantBody: "paid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != "" {
+				method, target, _ := strings.Cut(tt.setup, " ")
+				if rec := do(method, target, nil); rec.Code != http.StatusNoContent {
+					t.Fatalf("%s = %d %s", tt.setup, rec.Code, rec.Body.String())
+				}
+			}
+			rec := do(tt.method, tt.path, tt.header)
+			if rec.Code != tt.want || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.path, rec.Code, rec.Body.String(), tt.want, tt.wantBody)
+			}
+		})
+	}
+
+	server.Kill("search", 0, "")
+	var states []KillSwitchState
+	if err := json.Unmarshal(do(http.MethodGet, "/admin/kill", nil).Body.Bytes(), &states); err != nil {
+		t.Fatalf("decoding states: %v", err)
+	}
+	if len(states) != 1 || states[0].Key != "search" || states[0].Status != http.StatusServiceUnavailable {
+		t.Errorf("states = %+v, want search killed with 503", states)
+	}
+	if rec := do(http.MethodPost, "/admin/kill?key=x&status=200", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("POST with status 200 = %d, want 400", rec.Code)
+	}
+}
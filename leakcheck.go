@@ -0,0 +1,105 @@
+package shttp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+
+	"github.com/andres-vara/slogr"
+)
+
+// ErrUseAfterReturn is returned by request bodies and response writers that
+// are used after their handler returned, when leak detection is enabled.
+var ErrUseAfterReturn = errors.New("shttp: request used after its handler returned")
+
+// DetectUseAfterReturn enables a debug mode in which request bodies and
+// response writers refuse to be used once their handler has returned. Such
+// use happens when a handler leaks them into a goroutine, and corrupts
+// responses or races with the server. Offending calls fail with
+// ErrUseAfterReturn and are logged to logger once per request, with the
+// route and the stack of the goroutine responsible. A nil logger disables
+// the mode. It adds a little overhead to every request and is meant for
+// development and staging.
+func (r *Router) DetectUseAfterReturn(logger *slogr.Logger) {
+	r.root().leakLogger.Store(logger)
+}
+
+// leakGuard tracks whether the handler of a request has returned.
+type leakGuard struct {
+	ctx      context.Context
+	logger   *slogr.Logger
+	route    *route
+	returned atomic.Bool
+	reported atomic.Bool
+}
+
+// allow reports whether the request may still be used, logging the first
+// use after the handler returned.
+func (g *leakGuard) allow(what string) bool {
+	if !g.returned.Load() {
+		return true
+	}
+	if g.reported.CompareAndSwap(false, true) {
+		g.logger.Errorf(g.ctx, "[http.use_after_return] %s after the handler returned, route: %s, request_id: %s\n%s", what, g.route, GetRequestID(g.ctx), debug.Stack())
+	}
+	return false
+}
+
+// guardedBody is a request body that fails once the handler returned.
+type guardedBody struct {
+	io.ReadCloser
+	guard *leakGuard
+}
+
+func (b *guardedBody) Read(p []byte) (int, error) {
+	if !b.guard.allow("request body read") {
+		return 0, ErrUseAfterReturn
+	}
+	return b.ReadCloser.Read(p)
+}
+
+// guardedWriter is a response writer that fails once the handler returned.
+type guardedWriter struct {
+	http.ResponseWriter
+	guard *leakGuard
+}
+
+func (w *guardedWriter) Header() http.Header {
+	if !w.guard.allow("response header access") {
+		// Hand out a throwaway map so the live response is never touched
+		return make(http.Header)
+	}
+	return w.ResponseWriter.Header()
+}
+
+func (w *guardedWriter) WriteHeader(status int) {
+	if w.guard.allow("response WriteHeader") {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *guardedWriter) Write(b []byte) (int, error) {
+	if !w.guard.allow("response write") {
+		return 0, ErrUseAfterReturn
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *guardedWriter) FlushError() error {
+	if !w.guard.allow("response flush") {
+		return ErrUseAfterReturn
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *guardedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if !w.guard.allow("connection hijack") {
+		return nil, nil, ErrUseAfterReturn
+	}
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
@@ -0,0 +1,103 @@
+package shttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andres-vara/slogr"
+)
+
+// syncBuffer is a log sink safe for concurrent writes.
+type syncBuffer struct {
+	mu sync.Mutex
+	sb strings.Builder
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sb.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sb.String()
+}
+
+func TestDetectUseAfterReturn(t *testing.T) {
+	tests := []struct {
+		name string
+		use  func(w http.ResponseWriter, r *http.Request) error
+		what string
+	}{
+		{
+			name: "Write after return",
+			use: func(w http.ResponseWriter, r *http.Request) error {
+				_, err := w.Write([]byte("late"))
+				return err
+			},
+			what: "response write",
+		},
+		{
+			name: "Body read after return",
+			use: func(w http.ResponseWriter, r *http.Request) error {
+				_, err := io.ReadAll(r.Body)
+				return err
+			},
+			what: "request body read",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logOutput syncBuffer
+			router := NewRouter()
+			router.DetectUseAfterReturn(slogr.New(&logOutput, slogr.DefaultOptions()))
+
+			leaked := make(chan func() error, 1)
+			router.POST("/jobs/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				leaked <- func() error { return tt.use(w, r) }
+				w.Write([]byte("accepted"))
+				return nil
+			})
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs/1", strings.NewReader("payload")))
+
+			// Simulate the goroutine the handler leaked its request into
+			if err := (<-leaked)(); !errors.Is(err, ErrUseAfterReturn) {
+				t.Fatalf("err = %v, want ErrUseAfterReturn", err)
+			}
+			if rec.Body.String() != "accepted" {
+				t.Errorf("response body = %q, late write reached the client", rec.Body.String())
+			}
+			logStr := logOutput.String()
+			for _, want := range []string{"[http.use_after_return]", tt.what, "POST /jobs/{id}", "leakcheck_test.go"} {
+				if !strings.Contains(logStr, want) {
+					t.Errorf("log does not contain %q: %q", want, logStr)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectUseAfterReturnDisabled(t *testing.T) {
+	router := NewRouter()
+	var leakedWriter http.ResponseWriter
+	router.GET("/", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		leakedWriter = w
+		return nil
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if _, err := leakedWriter.Write([]byte("x")); err != nil {
+		t.Errorf("write without detection = %v, want nil", err)
+	}
+}
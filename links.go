@@ -0,0 +1,107 @@
+package shttp
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// Link is a hypermedia link in the HAL style.
+type Link struct {
+	Href string `json:"href"`
+}
+
+// Links maps link relations (self, next, prev, ...) to links. Embed it in
+// response types as
+//
+//	Links shttp.Links `json:"_links"`
+type Links map[string]Link
+
+// Page describes the pagination state of a collection response.
+type Page struct {
+	// 1-based page number
+	Number int
+
+	// Items per page
+	Size int
+
+	// Total number of items, 0 when unknown (no "last" link is produced)
+	Total int
+}
+
+// LinkBuilder builds Links from named routes (see Name). The first error is
+// kept and returned by Build, so calls can be chained.
+type LinkBuilder struct {
+	ctx   context.Context
+	links Links
+	err   error
+}
+
+// NewLinks starts building links with the router serving the request
+// carried by ctx.
+func NewLinks(ctx context.Context) *LinkBuilder {
+	return &LinkBuilder{ctx: ctx, links: make(Links)}
+}
+
+// Self adds the "self" link to the named route.
+func (b *LinkBuilder) Self(route string, params map[string]string) *LinkBuilder {
+	return b.Add("self", route, params)
+}
+
+// Add adds a link with the given relation to the named route.
+func (b *LinkBuilder) Add(rel, route string, params map[string]string) *LinkBuilder {
+	return b.add(rel, route, params, nil)
+}
+
+// Paginate adds the "self", "first", "prev", "next" and "last" links of a
+// collection served by the named route, passing the page number and size as
+// the "page" and "limit" query parameters along with query. "prev" and
+// "next" are omitted on the first and last page, "last" when the total is
+// unknown.
+func (b *LinkBuilder) Paginate(route string, params map[string]string, query url.Values, page Page) *LinkBuilder {
+	pageQuery := func(n int) url.Values {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		q.Set("page", strconv.Itoa(n))
+		q.Set("limit", strconv.Itoa(page.Size))
+		return q
+	}
+
+	b.add("self", route, params, pageQuery(page.Number))
+	b.add("first", route, params, pageQuery(1))
+	if page.Number > 1 {
+		b.add("prev", route, params, pageQuery(page.Number-1))
+	}
+	last := 0
+	if page.Total > 0 && page.Size > 0 {
+		last = (page.Total + page.Size - 1) / page.Size
+		b.add("last", route, params, pageQuery(last))
+	}
+	if last == 0 || page.Number < last {
+		b.add("next", route, params, pageQuery(page.Number+1))
+	}
+	return b
+}
+
+func (b *LinkBuilder) add(rel, route string, params map[string]string, query url.Values) *LinkBuilder {
+	if b.err != nil {
+		return b
+	}
+	href, err := URLFor(b.ctx, route, params)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	if len(query) > 0 {
+		href += "?" + query.Encode()
+	}
+	b.links[rel] = Link{Href: href}
+	return b
+}
+
+// Build returns the links, or the first error met while building them.
+func (b *LinkBuilder) Build() (Links, error) {
+	return b.links, b.err
+}
@@ -0,0 +1,107 @@
+package shttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestLinkBuilder(t *testing.T) {
+	tests := []struct {
+		name  string
+		page  Page
+		want  map[string]string
+		wantN int
+	}{
+		{
+			name: "Middle page",
+			page: Page{Number: 2, Size: 10, Total: 35},
+			want: map[string]string{
+				"self":  "/users/7/orders?limit=10&page=2&status=open",
+				"first": "/users/7/orders?limit=10&page=1&status=open",
+				"prev":  "/users/7/orders?limit=10&page=1&status=open",
+				"next":  "/users/7/orders?limit=10&page=3&status=open",
+				"last":  "/users/7/orders?limit=10&page=4&status=open",
+				"owner": "/users/7",
+			},
+		},
+		{
+			name: "Last page has no next",
+			page: Page{Number: 4, Size: 10, Total: 35},
+			want: map[string]string{
+				"self":  "/users/7/orders?limit=10&page=4&status=open",
+				"first": "/users/7/orders?limit=10&page=1&status=open",
+				"prev":  "/users/7/orders?limit=10&page=3&status=open",
+				"last":  "/users/7/orders?limit=10&page=4&status=open",
+				"owner": "/users/7",
+			},
+		},
+		{
+			name: "Unknown total",
+			page: Page{Number: 1, Size: 10},
+			want: map[string]string{
+				"self":  "/users/7/orders?limit=10&page=1&status=open",
+				"first": "/users/7/orders?limit=10&page=1&status=open",
+				"next":  "/users/7/orders?limit=10&page=2&status=open",
+				"owner": "/users/7",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewRouter()
+			router.GET("/users/{id}", simpleHandler("user"), Name("user"))
+			router.GET("/users/{id}/orders", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				params := map[string]string{"id": PathValue(r, "id")}
+				links, err := NewLinks(ctx).
+					Paginate("orders", params, url.Values{"status": {"open"}}, tt.page).
+					Add("owner", "user", params).
+					Build()
+				if err != nil {
+					return err
+				}
+				return json.NewEncoder(w).Encode(struct {
+					Links Links `json:"_links"`
+				}{links})
+			}, Name("orders"))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/7/orders", nil))
+
+			var got struct {
+				Links map[string]struct {
+					Href string `json:"href"`
+				} `json:"_links"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decoding %q: %v", w.Body.String(), err)
+			}
+			if len(got.Links) != len(tt.want) {
+				t.Errorf("links = %v, want %v", got.Links, tt.want)
+			}
+			for rel, href := range tt.want {
+				if got.Links[rel].Href != href {
+					t.Errorf("%s = %q, want %q", rel, got.Links[rel].Href, href)
+				}
+			}
+		})
+	}
+}
+
+func TestLinkBuilderError(t *testing.T) {
+	router := NewRouter()
+	router.GET("/", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		_, err := NewLinks(ctx).Self("missing", nil).Add("other", "missing", nil).Build()
+		return err
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500 for unknown route", w.Code)
+	}
+}
@@ -0,0 +1,43 @@
+package shttp
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// longPollJitter is the fraction of the timeout randomly added or removed
+// so clients that connected together do not all reconnect together.
+const longPollJitter = 0.1
+
+// LongPoll holds the request open until a value arrives on waitFor, then
+// writes it as JSON with 200. If timeout (±10% jitter) elapses, waitFor is
+// closed or the route deadline is reached first, it responds 204 so the
+// client polls again. When the client disconnects it returns nil without
+// writing. Routes using LongPoll need a Timeout (or NoTimeout) longer than
+// timeout, otherwise the router's default timeout ends the poll early.
+func LongPoll[T any](ctx context.Context, w http.ResponseWriter, waitFor <-chan T, timeout time.Duration) error {
+	jitter := time.Duration((rand.Float64()*2 - 1) * longPollJitter * float64(timeout))
+	timer := time.NewTimer(timeout + jitter)
+	defer timer.Stop()
+
+	select {
+	case v, ok := <-waitFor:
+		if !ok {
+			break
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		return JSON(w, http.StatusOK, v)
+	case <-timer.C:
+	case <-ctx.Done():
+		if ctx.Err() == context.Canceled {
+			// Client went away; nobody is left to answer.
+			return nil
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
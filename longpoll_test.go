@@ -0,0 +1,62 @@
+package shttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLongPoll(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(ch chan int, cancel context.CancelFunc)
+		timeout    time.Duration
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "Value arrives",
+			setup:      func(ch chan int, _ context.CancelFunc) { ch <- 42 },
+			timeout:    time.Second,
+			wantStatus: http.StatusOK,
+			wantBody:   "42\n",
+		},
+		{
+			name:       "Timeout",
+			setup:      func(chan int, context.CancelFunc) {},
+			timeout:    10 * time.Millisecond,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "Channel closed",
+			setup:      func(ch chan int, _ context.CancelFunc) { close(ch) },
+			timeout:    time.Second,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "Client disconnects",
+			setup:      func(_ chan int, cancel context.CancelFunc) { cancel() },
+			timeout:    time.Second,
+			wantStatus: http.StatusOK, // recorder default: nothing written
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			ch := make(chan int)
+			go tt.setup(ch, cancel)
+
+			w := httptest.NewRecorder()
+			if err := LongPoll(ctx, w, ch, tt.timeout); err != nil {
+				t.Fatalf("LongPoll: %v", err)
+			}
+			if w.Code != tt.wantStatus || w.Body.String() != tt.wantBody {
+				t.Errorf("response = %d %q, want %d %q", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package shttp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Do executes req in-process through the full server stack (server level
+// checks, middleware and router) without opening a connection, and returns
+// the recorded response. The request runs with ctx, which also gets the
+// values of TCP request contexts it does not carry, so Go, TrackConnection
+// and Subscribe work in handlers as they do over the network. Requests built
+// with a path only (e.g. http.NewRequest("GET", "/users/1", nil)) are fine;
+// an empty RemoteAddr is reported as 127.0.0.1.
+//
+// The returned response body is already fully buffered. Do only fails for
+// invalid requests; handler failures are reported through the status code.
+func (s *Server) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if req == nil || req.URL == nil {
+		return nil, fmt.Errorf("shttp: Do requires a request with a URL")
+	}
+	req = req.WithContext(s.withBaseValues(ctx))
+	if req.Body == nil {
+		req.Body = http.NoBody
+	}
+	if req.RemoteAddr == "" {
+		req.RemoteAddr = "127.0.0.1:0"
+	}
+	if req.RequestURI == "" {
+		req.RequestURI = req.URL.RequestURI()
+	}
+
+	rec := newResponseRecorder()
+	s.ServeHTTP(rec, req)
+	return rec.result(req), nil
+}
+
+// result converts the recording into an *http.Response for req.
+func (rec *responseRecorder) result(req *http.Request) *http.Response {
+	body := rec.body.Bytes()
+	return &http.Response{
+		Status:        strconv.Itoa(rec.status) + " " + http.StatusText(rec.status),
+		StatusCode:    rec.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rec.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,69 @@
+package shttp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestServerDo(t *testing.T) {
+	server := New(context.Background(), &Config{Addr: "127.0.0.1:0"})
+	server.Use(func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			w.Header().Set("X-Middleware", "ran")
+			return next(ctx, w, r)
+		}
+	})
+	server.POST("/echo/{name}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(PathValue(r, "name") + ":" + string(body)))
+		return nil
+	})
+	server.GET("/values", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		for _, key := range []any{goroutineTrackerKey{}, connRegistryKey{}, pubSubKey{}} {
+			if ctx.Value(key) == nil {
+				return NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("missing %T", key))
+			}
+		}
+		_, err := io.WriteString(w, "ok")
+		return err
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       io.Reader
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "Routed through middleware", method: http.MethodPost, target: "/echo/bob", body: strings.NewReader("hi"), wantStatus: http.StatusCreated, wantBody: "bob:hi"},
+		{name: "Server values in the context", method: http.MethodGet, target: "/values", wantStatus: http.StatusOK, wantBody: "ok"},
+		{name: "Not found", method: http.MethodGet, target: "/missing", wantStatus: http.StatusNotFound, wantBody: "404 page not found\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.target, tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := server.Do(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Do: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus || string(body) != tt.wantBody {
+				t.Errorf("response = %d %q, want %d %q", resp.StatusCode, body, tt.wantStatus, tt.wantBody)
+			}
+			if tt.wantStatus == http.StatusCreated && resp.Header.Get("X-Middleware") != "ran" {
+				t.Error("middleware did not run")
+			}
+		})
+	}
+}
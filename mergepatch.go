@@ -0,0 +1,193 @@
+package shttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Patch is a partial update of a T decoded by BindPatch, following JSON
+// merge patch semantics (RFC 7396): fields absent from the body are left
+// alone, fields set to a value replace it, and fields set to null are
+// cleared. Unlike decoding into a T, it tells an absent field from one set
+// to its zero value:
+//
+//	patch, err := shttp.BindPatch[User](r)
+//	if err != nil {
+//		return err
+//	}
+//	user, err := store.Get(ctx, id)
+//	...
+//	if err := patch.Apply(&user); err != nil {
+//		return err
+//	}
+//	if patch.Has("email") {
+//		// re-verify the new address
+//	}
+type Patch[T any] struct {
+	raw    json.RawMessage
+	fields map[string]json.RawMessage
+	value  T
+}
+
+// BindPatch decodes a JSON merge patch for a T from the request body. The
+// Content-Type must be application/merge-patch+json or application/json,
+// and the body a JSON object of at most 1 MiB whose fields all belong to T;
+// refused input is reported as an HTTPError (400, 413 or 415).
+func BindPatch[T any](r *http.Request) (Patch[T], error) {
+	var p Patch[T]
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+		return p, NewHTTPError(http.StatusUnsupportedMediaType, "Content-Type must be application/merge-patch+json")
+	}
+
+	// The media type was checked above; the other strict checks apply
+	opts := StrictBindOptions()
+	opts.RequireJSONContentType = false
+	if err := BindWithOptions(r, &p.raw, opts); err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(p.raw, &p.fields); err != nil || p.fields == nil {
+		return p, NewHTTPError(http.StatusBadRequest, "invalid JSON body: a merge patch must be an object")
+	}
+	dec := json.NewDecoder(bytes.NewReader(p.raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p.value); err != nil {
+		return p, NewHTTPError(http.StatusBadRequest, "invalid JSON body: "+err.Error())
+	}
+	return p, nil
+}
+
+// Has reports whether the patch sets the field with the given JSON name,
+// to a value or to null.
+func (p Patch[T]) Has(field string) bool {
+	_, ok := p.fields[field]
+	return ok
+}
+
+// IsNull reports whether the patch clears the field with the given JSON
+// name.
+func (p Patch[T]) IsNull(field string) bool {
+	raw, ok := p.fields[field]
+	return ok && isJSONNull(raw)
+}
+
+// Fields returns the JSON names of the fields the patch sets, sorted.
+func (p Patch[T]) Fields() []string {
+	fields := make([]string, 0, len(p.fields))
+	for field := range p.fields {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+// Value returns the patch decoded into a zero T: the fields the patch does
+// not set hold their zero value. Use Has to tell them apart.
+func (p Patch[T]) Value() T {
+	return p.value
+}
+
+// Apply applies the patch to target. Nested objects are merged field by
+// field and arrays replaced. null clears pointer, slice, map and interface
+// fields and removes map members; fields of other types cannot hold null
+// and are left unchanged, so declare nullable fields as pointers. Validate
+// target afterwards if its type implements Validator.
+func (p Patch[T]) Apply(target *T) error {
+	if len(p.raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(p.raw, target); err != nil {
+		return err
+	}
+	// json.Unmarshal keeps null map members with their zero value
+	removeNullMembers(reflect.ValueOf(target), p.raw)
+	return nil
+}
+
+// removeNullMembers deletes from the maps of v the members the patch raw
+// sets to null, descending into the objects it patches.
+func removeNullMembers(v reflect.Value, raw json.RawMessage) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &members); err != nil {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		for name, member := range members {
+			key := reflect.ValueOf(name).Convert(v.Type().Key())
+			if isJSONNull(member) {
+				v.SetMapIndex(key, reflect.Value{})
+			} else if elem := v.MapIndex(key); elem.IsValid() {
+				removeNullMembers(elem, member)
+			}
+		}
+	case reflect.Struct:
+		for name, member := range members {
+			if field, ok := jsonField(v, name); ok && !isJSONNull(member) {
+				removeNullMembers(field, member)
+			}
+		}
+	}
+}
+
+// jsonField returns the field of the struct v that encoding/json decodes
+// the member name into: an exact match of its JSON name first, then a
+// case-insensitive one, looking into embedded structs.
+func jsonField(v reflect.Value, name string) (reflect.Value, bool) {
+	var folded reflect.Value
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		fieldName, _, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && fieldName == "" {
+			embedded := v.Field(i)
+			if embedded.Kind() == reflect.Pointer {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if field, ok := jsonField(embedded, name); ok {
+					return field, true
+				}
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if fieldName == "" {
+			fieldName = sf.Name
+		}
+		if fieldName == name {
+			return v.Field(i), true
+		}
+		if !folded.IsValid() && strings.EqualFold(fieldName, name) {
+			folded = v.Field(i)
+		}
+	}
+	return folded, folded.IsValid()
+}
+
+func isJSONNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
@@ -0,0 +1,94 @@
+package shttp
+
+import (
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type patchAddress struct {
+	City string `json:"city"`
+	Zip  string `json:"zip"`
+}
+
+type patchUser struct {
+	Name     string        `json:"name"`
+	Age      int           `json:"age"`
+	Nickname *string       `json:"nickname"`
+	Tags     []string      `json:"tags"`
+	Address  *patchAddress `json:"address"`
+
+	Labels map[string]string `json:"labels"`
+}
+
+func patchRequest(contentType, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPatch, "/users/1", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	return req
+}
+
+func TestBindPatch(t *testing.T) {
+	nick := "al"
+	user := patchUser{Name: "Alice", Age: 30, Nickname: &nick, Tags: []string{"a", "b"}, Address: &patchAddress{City: "Paris", Zip: "75001"},
+		Labels: map[string]string{"team": "core", "tier": "1"}}
+
+	patch, err := BindPatch[patchUser](patchRequest("application/merge-patch+json",
+		`{"age": 0, "nickname": null, "tags": ["c"], "address": {"city": "Lyon"}, "labels": {"team": null, "env": "prod"}}`))
+	if err != nil {
+		t.Fatalf("BindPatch() error = %v", err)
+	}
+
+	if got, want := patch.Fields(), []string{"address", "age", "labels", "nickname", "tags"}; !slices.Equal(got, want) {
+		t.Errorf("Fields() = %v, want %v", got, want)
+	}
+	if !patch.Has("age") || patch.Has("name") {
+		t.Errorf("Has(age) = %t, Has(name) = %t; want true, false", patch.Has("age"), patch.Has("name"))
+	}
+	if !patch.IsNull("nickname") || patch.IsNull("age") {
+		t.Errorf("IsNull(nickname) = %t, IsNull(age) = %t; want true, false", patch.IsNull("nickname"), patch.IsNull("age"))
+	}
+	if v := patch.Value(); v.Name != "" || v.Address == nil || v.Address.City != "Lyon" {
+		t.Errorf("Value() = %+v", v)
+	}
+
+	if err := patch.Apply(&user); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if user.Name != "Alice" || user.Age != 0 || user.Nickname != nil || !slices.Equal(user.Tags, []string{"c"}) {
+		t.Errorf("patched user = %+v", user)
+	}
+	if *user.Address != (patchAddress{City: "Lyon", Zip: "75001"}) {
+		t.Errorf("patched address = %+v, want the nested object merged", *user.Address)
+	}
+	if want := map[string]string{"tier": "1", "env": "prod"}; !maps.Equal(user.Labels, want) {
+		t.Errorf("patched labels = %v, want %v: null members removed", user.Labels, want)
+	}
+}
+
+func TestBindPatchErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{name: "wrong content type", contentType: "text/plain", body: `{}`, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "not an object", contentType: "application/json", body: `["name"]`, wantStatus: http.StatusBadRequest},
+		{name: "null document", contentType: "application/json", body: `null`, wantStatus: http.StatusBadRequest},
+		{name: "unknown field", contentType: "application/json", body: `{"role": "admin"}`, wantStatus: http.StatusBadRequest},
+		{name: "wrong type", contentType: "application/json", body: `{"age": "old"}`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := BindPatch[patchUser](patchRequest(tt.contentType, tt.body))
+			var httpErr HTTPError
+			if !errors.As(err, &httpErr) || httpErr.StatusCode != tt.wantStatus {
+				t.Errorf("BindPatch() error = %v, want status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -1,12 +1,17 @@
 package shttp
 
 import (
+	"bufio"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/andres-vara/slogr"
@@ -162,6 +167,106 @@ func LoggerMiddleware(logger *slogr.Logger) Middleware {
 // If a non-nil logger is provided it will be used directly; otherwise the
 // middleware will try to obtain a logger from the request context.
 func LoggingMiddleware(logger *slogr.Logger) Middleware {
+	return LoggingMiddlewareWithOptions(logger, LoggingOptions{})
+}
+
+// LoggingOptions customizes the behavior of LoggingMiddlewareWithOptions.
+type LoggingOptions struct {
+	// Canonical emits exactly one structured entry per request ("canonical log
+	// line") combining timing, auth info, status, bytes written, error and any
+	// attributes added by handlers via AddLogAttrs, instead of the default
+	// [http.request] + [http.response] pair.
+	Canonical bool
+
+	// SkipPaths lists request paths that are not logged at all, such as health
+	// probes or static assets. An entry ending in "*" matches every path with
+	// that prefix (e.g. "/static/*").
+	SkipPaths []string
+
+	// LevelForStatus maps the final response status to the level of the
+	// response entry. Defaults to DefaultLevelForStatus.
+	LevelForStatus func(status int) slog.Level
+}
+
+// DefaultLevelForStatus logs server errors (5xx) at ERROR, client errors
+// (4xx) at WARN and everything else at INFO, so client mistakes do not show
+// up as server errors.
+func DefaultLevelForStatus(status int) slog.Level {
+	switch {
+	case status >= 500:
+		return slog.LevelError
+	case status >= 400:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// logAt logs msg at the given level.
+func logAt(ctx context.Context, l *slogr.Logger, level slog.Level, msg string, args ...any) {
+	switch {
+	case level >= slog.LevelError:
+		l.Error(ctx, msg, args...)
+	case level >= slog.LevelWarn:
+		l.Warn(ctx, msg, args...)
+	case level >= slog.LevelInfo:
+		l.Info(ctx, msg, args...)
+	default:
+		l.Debug(ctx, msg, args...)
+	}
+}
+
+// finalStatus returns the status the client receives for a request that
+// went through the middleware chain with rw.
+func finalStatus(rw *responseWriter, err error) int {
+	if err != nil && !rw.wroteHeader {
+		// The router writes the error response after the middleware chain returns.
+		return statusFromError(err)
+	}
+	return rw.statusCode()
+}
+
+// matchPath reports whether path matches any of the patterns. Patterns ending
+// in "*" match by prefix; all others must match exactly.
+func matchPath(patterns []string, path string) bool {
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// logAttrsKey is the context key for attributes collected for the canonical log line.
+type logAttrsKey struct{}
+
+// logAttrs collects attributes added by handlers during a request.
+type logAttrs struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// AddLogAttrs attaches attributes to the canonical log line of the current request.
+// It is a no-op when the request is not being logged in canonical mode.
+func AddLogAttrs(ctx context.Context, attrs ...slog.Attr) {
+	if la, ok := ctx.Value(logAttrsKey{}).(*logAttrs); ok {
+		la.mu.Lock()
+		la.attrs = append(la.attrs, attrs...)
+		la.mu.Unlock()
+	}
+}
+
+// LoggingMiddlewareWithOptions creates a logging middleware configured by opts.
+// Logger resolution follows LoggingMiddleware.
+func LoggingMiddlewareWithOptions(logger *slogr.Logger, opts LoggingOptions) Middleware {
+	levelFor := opts.LevelForStatus
+	if levelFor == nil {
+		levelFor = DefaultLevelForStatus
+	}
 	return func(next Handler) Handler {
 		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 			start := time.Now()
@@ -171,32 +276,70 @@ func LoggingMiddleware(logger *slogr.Logger) Middleware {
 			} else {
 				l = GetLogger(ctx)
 			}
-			if l == nil {
-				// No logger available, proceed without logging
+			if l == nil || matchPath(opts.SkipPaths, r.URL.Path) {
+				// No logger available or path excluded, proceed without logging
 				return next(ctx, w, r)
 			}
+
+			// Make sure status and size are observable even when the middleware
+			// is used outside of the Router.
+			rw := wrapResponseWriter(w)
+
+			if opts.Canonical {
+				la := &logAttrs{}
+				ctx = context.WithValue(ctx, logAttrsKey{}, la)
+				err := next(ctx, rw, r)
+				logCanonical(ctx, l, levelFor, r, rw, la, err, time.Since(start))
+				return err
+			}
+
 			// Log a request entry with contextual fields
 			l.Infof(ctx, "[http.request] method=%s path=%s request_id=%s user_id=%s client_ip=%s", r.Method, r.URL.Path, GetRequestID(ctx), GetUserID(ctx), GetClientIP(ctx))
 
-			err := next(ctx, w, r)
+			err := next(ctx, rw, r)
 			duration := time.Since(start)
 
-			// Log a response entry with status/duration and optional error
+			// Log a response entry with status/size/duration and optional error,
+			// at the level mapped from the final status
+			status := finalStatus(rw, err)
+			var msg string
 			if err != nil {
-				l.Errorf(ctx, "[http.response] method=%s path=%s request_id=%s user_id=%s client_ip=%s error=%v duration_ms=%d", r.Method, r.URL.Path, GetRequestID(ctx), GetUserID(ctx), GetClientIP(ctx), err, duration.Milliseconds())
+				msg = fmt.Sprintf("[http.response] method=%s path=%s request_id=%s user_id=%s client_ip=%s status=%d bytes=%d error=%v duration_ms=%d", r.Method, r.URL.Path, GetRequestID(ctx), GetUserID(ctx), GetClientIP(ctx), status, rw.size, err, duration.Milliseconds())
 			} else {
-				// try to obtain status code if responseWriter wrapped this (best-effort)
-				status := http.StatusOK
-				if rw, ok := w.(*responseWriter); ok && rw.status != 0 {
-					status = rw.status
-				}
-				l.Infof(ctx, "[http.response] method=%s path=%s request_id=%s user_id=%s client_ip=%s status=%d duration_ms=%d", r.Method, r.URL.Path, GetRequestID(ctx), GetUserID(ctx), GetClientIP(ctx), status, duration.Milliseconds())
+				msg = fmt.Sprintf("[http.response] method=%s path=%s request_id=%s user_id=%s client_ip=%s status=%d bytes=%d duration_ms=%d", r.Method, r.URL.Path, GetRequestID(ctx), GetUserID(ctx), GetClientIP(ctx), status, rw.size, duration.Milliseconds())
 			}
+			logAt(ctx, l, levelFor(status), msg)
 			return err
 		}
 	}
 }
 
+// logCanonical emits the single canonical log line for a finished request.
+func logCanonical(ctx context.Context, l *slogr.Logger, levelFor func(int) slog.Level, r *http.Request, rw *responseWriter, la *logAttrs, err error, duration time.Duration) {
+	status := finalStatus(rw, err)
+
+	args := []any{
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.Int("status", status),
+		slog.Int64("bytes", rw.size),
+		slog.Int64("duration_ms", duration.Milliseconds()),
+		slog.String("request_id", GetRequestID(ctx)),
+		slog.String("user_id", GetUserID(ctx)),
+		slog.String("client_ip", GetClientIP(ctx)),
+	}
+	if err != nil {
+		args = append(args, slog.String("error", err.Error()))
+	}
+	la.mu.Lock()
+	for _, a := range la.attrs {
+		args = append(args, a)
+	}
+	la.mu.Unlock()
+
+	logAt(ctx, l, levelFor(status), "[http.canonical]", args...)
+}
+
 // RecoveryMiddleware creates a middleware that recovers from panics
 func RecoveryMiddleware(logger *slogr.Logger) Middleware {
 	return func(next Handler) Handler {
@@ -226,6 +369,12 @@ func RecoveryMiddleware(logger *slogr.Logger) Middleware {
 
 // CORSMiddleware creates a middleware that handles CORS
 func CORSMiddleware(allowedOrigins []string) Middleware {
+	return corsMiddleware(func() []string { return allowedOrigins })
+}
+
+// corsMiddleware implements CORS handling with origins resolved per request,
+// so the allowed list can change at runtime.
+func corsMiddleware(origins func() []string) Middleware {
 	return func(next Handler) Handler {
 		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 			// Handle preflight requests
@@ -239,7 +388,7 @@ func CORSMiddleware(allowedOrigins []string) Middleware {
 
 			// Add CORS headers to response
 			origin := r.Header.Get("Origin")
-			for _, allowed := range allowedOrigins {
+			for _, allowed := range origins() {
 				if allowed == "*" || allowed == origin {
 					w.Header().Set("Access-Control-Allow-Origin", origin)
 					break
@@ -263,12 +412,24 @@ func TimeoutMiddleware(timeout time.Duration) Middleware {
 }
 
 // responseWriter wraps http.ResponseWriter to capture status and prevent multiple header writes.
+// It passes the optional Flusher, Hijacker and ReaderFrom interfaces through
+// and implements Unwrap for http.ResponseController.
 type responseWriter struct {
 	http.ResponseWriter
 	status      int
+	size        int64
 	wroteHeader bool
 }
 
+// wrapResponseWriter returns w itself when it already is a *responseWriter,
+// otherwise a new wrapper around it.
+func wrapResponseWriter(w http.ResponseWriter) *responseWriter {
+	if rw, ok := w.(*responseWriter); ok {
+		return rw
+	}
+	return &responseWriter{ResponseWriter: w}
+}
+
 func (w *responseWriter) WriteHeader(status int) {
 	if w.wroteHeader {
 		return
@@ -282,7 +443,102 @@ func (w *responseWriter) Write(b []byte) (int, error) {
 	if !w.wroteHeader {
 		w.WriteHeader(http.StatusOK)
 	}
-	return w.ResponseWriter.Write(b)
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush streaming responses.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush sends the buffered response to the client, for streaming handlers
+// asserting http.Flusher.
+func (w *responseWriter) Flush() {
+	w.FlushError()
+}
+
+// FlushError is Flush reporting failures, used by http.ResponseController.
+func (w *responseWriter) FlushError() error {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// ReadFrom keeps the sendfile fast path of io.Copy into the response, e.g.
+// when serving files or proxying, while still counting the bytes written.
+func (w *responseWriter) ReadFrom(src io.Reader) (int64, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(w.ResponseWriter, src)
+	}
+	w.size += n
+	return n, err
+}
+
+// Hijack hands the connection over to the handler, e.g. for a WebSocket (see
+// Upgrade). The response counts as a 101 from then on, so the router writes
+// nothing more and the request is logged as switching protocols.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.status, w.wroteHeader = http.StatusSwitchingProtocols, true
+	}
+	return conn, brw, err
+}
+
+// ResponseStatus returns the status code written so far to w, a writer
+// passed to middleware and handlers by the router, or 0 while no header has
+// been written. Middleware reading it after the handler returned see the
+// real status, except for handler errors: the router writes their response
+// (the HTTPError's status, 500 for other errors) after the middleware chain
+// returns. Writers wrapped by other middleware are looked through with their
+// Unwrap method.
+func ResponseStatus(w http.ResponseWriter) int {
+	if rw := findResponseWriter(w); rw != nil {
+		return rw.status
+	}
+	return 0
+}
+
+// ResponseSize returns the number of body bytes written so far to w (see
+// ResponseStatus).
+func ResponseSize(w http.ResponseWriter) int64 {
+	if rw := findResponseWriter(w); rw != nil {
+		return rw.size
+	}
+	return 0
+}
+
+// findResponseWriter returns the router's writer under w, if any.
+func findResponseWriter(w http.ResponseWriter) *responseWriter {
+	for {
+		switch v := w.(type) {
+		case *responseWriter:
+			return v
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = v.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// statusCode returns the status written so far, defaulting to 200.
+func (w *responseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
 }
 
 // DefaultMiddlewareStack returns a recommended middleware stack for typical HTTP services.
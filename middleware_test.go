@@ -3,6 +3,7 @@ package shttp
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -254,6 +255,7 @@ func TestLoggingMiddleware(t *testing.T) {
 				"user_id=test-user-id",
 				"client_ip=127.0.0.1",
 				"status=200",
+				"bytes=7",
 			},
 			wantLogNotContains: []string{
 				"error=",
@@ -595,3 +597,275 @@ func TestResponseWriter(t *testing.T) {
 		})
 	}
 }
+
+func TestResponseWriterOptionalInterfaces(t *testing.T) {
+	router := NewRouter()
+	router.GET("/stream", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		_, flusher := w.(http.Flusher)
+		_, hijacker := w.(http.Hijacker)
+		_, readerFrom := w.(io.ReaderFrom)
+		if !flusher || !hijacker || !readerFrom {
+			return NewHTTPError(http.StatusInternalServerError, "optional interface hidden")
+		}
+		if _, err := io.Copy(w, strings.NewReader("chunk")); err != nil {
+			return err
+		}
+		return http.NewResponseController(w).Flush()
+	})
+
+	var size int64
+	var status int
+	router.Use(func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			err := next(ctx, w, r)
+			rw := w.(*responseWriter)
+			size, status = rw.size, finalStatus(rw, err)
+			return err
+		}
+	})
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+	resp, err := http.Get(ts.URL + "/stream")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "chunk" {
+		t.Fatalf("got %d %q, want 200 chunk", resp.StatusCode, body)
+	}
+	if size != 5 || status != http.StatusOK {
+		t.Errorf("recorded size %d, status %d; want 5, 200", size, status)
+	}
+}
+
+// unwrappingWriter stands for a writer added by third-party middleware.
+type unwrappingWriter struct {
+	http.ResponseWriter
+}
+
+func (w unwrappingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestResponseStatusAndSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    Handler
+		wrap       bool
+		wantStatus int
+		wantSize   int64
+	}{
+		{
+			name: "Explicit status",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				w.WriteHeader(http.StatusCreated)
+				_, err := w.Write([]byte("created"))
+				return err
+			},
+			wantStatus: http.StatusCreated,
+			wantSize:   7,
+		},
+		{
+			name:       "Through a wrapping middleware",
+			handler:    simpleHandler("hello"),
+			wrap:       true,
+			wantStatus: http.StatusOK,
+			wantSize:   5,
+		},
+		{
+			name: "Handler error not written yet",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return NewHTTPError(http.StatusTeapot, "teapot")
+			},
+			wantStatus: 0,
+			wantSize:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var status int
+			var size int64
+			router := NewRouter()
+			if tt.wrap {
+				router.Use(func(next Handler) Handler {
+					return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+						return next(ctx, unwrappingWriter{w}, r)
+					}
+				})
+			}
+			router.Use(func(next Handler) Handler {
+				return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					if _, ok := w.(unwrappingWriter); ok != tt.wrap {
+						t.Errorf("middleware got %T", w)
+					}
+					err := next(ctx, w, r)
+					status, size = ResponseStatus(w), ResponseSize(w)
+					return err
+				}
+			})
+			router.GET("/test", tt.handler)
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+
+			if status != tt.wantStatus || size != tt.wantSize {
+				t.Errorf("ResponseStatus, ResponseSize = %d, %d; want %d, %d", status, size, tt.wantStatus, tt.wantSize)
+			}
+		})
+	}
+
+	if got := ResponseStatus(httptest.NewRecorder()); got != 0 {
+		t.Errorf("ResponseStatus(unwrapped writer) = %d, want 0", got)
+	}
+}
+
+func TestLoggingMiddlewareCanonical(t *testing.T) {
+	var logOutput strings.Builder
+	logger := slogr.New(&logOutput, &slogr.Options{
+		Level:       slog.LevelDebug,
+		HandlerType: slogr.HandlerTypeJSON,
+	})
+
+	tests := []struct {
+		name            string
+		handler         Handler
+		wantLogContains []string
+	}{
+		{
+			name: "Single entry with handler attributes",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				AddLogAttrs(ctx, slog.String("order_id", "o-42"))
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("created"))
+				return nil
+			},
+			wantLogContains: []string{
+				`"msg":"[http.canonical]"`,
+				`"status":201`,
+				`"bytes":7`,
+				`"request_id":"test-request-id"`,
+				`"order_id":"o-42"`,
+			},
+		},
+		{
+			name: "Error status derived from HTTPError",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return NewHTTPError(http.StatusNotFound, "missing")
+			},
+			wantLogContains: []string{
+				`"level":"WARN"`,
+				`"status":404`,
+				`"error":"missing"`,
+			},
+		},
+		{
+			name:    "Server error logged at ERROR",
+			handler: errorHandler("boom"),
+			wantLogContains: []string{
+				`"level":"ERROR"`,
+				`"status":500`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logOutput.Reset()
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req = req.WithContext(context.WithValue(req.Context(), RequestIDKey, "test-request-id"))
+
+			executeMiddlewareTest(t, LoggingMiddlewareWithOptions(logger, LoggingOptions{Canonical: true}), tt.handler, req)
+
+			logStr := logOutput.String()
+			if lines := strings.Count(strings.TrimSpace(logStr), "\n") + 1; lines != 1 {
+				t.Errorf("expected exactly one log entry, got %d: %q", lines, logStr)
+			}
+			for _, wantStr := range tt.wantLogContains {
+				if !strings.Contains(logStr, wantStr) {
+					t.Errorf("Log output does not contain %q: %q", wantStr, logStr)
+				}
+			}
+		})
+	}
+}
+
+func TestLoggingMiddlewareLevels(t *testing.T) {
+	var logOutput strings.Builder
+	logger := slogr.New(&logOutput, &slogr.Options{
+		Level:       slog.LevelDebug,
+		HandlerType: slogr.HandlerTypeJSON,
+	})
+	statusHandler := func(status int) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			w.WriteHeader(status)
+			return nil
+		}
+	}
+	// Treat 404s as routine and only escalate 5xx
+	custom := func(status int) slog.Level {
+		if status >= 500 {
+			return slog.LevelError
+		}
+		return slog.LevelDebug
+	}
+
+	tests := []struct {
+		name      string
+		opts      LoggingOptions
+		handler   Handler
+		wantLevel string
+	}{
+		{name: "Success at INFO", handler: statusHandler(http.StatusOK), wantLevel: "INFO"},
+		{name: "Written 4xx at WARN", handler: statusHandler(http.StatusConflict), wantLevel: "WARN"},
+		{name: "Returned 4xx at WARN", handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			return NewHTTPError(http.StatusBadRequest, "bad")
+		}, wantLevel: "WARN"},
+		{name: "Written 5xx at ERROR", handler: statusHandler(http.StatusBadGateway), wantLevel: "ERROR"},
+		{name: "Custom mapping", opts: LoggingOptions{LevelForStatus: custom}, handler: statusHandler(http.StatusNotFound), wantLevel: "DEBUG"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logOutput.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			executeMiddlewareTest(t, LoggingMiddlewareWithOptions(logger, tt.opts), tt.handler, req)
+
+			lines := strings.Split(strings.TrimSpace(logOutput.String()), "\n")
+			response := lines[len(lines)-1]
+			if !strings.Contains(response, `"level":"`+tt.wantLevel+`"`) {
+				t.Errorf("response entry = %s, want level %s", response, tt.wantLevel)
+			}
+		})
+	}
+}
+
+func TestLoggingMiddlewareSkipPaths(t *testing.T) {
+	var logOutput strings.Builder
+	logger := slogr.New(&logOutput, slogr.DefaultOptions())
+	opts := LoggingOptions{SkipPaths: []string{"/healthz", "/static/*"}}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantLog bool
+	}{
+		{name: "Exact match skipped", path: "/healthz", wantLog: false},
+		{name: "Prefix match skipped", path: "/static/css/site.css", wantLog: false},
+		{name: "Other path logged", path: "/api/users", wantLog: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logOutput.Reset()
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			executeMiddlewareTest(t, LoggingMiddlewareWithOptions(logger, opts), simpleHandler("ok"), req)
+
+			if gotLog := logOutput.Len() > 0; gotLog != tt.wantLog {
+				t.Errorf("logged = %v, want %v: %q", gotLog, tt.wantLog, logOutput.String())
+			}
+		})
+	}
+}
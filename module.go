@@ -0,0 +1,52 @@
+package shttp
+
+import "context"
+
+// Module packages a feature (auth, billing, admin UI, ...) as a reusable unit
+// that can be mounted on a Server with Register.
+type Module interface {
+	// Routes registers the module's routes. The router is scoped to the
+	// module: middleware added with r.Use only applies to these routes.
+	Routes(r *Router)
+
+	// Middleware returns middleware applied to the module's routes only.
+	Middleware() []Middleware
+
+	// OnStart is called before the server starts listening; an error
+	// aborts the start.
+	OnStart(ctx context.Context) error
+
+	// OnStop is called after the server has shut down, in reverse
+	// registration order.
+	OnStop(ctx context.Context) error
+}
+
+// BaseModule provides no-op implementations of the Module methods. Embed it
+// to implement only the methods a module needs.
+type BaseModule struct{}
+
+// Routes implements Module.
+func (BaseModule) Routes(*Router) {}
+
+// Middleware implements Module.
+func (BaseModule) Middleware() []Middleware { return nil }
+
+// OnStart implements Module.
+func (BaseModule) OnStart(context.Context) error { return nil }
+
+// OnStop implements Module.
+func (BaseModule) OnStop(context.Context) error { return nil }
+
+// Register mounts modules on the server: each module's routes are registered
+// on their own scoped router carrying the module's middleware, and its
+// lifecycle methods are hooked into Start and Shutdown.
+func (s *Server) Register(modules ...Module) {
+	for _, m := range modules {
+		scope := s.Router().newScope()
+		scope.Use(m.Middleware()...)
+		m.Routes(scope)
+
+		s.startHooks = append(s.startHooks, m.OnStart)
+		s.stopHooks = append(s.stopHooks, m.OnStop)
+	}
+}
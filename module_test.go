@@ -0,0 +1,159 @@
+package shttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/andres-vara/slogr"
+)
+
+// eventLog records lifecycle events from concurrently running hooks.
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *eventLog) add(event string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+func (l *eventLog) list() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.events)
+}
+
+// testModule is a Module with configurable routes and lifecycle hooks.
+type testModule struct {
+	BaseModule
+	name     string
+	events   *eventLog
+	startErr error
+}
+
+func (m *testModule) Routes(r *Router) {
+	r.GET("/"+m.name, simpleHandler(m.name))
+}
+
+func (m *testModule) Middleware() []Middleware {
+	return []Middleware{func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			w.Header().Set("X-Module", m.name)
+			return next(ctx, w, r)
+		}
+	}}
+}
+
+func (m *testModule) OnStart(context.Context) error {
+	m.events.add("start " + m.name)
+	return m.startErr
+}
+
+func (m *testModule) OnStop(context.Context) error {
+	m.events.add("stop " + m.name)
+	return nil
+}
+
+func TestServerRegisterModules(t *testing.T) {
+	events := &eventLog{}
+	server := New(context.Background(), &Config{Addr: "127.0.0.1:0", Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	server.Register(
+		&testModule{name: "billing", events: events},
+		&testModule{name: "admin", events: events},
+	)
+
+	// Module middleware is isolated to the module's own routes.
+	for _, name := range []string{"billing", "admin"} {
+		req := httptest.NewRequest(http.MethodGet, "/"+name, nil)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+
+		if w.Body.String() != name {
+			t.Errorf("Body = %q, want %q", w.Body.String(), name)
+		}
+		if got := w.Header().Get("X-Module"); got != name {
+			t.Errorf("X-Module = %q, want %q", got, name)
+		}
+	}
+
+	go server.Start()
+	waitFor(t, func() bool { return len(events.list()) == 2 })
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	want := []string{"start billing", "start admin", "stop admin", "stop billing"}
+	if got := events.list(); !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestServerRegisterModuleStartError(t *testing.T) {
+	events := &eventLog{}
+	startErr := errors.New("db unreachable")
+	server := New(context.Background(), &Config{Addr: "127.0.0.1:0", Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	server.Register(&testModule{name: "db", events: events, startErr: startErr})
+
+	if err := server.Start(); !errors.Is(err, startErr) {
+		t.Errorf("Start() error = %v, want %v", err, startErr)
+	}
+	server.Wait()
+}
+
+func TestServerStartHooksAfterListen(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	server := New(context.Background(), &Config{Addr: taken.Addr().String(), Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	started := false
+	server.OnStart(func(context.Context) error {
+		started = true
+		return nil
+	})
+	if err := server.Start(); err == nil {
+		t.Fatal("Start() on a taken address succeeded")
+	}
+	if started {
+		t.Error("start hook ran although listening failed")
+	}
+}
+
+func TestServerLifecycleHooks(t *testing.T) {
+	events := &eventLog{}
+	server := New(context.Background(), &Config{Addr: "127.0.0.1:0", Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	hook := func(event string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			events.add(event)
+			return err
+		}
+	}
+	drainErr := errors.New("consumer did not drain")
+	server.OnStart(hook("start db", nil))
+	server.OnShutdown(hook("stop db", nil))
+	server.Register(&testModule{name: "billing", events: events})
+	server.OnStart(hook("start consumer", nil))
+	server.OnShutdown(hook("stop consumer", drainErr))
+
+	go server.Start()
+	waitFor(t, func() bool { return len(events.list()) == 3 })
+	if err := server.Shutdown(context.Background()); !errors.Is(err, drainErr) {
+		t.Errorf("Shutdown() error = %v, want %v", err, drainErr)
+	}
+
+	want := []string{"start db", "start billing", "start consumer", "stop consumer", "stop billing", "stop db"}
+	if got := events.list(); !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
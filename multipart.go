@@ -0,0 +1,129 @@
+package shttp
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrNotMultipart is yielded by Parts when the request is not multipart.
+	ErrNotMultipart = NewHTTPError(http.StatusUnsupportedMediaType, "request is not multipart")
+
+	// ErrTooManyParts is yielded by Parts when PartLimits.MaxParts is exceeded.
+	ErrTooManyParts = NewHTTPError(http.StatusRequestEntityTooLarge, "too many multipart parts")
+
+	// ErrPartTooLarge is returned by Part.Read when PartLimits.MaxPartSize is exceeded.
+	ErrPartTooLarge = NewHTTPError(http.StatusRequestEntityTooLarge, "multipart part too large")
+
+	// ErrBodyTooLarge is returned when PartLimits.MaxTotalSize is exceeded.
+	ErrBodyTooLarge = NewHTTPError(http.StatusRequestEntityTooLarge, "request body too large")
+)
+
+// PartLimits bounds a streaming multipart upload. Zero values mean no limit.
+type PartLimits struct {
+	// Maximum number of parts
+	MaxParts int
+
+	// Maximum size in bytes of a single part's content
+	MaxPartSize int64
+
+	// Maximum size in bytes of the whole request body
+	MaxTotalSize int64
+}
+
+// Part is a single multipart part whose reads enforce PartLimits.
+type Part struct {
+	*multipart.Part
+	r io.Reader
+}
+
+// Read reads the part content, failing with ErrPartTooLarge or
+// ErrBodyTooLarge once a limit is exceeded.
+func (p *Part) Read(b []byte) (int, error) {
+	return p.r.Read(b)
+}
+
+// Parts streams the parts of a multipart request body without buffering
+// files in memory or on disk; data is only read from the connection as the
+// handler consumes each part, which gives natural backpressure.
+//
+//	for part, err := range shttp.Parts(r, shttp.PartLimits{MaxPartSize: 1 << 30}) {
+//		if err != nil {
+//			return err
+//		}
+//		io.Copy(dst, part)
+//	}
+//
+// Breaking out of the loop aborts the upload early. An error is yielded at
+// most once and ends the iteration. A part's content is only valid until the
+// next iteration.
+func Parts(r *http.Request, limits PartLimits) iter.Seq2[*Part, error] {
+	return func(yield func(*Part, error) bool) {
+		mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+			yield(nil, ErrNotMultipart)
+			return
+		}
+
+		var body io.Reader = r.Body
+		if limits.MaxTotalSize > 0 {
+			body = &limitReader{r: body, remaining: limits.MaxTotalSize, err: ErrBodyTooLarge}
+		}
+		mr := multipart.NewReader(body, params["boundary"])
+
+		for count := 1; ; count++ {
+			p, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if limits.MaxParts > 0 && count > limits.MaxParts {
+				p.Close()
+				yield(nil, ErrTooManyParts)
+				return
+			}
+
+			part := &Part{Part: p, r: p}
+			if limits.MaxPartSize > 0 {
+				part.r = &limitReader{r: p, remaining: limits.MaxPartSize, err: ErrPartTooLarge}
+			}
+			cont := yield(part, nil)
+			p.Close()
+			if !cont {
+				return
+			}
+		}
+	}
+}
+
+// limitReader reads from r and fails with err once more than remaining bytes
+// have been read.
+type limitReader struct {
+	r         io.Reader
+	remaining int64
+	err       error
+}
+
+func (l *limitReader) Read(b []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, l.err
+	}
+	// Read one byte past the limit so an exactly-sized stream is not rejected.
+	if int64(len(b)) > l.remaining+1 {
+		b = b[:l.remaining+1]
+	}
+	n, err := l.r.Read(b)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), l.err
+	}
+	return n, err
+}
@@ -0,0 +1,88 @@
+package shttp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newMultipartRequest builds a POST request with one file part per entry.
+func newMultipartRequest(t *testing.T, files map[string]string, order []string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, name := range order {
+		fw, err := mw.CreateFormFile(name, name+".txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(files[name]))
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestParts(t *testing.T) {
+	files := map[string]string{"a": "alpha", "b": strings.Repeat("b", 100)}
+	order := []string{"a", "b"}
+
+	tests := []struct {
+		name      string
+		limits    PartLimits
+		wantParts []string
+		wantErr   error
+	}{
+		{name: "No limits", wantParts: []string{"alpha", files["b"]}},
+		{name: "Part exactly at limit", limits: PartLimits{MaxPartSize: 100}, wantParts: []string{"alpha", files["b"]}},
+		{name: "Part too large", limits: PartLimits{MaxPartSize: 10}, wantParts: []string{"alpha"}, wantErr: ErrPartTooLarge},
+		{name: "Too many parts", limits: PartLimits{MaxParts: 1}, wantParts: []string{"alpha"}, wantErr: ErrTooManyParts},
+		{name: "Body too large", limits: PartLimits{MaxTotalSize: 64}, wantErr: ErrBodyTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newMultipartRequest(t, files, order)
+
+			var got []string
+			var gotErr error
+			for part, err := range Parts(req, tt.limits) {
+				if err != nil {
+					gotErr = err
+					break
+				}
+				data, err := io.ReadAll(part)
+				if err != nil {
+					gotErr = err
+					break
+				}
+				got = append(got, string(data))
+			}
+
+			if !errors.Is(gotErr, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", gotErr, tt.wantErr)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantParts, ",") {
+				t.Errorf("parts = %q, want %q", got, tt.wantParts)
+			}
+		})
+	}
+}
+
+func TestPartsNotMultipart(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+
+	for _, err := range Parts(req, PartLimits{}) {
+		if !errors.Is(err, ErrNotMultipart) {
+			t.Errorf("error = %v, want ErrNotMultipart", err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package shttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// routerKey is the context key for the router serving a request.
+type routerKey struct{}
+
+// Name names the route so URLs to it can be built with Router.URL, URLFor
+// and CreatedAt. Routes for several methods on the same pattern may share a
+// name; using a name for two different patterns panics.
+func Name(name string) RouteOption {
+	return func(rt *route) {
+		rt.name = name
+	}
+}
+
+// URL builds the path of the route registered under name, substituting
+// params for its parameters. Values are path-escaped, except that the
+// slashes of a {name...} wildcard are kept. Every parameter of the pattern
+// must be given.
+func (r *Router) URL(name string, params map[string]string) (string, error) {
+	var pattern string
+	for _, rt := range r.routeList() {
+		if rt.name == name {
+			pattern = rt.pattern
+			break
+		}
+	}
+	if pattern == "" {
+		return "", fmt.Errorf("shttp: no route named %q", name)
+	}
+
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments {
+		param, ok := strings.CutPrefix(seg, "{")
+		if !ok {
+			continue
+		}
+		param = strings.TrimSuffix(param, "}")
+		if param == "$" {
+			segments[i] = ""
+			continue
+		}
+		wildcard := strings.HasSuffix(param, "...")
+		param = strings.TrimSuffix(param, "...")
+		value, ok := params[param]
+		if !ok {
+			return "", fmt.Errorf("shttp: route %q: missing parameter %q", name, param)
+		}
+		if wildcard {
+			parts := strings.Split(value, "/")
+			for j, part := range parts {
+				parts[j] = url.PathEscape(part)
+			}
+			segments[i] = strings.Join(parts, "/")
+		} else {
+			segments[i] = url.PathEscape(value)
+		}
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// URLFor builds the path of a named route using the router serving the
+// request carried by ctx (see Router.URL).
+func URLFor(ctx context.Context, name string, params map[string]string) (string, error) {
+	r, ok := ctx.Value(routerKey{}).(*Router)
+	if !ok {
+		return "", fmt.Errorf("shttp: no router in context to build %q", name)
+	}
+	return r.URL(name, params)
+}
+
+// CreatedAt answers 201 Created with a Location header pointing at the
+// named route and body encoded as JSON (no body when nil). Building
+// Location from the route keeps it in sync with the actual pattern.
+func CreatedAt(ctx context.Context, w http.ResponseWriter, routeName string, params map[string]string, body any) error {
+	location, err := URLFor(ctx, routeName, params)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", location)
+	if body == nil {
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	}
+	return JSON(w, http.StatusCreated, body)
+}
@@ -0,0 +1,75 @@
+package shttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouterURL(t *testing.T) {
+	router := NewRouter()
+	router.GET("/users/{id}", simpleHandler("user"), Name("user"))
+	router.PUT("/users/{id}", simpleHandler("update"), Name("user"))
+	router.GET("/files/{path...}", simpleHandler("file"), Name("file"))
+	router.GET("/", simpleHandler("home"), Name("home"))
+
+	tests := []struct {
+		name    string
+		route   string
+		params  map[string]string
+		want    string
+		wantErr bool
+	}{
+		{name: "Parameter", route: "user", params: map[string]string{"id": "42"}, want: "/users/42"},
+		{name: "Escaped parameter", route: "user", params: map[string]string{"id": "a b/c"}, want: "/users/a%20b%2Fc"},
+		{name: "Wildcard keeps slashes", route: "file", params: map[string]string{"path": "docs/a b.txt"}, want: "/files/docs/a%20b.txt"},
+		{name: "No parameters", route: "home", want: "/"},
+		{name: "Missing parameter", route: "user", wantErr: true},
+		{name: "Unknown route", route: "nope", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := router.URL(tt.route, tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("URL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouterDuplicateName(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("reusing a route name for another pattern did not panic")
+		}
+	}()
+	router := NewRouter()
+	router.GET("/a", simpleHandler("a"), Name("x"))
+	router.GET("/b", simpleHandler("b"), Name("x"))
+}
+
+func TestCreatedAt(t *testing.T) {
+	router := NewRouter()
+	router.GET("/orders/{id}", simpleHandler("order"), Name("order"))
+	router.POST("/orders", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return CreatedAt(ctx, w, "order", map[string]string{"id": "o-1"}, map[string]string{"id": "o-1"})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/orders", nil))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201", w.Code)
+	}
+	if got := w.Header().Get("Location"); got != "/orders/o-1" {
+		t.Errorf("Location = %q, want %q", got, "/orders/o-1")
+	}
+	if got := strings.TrimSpace(w.Body.String()); got != `{"id":"o-1"}` {
+		t.Errorf("body = %q", got)
+	}
+}
@@ -0,0 +1,225 @@
+package shttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ParamSpec describes a query or header parameter validated by Params.
+// Use Int, String or Bool to build one.
+type ParamSpec interface {
+	// paramName returns the name the parsed value is stored under.
+	paramName() string
+
+	// parse extracts and validates the parameter from r. A nil value with
+	// a nil error means the parameter is absent and has no default.
+	parse(r *http.Request) (any, error)
+}
+
+// paramSource holds where a parameter is read from.
+type paramSource struct {
+	name     string
+	header   bool
+	required bool
+}
+
+func (p *paramSource) paramName() string {
+	return p.name
+}
+
+// raw returns the parameter's raw value and whether it was present.
+func (p *paramSource) raw(r *http.Request) (string, bool) {
+	if p.header {
+		v := r.Header.Get(p.name)
+		return v, v != ""
+	}
+	q := r.URL.Query()
+	return q.Get(p.name), q.Has(p.name)
+}
+
+// IntParam is an integer parameter.
+type IntParam struct {
+	paramSource
+	min, max *int
+	def      *int
+}
+
+// Int declares an integer query parameter.
+func Int(name string) *IntParam {
+	return &IntParam{paramSource: paramSource{name: name}}
+}
+
+// Min rejects values below n.
+func (p *IntParam) Min(n int) *IntParam { p.min = &n; return p }
+
+// Max rejects values above n.
+func (p *IntParam) Max(n int) *IntParam { p.max = &n; return p }
+
+// Default is used when the parameter is absent.
+func (p *IntParam) Default(n int) *IntParam { p.def = &n; return p }
+
+// Required rejects requests without the parameter.
+func (p *IntParam) Required() *IntParam { p.required = true; return p }
+
+// Header reads the parameter from the request header of that name.
+func (p *IntParam) Header() *IntParam { p.header = true; return p }
+
+func (p *IntParam) parse(r *http.Request) (any, error) {
+	raw, ok := p.raw(r)
+	if !ok {
+		return absent(&p.paramSource, p.def)
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, fmt.Errorf("must be an integer")
+	}
+	if p.min != nil && n < *p.min {
+		return nil, fmt.Errorf("must be at least %d", *p.min)
+	}
+	if p.max != nil && n > *p.max {
+		return nil, fmt.Errorf("must be at most %d", *p.max)
+	}
+	return n, nil
+}
+
+// StringParam is a string parameter.
+type StringParam struct {
+	paramSource
+	oneOf  []string
+	maxLen int
+	def    *string
+}
+
+// String declares a string query parameter.
+func String(name string) *StringParam {
+	return &StringParam{paramSource: paramSource{name: name}}
+}
+
+// OneOf rejects values other than the given ones.
+func (p *StringParam) OneOf(values ...string) *StringParam { p.oneOf = values; return p }
+
+// MaxLen rejects values longer than n bytes.
+func (p *StringParam) MaxLen(n int) *StringParam { p.maxLen = n; return p }
+
+// Default is used when the parameter is absent.
+func (p *StringParam) Default(s string) *StringParam { p.def = &s; return p }
+
+// Required rejects requests without the parameter.
+func (p *StringParam) Required() *StringParam { p.required = true; return p }
+
+// Header reads the parameter from the request header of that name.
+func (p *StringParam) Header() *StringParam { p.header = true; return p }
+
+func (p *StringParam) parse(r *http.Request) (any, error) {
+	raw, ok := p.raw(r)
+	if !ok {
+		return absent(&p.paramSource, p.def)
+	}
+	if len(p.oneOf) > 0 && !slices.Contains(p.oneOf, raw) {
+		return nil, fmt.Errorf("must be one of %s", strings.Join(p.oneOf, ", "))
+	}
+	if p.maxLen > 0 && len(raw) > p.maxLen {
+		return nil, fmt.Errorf("must be at most %d characters", p.maxLen)
+	}
+	return raw, nil
+}
+
+// BoolParam is a boolean parameter accepting the values of strconv.ParseBool.
+type BoolParam struct {
+	paramSource
+	def *bool
+}
+
+// Bool declares a boolean query parameter.
+func Bool(name string) *BoolParam {
+	return &BoolParam{paramSource: paramSource{name: name}}
+}
+
+// Default is used when the parameter is absent.
+func (p *BoolParam) Default(b bool) *BoolParam { p.def = &b; return p }
+
+// Required rejects requests without the parameter.
+func (p *BoolParam) Required() *BoolParam { p.required = true; return p }
+
+// Header reads the parameter from the request header of that name.
+func (p *BoolParam) Header() *BoolParam { p.header = true; return p }
+
+func (p *BoolParam) parse(r *http.Request) (any, error) {
+	raw, ok := p.raw(r)
+	if !ok {
+		return absent(&p.paramSource, p.def)
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, fmt.Errorf("must be a boolean")
+	}
+	return b, nil
+}
+
+// absent returns the value of a missing parameter: its default, nothing,
+// or an error when it is required.
+func absent[T any](p *paramSource, def *T) (any, error) {
+	switch {
+	case p.required:
+		return nil, fmt.Errorf("is required")
+	case def != nil:
+		return *def, nil
+	default:
+		return nil, nil
+	}
+}
+
+// paramValuesKey is the context key for the values parsed by Params.
+type paramValuesKey struct{}
+
+// Params validates the route's query and header parameters before the
+// handler runs, e.g.
+//
+//	r.GET("/items", list, shttp.Params(
+//		shttp.Int("limit").Min(1).Max(100).Default(20),
+//		shttp.String("sort").OneOf("name", "date"),
+//		shttp.String("X-Tenant").Header().Required(),
+//	))
+//
+// Requests with invalid parameters get a 400 listing every problem. Parsed
+// values are available to the handler through ParamValue.
+func Params(specs ...ParamSpec) RouteOption {
+	return func(rt *route) {
+		rt.paramSpecs = append(rt.paramSpecs, specs...)
+	}
+}
+
+// validateParams wraps handler with the validation of specs.
+func validateParams(specs []ParamSpec, handler Handler) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		values := make(map[string]any, len(specs))
+		var problems []string
+		for _, spec := range specs {
+			v, err := spec.parse(r)
+			if err != nil {
+				problems = append(problems, spec.paramName()+" "+err.Error())
+				continue
+			}
+			if v != nil {
+				values[spec.paramName()] = v
+			}
+		}
+		if len(problems) > 0 {
+			return NewHTTPError(http.StatusBadRequest, "invalid parameters: "+strings.Join(problems, "; "))
+		}
+		return handler(context.WithValue(ctx, paramValuesKey{}, values), w, r)
+	}
+}
+
+// ParamValue returns the value parsed by Params for name, or the zero value
+// when the parameter was absent without default or has another type (int
+// for Int, string for String, bool for Bool).
+func ParamValue[T any](ctx context.Context, name string) T {
+	values, _ := ctx.Value(paramValuesKey{}).(map[string]any)
+	v, _ := values[name].(T)
+	return v
+}
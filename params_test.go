@@ -0,0 +1,57 @@
+package shttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParams(t *testing.T) {
+	router := NewRouter()
+	router.GET("/items", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		fmt.Fprintf(w, "limit=%d sort=%q archived=%v tenant=%q",
+			ParamValue[int](ctx, "limit"), ParamValue[string](ctx, "sort"),
+			ParamValue[bool](ctx, "archived"), ParamValue[string](ctx, "X-Tenant"))
+		return nil
+	}, Params(
+		Int("limit").Min(1).Max(100).Default(20),
+		String("sort").OneOf("name", "date"),
+		Bool("archived"),
+		String("X-Tenant").Header().Required(),
+	))
+
+	tests := []struct {
+		name       string
+		query      string
+		tenant     string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "Defaults", tenant: "acme", wantStatus: http.StatusOK, wantBody: `limit=20 sort="" archived=false tenant="acme"`},
+		{name: "Parsed values", query: "?limit=5&sort=date&archived=true", tenant: "acme", wantStatus: http.StatusOK, wantBody: `limit=5 sort="date" archived=true tenant="acme"`},
+		{name: "Out of range", query: "?limit=500", tenant: "acme", wantStatus: http.StatusBadRequest, wantBody: "limit must be at most 100"},
+		{name: "Not an integer", query: "?limit=ten", tenant: "acme", wantStatus: http.StatusBadRequest, wantBody: "limit must be an integer"},
+		{name: "Every problem reported", query: "?sort=size&archived=maybe", wantStatus: http.StatusBadRequest, wantBody: "sort must be one of name, date; archived must be a boolean; X-Tenant is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/items"+tt.query, nil)
+			if tt.tenant != "" {
+				req.Header.Set("X-Tenant", tt.tenant)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %q, want containing %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -2,8 +2,11 @@ package shttp
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"slices"
 	"strings"
+	"unicode"
 )
 
 // pathParamsKey is the context key used to store path parameters.
@@ -43,26 +46,101 @@ func PathValue(r *http.Request, key string) string {
 	return ""
 }
 
-// extractPathParams extracts named parameters from a registered pattern and an actual path.
-// Example: pattern "/users/{id}" and path "/users/123" -> map[id]="123"
-func extractPathParams(pattern, path string) map[string]string {
-	// Normalize leading/trailing slashes then split
-	pSegs := strings.Split(strings.Trim(pattern, "/"), "/")
-	aSegs := strings.Split(strings.Trim(path, "/"), "/")
+// ValidatePattern checks a route pattern and returns its normalized form:
+// surrounding spaces are trimmed and a missing leading slash is added.
+// Every segment must be either a literal or a whole "{name}" parameter;
+// the last segment may also be a "{name...}" wildcard or "{$}". Empty
+// segments (other than a trailing slash), "." and "..", unbalanced braces,
+// inner whitespace, invalid parameter names and duplicate parameter names
+// are rejected.
+func ValidatePattern(pattern string) (string, error) {
+	normalized, _, err := parsePattern(pattern)
+	return normalized, err
+}
+
+// parsePattern validates and normalizes pattern and returns the names of
+// its parameters.
+func parsePattern(pattern string) (string, []string, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return "", nil, fmt.Errorf("shttp: invalid route pattern: empty pattern")
+	}
+	if !strings.HasPrefix(pattern, "/") {
+		pattern = "/" + pattern
+	}
+	invalid := func(format string, args ...any) (string, []string, error) {
+		return "", nil, fmt.Errorf("shttp: invalid route pattern %q: %s", pattern, fmt.Sprintf(format, args...))
+	}
 
-	if len(pSegs) != len(aSegs) {
-		// If lengths differ, we still try to match trailing empty segment cases
-		return nil
+	// Text before a space would read as the method of a method pattern
+	// ("GET /users/{id}")
+	if strings.ContainsAny(pattern, " \t\r\n") {
+		return invalid("whitespace inside the pattern")
 	}
 
-	params := make(map[string]string)
-	for i := 0; i < len(pSegs); i++ {
-		ps := pSegs[i]
-		if strings.HasPrefix(ps, "{") && strings.HasSuffix(ps, "}") {
-			key := strings.TrimSuffix(strings.TrimPrefix(ps, "{"), "}")
-			params[key] = aSegs[i]
+	var params []string
+	segments := strings.Split(pattern[1:], "/")
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		switch {
+		case seg == "" && !last:
+			return invalid("empty segment")
+		case seg == "." || seg == "..":
+			return invalid("%q segment", seg)
+		case !strings.ContainsAny(seg, "{}"):
+			continue
+		}
+
+		name, ok := strings.CutPrefix(seg, "{")
+		if ok {
+			name, ok = strings.CutSuffix(name, "}")
+		}
+		if !ok || strings.ContainsAny(name, "{}") {
+			return invalid("segment %q must be a literal or a whole {name} parameter", seg)
+		}
+		if name == "$" {
+			if !last {
+				return invalid("{$} must be the last segment")
+			}
+			continue
+		}
+		if wildcard, ok := strings.CutSuffix(name, "..."); ok {
+			if !last {
+				return invalid("wildcard %q must be the last segment", seg)
+			}
+			name = wildcard
 		}
+		if !isIdentifier(name) {
+			return invalid("parameter name %q is not a valid identifier", name)
+		}
+		if slices.Contains(params, name) {
+			return invalid("duplicate parameter %q", name)
+		}
+		params = append(params, name)
 	}
+	return pattern, params, nil
+}
 
+// isIdentifier reports whether name is a valid Go identifier, as the router
+// requires for parameter names, like ServeMux.
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if !unicode.IsLetter(c) && c != '_' && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// pathParams returns the values of the named parameters the router matched
+// for req, set by Router.ServeHTTP with req.SetPathValue.
+func pathParams(req *http.Request, names []string) map[string]string {
+	params := make(map[string]string, len(names))
+	for _, name := range names {
+		params[name] = req.PathValue(name)
+	}
 	return params
 }
@@ -0,0 +1,72 @@
+package shttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidatePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+		wantErr string
+	}{
+		{pattern: "/users/{id}", want: "/users/{id}"},
+		{pattern: " users/{id} ", want: "/users/{id}"},
+		{pattern: "/", want: "/"},
+		{pattern: "/static/", want: "/static/"},
+		{pattern: "/files/{path...}", want: "/files/{path...}"},
+		{pattern: "/exact/{$}", want: "/exact/{$}"},
+		{pattern: "", wantErr: "empty pattern"},
+		{pattern: "/users//{id}", wantErr: "empty segment"},
+		{pattern: "/users/../admin", wantErr: `".." segment`},
+		{pattern: "/users/{id", wantErr: "whole {name} parameter"},
+		{pattern: "/users/id}", wantErr: "whole {name} parameter"},
+		{pattern: "/users/v{id}", wantErr: "whole {name} parameter"},
+		{pattern: "/users/{1id}", wantErr: "not a valid identifier"},
+		{pattern: "/users/{id}/posts/{id}", wantErr: `duplicate parameter "id"`},
+		{pattern: "/files/{path...}/meta", wantErr: "must be the last segment"},
+		{pattern: "/0 0", wantErr: "whitespace"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got, err := ValidatePattern(tt.pattern)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ValidatePattern(%q) = %q, %v; want %q", tt.pattern, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouterRejectsInvalidPattern(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("registering an invalid pattern did not panic")
+		}
+	}()
+	NewRouter().GET("/users/{id}/{id}", simpleHandler("x"))
+}
+
+func TestRouterWildcardParam(t *testing.T) {
+	router := NewRouter()
+	router.GET("/files/{path...}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Write([]byte(PathValue(r, "path")))
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/a/b/c.txt", nil))
+	if w.Body.String() != "a/b/c.txt" {
+		t.Errorf("path = %q, want %q", w.Body.String(), "a/b/c.txt")
+	}
+}
@@ -0,0 +1,80 @@
+package shttp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// CheckIfMatch protects a write against lost updates: when the request has
+// an If-Match header that does not match etag, the current ETag of the
+// resource, it returns a 412 Precondition Failed HTTPError, meaning the
+// client edited a stale copy. Requests without If-Match pass; combine with
+// RequireIfMatchMiddleware to make the header mandatory. Tags are compared
+// with the strong comparison RFC 9110 requires for If-Match: weak tags never
+// match, so etag must be a strong one, like the ETags JSONWithETag sends for
+// Versioned values.
+//
+//	user, err := store.Get(ctx, id)
+//	...
+//	if err := shttp.CheckIfMatchVersion(r, user); err != nil {
+//		return err
+//	}
+func CheckIfMatch(r *http.Request, etag string) error {
+	header := r.Header.Get("If-Match")
+	if header == "" || etagMatchesStrong(header, etag) {
+		return nil
+	}
+	return NewHTTPError(http.StatusPreconditionFailed, "the resource was modified since it was read")
+}
+
+// etagMatchesStrong reports whether an If-Match header matches etag, using
+// the strong comparison RFC 9110 requires for If-Match.
+func etagMatchesStrong(header, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimSpace(candidate) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckIfMatchVersion is CheckIfMatch with the ETag JSONWithETag sends for
+// current.
+func CheckIfMatchVersion(r *http.Request, current Versioned) error {
+	return CheckIfMatch(r, versionETag(current.ResourceVersion()))
+}
+
+// CheckVersion protects a write against lost updates when clients send back
+// the version they read in the body rather than in If-Match: it returns a
+// 409 Conflict HTTPError when submitted, the decoded request, carries
+// another version than current.
+func CheckVersion(submitted, current Versioned) error {
+	if submitted.ResourceVersion() == current.ResourceVersion() {
+		return nil
+	}
+	return NewHTTPError(http.StatusConflict, "version conflict: the resource is at version "+current.ResourceVersion())
+}
+
+// RequireIfMatchMiddleware answers PUT, PATCH and DELETE requests without
+// an If-Match header with 428 Precondition Required, so clients cannot skip
+// the lost-update check of CheckIfMatch. Apply it to the routes that check.
+func RequireIfMatchMiddleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			switch r.Method {
+			case http.MethodPut, http.MethodPatch, http.MethodDelete:
+				if strings.TrimSpace(r.Header.Get("If-Match")) == "" {
+					return NewHTTPError(http.StatusPreconditionRequired, "this request requires an If-Match header")
+				}
+			}
+			return next(ctx, w, r)
+		}
+	}
+}
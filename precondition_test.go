@@ -0,0 +1,89 @@
+package shttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckIfMatch(t *testing.T) {
+	current := versionedDoc{ID: 1, Version: 3}
+	tests := []struct {
+		name    string
+		ifMatch string
+		want    int
+	}{
+		{name: "no header", want: http.StatusOK},
+		{name: "current version", ifMatch: `"3"`, want: http.StatusOK},
+		{name: "weak tags never match", ifMatch: `W/"3"`, want: http.StatusPreconditionFailed},
+		{name: "one of several", ifMatch: `"2", "3"`, want: http.StatusOK},
+		{name: "any", ifMatch: "*", want: http.StatusOK},
+		{name: "stale version", ifMatch: `"2"`, want: http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/docs/1", nil)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			if got := statusOf(CheckIfMatchVersion(req, current)); got != tt.want {
+				t.Errorf("CheckIfMatchVersion() status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	weak := httptest.NewRequest(http.MethodPut, "/docs/1", nil)
+	weak.Header.Set("If-Match", `W/"3"`)
+	if got := statusOf(CheckIfMatch(weak, `W/"3"`)); got != http.StatusPreconditionFailed {
+		t.Errorf("CheckIfMatch() with a weak current ETag status = %d, want %d", got, http.StatusPreconditionFailed)
+	}
+
+	if got := statusOf(CheckVersion(versionedDoc{Version: 2}, current)); got != http.StatusConflict {
+		t.Errorf("CheckVersion(stale) status = %d, want %d", got, http.StatusConflict)
+	}
+	if err := CheckVersion(versionedDoc{Version: 3}, current); err != nil {
+		t.Errorf("CheckVersion(current) error = %v", err)
+	}
+}
+
+func TestRequireIfMatchMiddleware(t *testing.T) {
+	router := NewRouter()
+	router.Use(RequireIfMatchMiddleware())
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		router.Handle(method, "/docs/{id}", simpleHandler("ok"))
+	}
+
+	tests := []struct {
+		method, ifMatch string
+		want            int
+	}{
+		{http.MethodGet, "", http.StatusOK},
+		{http.MethodPut, "", http.StatusPreconditionRequired},
+		{http.MethodDelete, "", http.StatusPreconditionRequired},
+		{http.MethodPut, `"3"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/docs/1", nil)
+		if tt.ifMatch != "" {
+			req.Header.Set("If-Match", tt.ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s with If-Match %q = %d, want %d", tt.method, tt.ifMatch, w.Code, tt.want)
+		}
+	}
+}
+
+// statusOf returns the status of an HTTPError, 200 for nil.
+func statusOf(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var httpErr HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode
+	}
+	return http.StatusInternalServerError
+}
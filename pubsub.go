@@ -0,0 +1,273 @@
+package shttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/andres-vara/slogr"
+)
+
+// subscriptionBuffer is the number of messages buffered per subscriber.
+// Messages published while a subscriber's buffer is full are dropped for it.
+const subscriptionBuffer = 64
+
+// pubSubKey is the context key for the server's pub/sub hub.
+type pubSubKey struct{}
+
+// Message is a message published on a topic.
+type Message struct {
+	Topic string
+	Data  []byte
+}
+
+// Broker carries published messages between server instances, so that
+// subscribers connected to any replica behind a load balancer receive them.
+// Implementations must be safe for concurrent use; see the shttpredis
+// package for a Redis implementation.
+type Broker interface {
+	// Publish sends msg to the instances subscribed to its topic, including
+	// this one.
+	Publish(ctx context.Context, msg Message) error
+
+	// Subscribe returns a channel receiving the messages published on topic
+	// by any instance, closed once ctx is done or the subscription is lost,
+	// in which case the server subscribes again. Delivery is at most once:
+	// messages published while the broker is unreachable may be lost.
+	Subscribe(ctx context.Context, topic string) (<-chan Message, error)
+}
+
+// brokerRetryInterval is the delay before retrying a failed or lost Broker
+// subscription.
+const brokerRetryInterval = time.Second
+
+// pubSub fans published messages out to the subscribers of their topic.
+// Without a broker messages stay within the process; with one, the hub
+// subscribes to the broker for every topic it has local subscribers for.
+type pubSub struct {
+	broker Broker
+	logger *slogr.Logger
+
+	mu     sync.RWMutex
+	topics map[string]*topicSubs
+}
+
+// topicSubs are the local subscribers of a topic.
+type topicSubs struct {
+	subs map[chan Message]struct{}
+
+	// Ends the broker subscription of the topic
+	cancel context.CancelFunc
+}
+
+func newPubSub(broker Broker, logger *slogr.Logger) *pubSub {
+	return &pubSub{broker: broker, logger: logger, topics: make(map[string]*topicSubs)}
+}
+
+// Publish sends msg to the current subscribers of topic, on every instance
+// when Config.Broker is set. A []byte or string msg is sent as is, anything
+// else is encoded as JSON. Publishing never blocks on subscribers:
+// subscribers that fall more than 64 messages behind miss messages.
+func (s *Server) Publish(topic string, msg any) error {
+	data, err := encodeMessage(msg)
+	if err != nil {
+		return err
+	}
+	m := Message{Topic: topic, Data: data}
+	if s.pubsub.broker != nil {
+		return s.pubsub.broker.Publish(s.ctx, m)
+	}
+	s.pubsub.deliver(m)
+	return nil
+}
+
+// Subscribe returns a channel receiving the messages published on topic
+// until ctx is done, when the channel is closed. ctx is usually the request
+// context.
+func (s *Server) Subscribe(ctx context.Context, topic string) <-chan Message {
+	return s.pubsub.subscribe(ctx, topic)
+}
+
+// Subscribe subscribes to topic on the Server handling the request carried
+// by ctx (see Server.Subscribe). Outside of a Server the returned channel is
+// closed right away.
+func Subscribe(ctx context.Context, topic string) <-chan Message {
+	if ps, ok := ctx.Value(pubSubKey{}).(*pubSub); ok {
+		return ps.subscribe(ctx, topic)
+	}
+	ch := make(chan Message)
+	close(ch)
+	return ch
+}
+
+// deliver sends msg to the local subscribers of its topic.
+func (ps *pubSub) deliver(msg Message) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	if t, ok := ps.topics[msg.Topic]; ok {
+		t.send(msg)
+	}
+}
+
+// send delivers msg to the subscribers; the caller holds the hub's lock.
+func (t *topicSubs) send(msg Message) {
+	for ch := range t.subs {
+		select {
+		case ch <- msg:
+		default:
+			// Slow subscriber; never hold up the publisher
+		}
+	}
+}
+
+func (ps *pubSub) subscribe(ctx context.Context, topic string) <-chan Message {
+	ch := make(chan Message, subscriptionBuffer)
+	ps.mu.Lock()
+	t, ok := ps.topics[topic]
+	if !ok {
+		t = &topicSubs{subs: make(map[chan Message]struct{})}
+		if ps.broker != nil {
+			var brokerCtx context.Context
+			brokerCtx, t.cancel = context.WithCancel(context.Background())
+			go ps.forward(brokerCtx, topic, t)
+		}
+		ps.topics[topic] = t
+	}
+	t.subs[ch] = struct{}{}
+	ps.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		ps.mu.Lock()
+		delete(t.subs, ch)
+		if len(t.subs) == 0 {
+			delete(ps.topics, topic)
+			if t.cancel != nil {
+				t.cancel()
+			}
+		}
+		ps.mu.Unlock()
+		close(ch)
+	}()
+	return ch
+}
+
+// forward delivers the messages of topic received from the broker to its
+// local subscribers t until ctx is done, retrying failed and lost
+// subscriptions after brokerRetryInterval.
+// Delivering to t rather than to the current subscribers of topic keeps a
+// subscription being torn down from duplicating the messages of its
+// replacement.
+func (ps *pubSub) forward(ctx context.Context, topic string, t *topicSubs) {
+	for ctx.Err() == nil {
+		msgs, err := ps.broker.Subscribe(ctx, topic)
+		if err != nil {
+			if ps.logger != nil {
+				ps.logger.Errorf(ctx, "[server.pubsub] Subscribing to %s failed: %v", topic, err)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(brokerRetryInterval):
+			}
+			continue
+		}
+		for msg := range msgs {
+			ps.mu.RLock()
+			t.send(msg)
+			ps.mu.RUnlock()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		// Messages published until the subscription is restored are lost
+		if ps.logger != nil {
+			ps.logger.Errorf(ctx, "[server.pubsub] Subscription to %s lost, subscribing again", topic)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(brokerRetryInterval):
+		}
+	}
+}
+
+// encodeMessage returns the bytes published for msg.
+func encodeMessage(msg any) ([]byte, error) {
+	switch m := msg.(type) {
+	case []byte:
+		return m, nil
+	case string:
+		return []byte(m), nil
+	}
+	return json.Marshal(msg)
+}
+
+// StreamTopic serves the messages published on topic as a Server-Sent Events
+// stream, one event named after the topic per message, until the client
+// disconnects. The connection is tracked (see TrackConnection): on shutdown
+// the client receives a "close" event and the handler returns. Routes using
+// it need NoTimeout, otherwise the router's default timeout ends the stream:
+//
+//	server.GET("/events", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+//		return shttp.StreamTopic(ctx, w, "notifications")
+//	}, shttp.NoTimeout())
+func StreamTopic(ctx context.Context, w http.ResponseWriter, topic string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	msgs := Subscribe(ctx, topic)
+
+	closing := make(chan struct{})
+	defer TrackConnection(ctx, func(ctx context.Context) error {
+		close(closing)
+		return nil
+	})()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	// Keep buffering proxies such as nginx from holding events back
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				return nil
+			}
+			if err := writeEvent(w, msg.Topic, msg.Data); err != nil {
+				return err
+			}
+		case <-closing:
+			return writeEvent(w, "close", []byte("going away"))
+		case <-ctx.Done():
+			return nil
+		}
+		if err := rc.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// writeEvent writes one Server-Sent Event, splitting data into one data
+// field per line.
+func writeEvent(w http.ResponseWriter, event string, data []byte) error {
+	var buf bytes.Buffer
+	buf.WriteString("event: " + event + "\n")
+	for line := range bytes.Lines(data) {
+		buf.WriteString("data: ")
+		buf.Write(bytes.TrimRight(line, "\r\n"))
+		buf.WriteByte('\n')
+	}
+	if len(data) == 0 {
+		buf.WriteString("data\n")
+	}
+	buf.WriteByte('\n')
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,152 @@
+package shttp
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andres-vara/slogr"
+)
+
+func TestServerPublishSubscribe(t *testing.T) {
+	server := New(context.Background(), &Config{Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	news := server.Subscribe(ctx, "news")
+	other := server.Subscribe(ctx, "other")
+
+	tests := []struct {
+		msg  any
+		want string
+	}{
+		{"plain text", "plain text"},
+		{[]byte("raw"), "raw"},
+		{map[string]int{"id": 7}, `{"id":7}`},
+	}
+	for _, tt := range tests {
+		if err := server.Publish("news", tt.msg); err != nil {
+			t.Fatalf("Publish(%v) error = %v", tt.msg, err)
+		}
+		select {
+		case msg := <-news:
+			if msg.Topic != "news" || string(msg.Data) != tt.want {
+				t.Errorf("received %s %q, want news %q", msg.Topic, msg.Data, tt.want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("message %v not received", tt.msg)
+		}
+	}
+	if err := server.Publish("news", func() {}); err == nil {
+		t.Error("Publish of an unencodable message succeeded")
+	}
+	select {
+	case msg := <-other:
+		t.Errorf("subscriber of another topic received %q", msg.Data)
+	default:
+	}
+
+	cancel()
+	for range news {
+	}
+	for range other {
+	}
+	server.pubsub.mu.RLock()
+	defer server.pubsub.mu.RUnlock()
+	if n := len(server.pubsub.topics); n != 0 {
+		t.Errorf("%d topics left after the subscribers ended", n)
+	}
+
+}
+
+// closingBroker loses every subscription right away.
+type closingBroker struct {
+	subscriptions atomic.Int32
+}
+
+func (b *closingBroker) Publish(ctx context.Context, msg Message) error { return nil }
+
+func (b *closingBroker) Subscribe(ctx context.Context, topic string) (<-chan Message, error) {
+	b.subscriptions.Add(1)
+	ch := make(chan Message)
+	close(ch)
+	return ch, nil
+}
+
+func TestServerBrokerSubscriptionLost(t *testing.T) {
+	logs := &syncBuffer{}
+	broker := &closingBroker{}
+	server := New(context.Background(), &Config{Broker: broker, Logger: slogr.New(logs, slogr.DefaultOptions())})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.Subscribe(ctx, "news")
+	waitFor(t, func() bool { return strings.Contains(logs.String(), "Subscription to news lost") })
+	time.Sleep(50 * time.Millisecond)
+	if n := broker.subscriptions.Load(); n != 1 {
+		t.Errorf("subscribed %d times right after losing the subscription, want 1", n)
+	}
+}
+
+func TestSubscribeOutsideServer(t *testing.T) {
+	if _, ok := <-Subscribe(context.Background(), "news"); ok {
+		t.Error("Subscribe outside of a server returned an open channel")
+	}
+}
+
+func TestStreamTopic(t *testing.T) {
+	server := New(context.Background(), &Config{Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	server.GET("/events", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return StreamTopic(ctx, w, "news")
+	}, NoTimeout())
+
+	ts := httptest.NewUnstartedServer(server)
+	ts.Config.BaseContext = server.HTTPServer().BaseContext
+	ts.Start()
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", got)
+	}
+	waitFor(t, func() bool { return server.Stats().TrackedConnections == 1 })
+
+	events := bufio.NewReader(resp.Body)
+	readEvent := func() string {
+		t.Helper()
+		var lines []string
+		for {
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatalf("reading event: %v", err)
+			}
+			if line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	server.Publish("news", "hello\nworld")
+	if got, want := readEvent(), "event: news\ndata: hello\ndata: world\n"; got != want {
+		t.Errorf("event = %q, want %q", got, want)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if got, want := readEvent(), "event: close\ndata: going away\n"; got != want {
+		t.Errorf("event = %q, want %q", got, want)
+	}
+}
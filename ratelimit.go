@@ -0,0 +1,155 @@
+package shttp
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket: requests are allowed at Rate per second on
+// average, with bursts of up to Burst requests.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitResult is the outcome of taking a token from a bucket.
+type RateLimitResult struct {
+	Allowed bool
+
+	// Tokens left in the bucket
+	Remaining int
+
+	// When Allowed is false, the time until a token is available
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the token buckets of a rate limiter. Implementations
+// must take tokens atomically and be safe for concurrent use; a store shared
+// by all replicas (see shttpredis.RateLimitStore) makes the limit global
+// rather than per process.
+type RateLimitStore interface {
+	// Take takes a token from the bucket of key, created full on first use.
+	Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error)
+}
+
+// rateLimitSweepInterval is the number of Take calls between two sweeps of
+// the idle buckets of a MemoryRateLimitStore.
+const rateLimitSweepInterval = 1024
+
+// MemoryRateLimitStore is an in-process RateLimitStore. Buckets that have
+// refilled completely are dropped periodically.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	takes   int
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	limit  RateLimit
+}
+
+// NewMemoryRateLimitStore creates an empty in-process RateLimitStore.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket)}
+}
+
+// Take implements RateLimitStore.
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.takes++; s.takes%rateLimitSweepInterval == 0 {
+		s.sweep(now)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+	b.refill(now)
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+		return RateLimitResult{RetryAfter: wait}, nil
+	}
+	b.tokens--
+	return RateLimitResult{Allowed: true, Remaining: int(b.tokens)}, nil
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+	b.last = now
+}
+
+// sweep drops the buckets that are full again; they are recreated full.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// RateLimitOptions configures RateLimitMiddleware.
+type RateLimitOptions struct {
+	// Buckets backing the limiter (default: a new MemoryRateLimitStore)
+	Store RateLimitStore
+
+	// Returns the bucket key of a request (default: the client address from
+	// RemoteAddr). Behind a proxy, derive it from a trusted header or the
+	// authenticated user instead. Requests with an empty key are not limited.
+	Key func(ctx context.Context, r *http.Request) string
+}
+
+// RateLimitMiddleware limits each client to limit, answering requests over
+// it with 429 Too Many Requests and a Retry-After header. Responses carry
+// X-RateLimit-Limit and X-RateLimit-Remaining. When the store fails the
+// request is let through, so a store outage does not take the service down.
+func RateLimitMiddleware(limit RateLimit, opts RateLimitOptions) Middleware {
+	if opts.Store == nil {
+		opts.Store = NewMemoryRateLimitStore()
+	}
+	if opts.Key == nil {
+		opts.Key = remoteHost
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			key := opts.Key(ctx, r)
+			if key == "" {
+				return next(ctx, w, r)
+			}
+			res, err := opts.Store.Take(ctx, key, limit)
+			if err != nil {
+				return next(ctx, w, r)
+			}
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			if !res.Allowed {
+				seconds := int(math.Ceil(res.RetryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+				return NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded")
+			}
+			return next(ctx, w, r)
+		}
+	}
+}
+
+// remoteHost returns the host part of the request's RemoteAddr.
+func remoteHost(ctx context.Context, r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
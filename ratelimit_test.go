@@ -0,0 +1,85 @@
+package shttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	limit := RateLimit{Rate: 10, Burst: 2}
+	ctx := context.Background()
+
+	for i, want := range []bool{true, true, false} {
+		res, err := store.Take(ctx, "client", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != want {
+			t.Fatalf("take %d: Allowed = %v, want %v", i, res.Allowed, want)
+		}
+		if !res.Allowed && (res.RetryAfter <= 0 || res.RetryAfter > 100*time.Millisecond) {
+			t.Errorf("RetryAfter = %v, want up to 100ms", res.RetryAfter)
+		}
+	}
+	if res, _ := store.Take(ctx, "other", limit); !res.Allowed || res.Remaining != 1 {
+		t.Errorf("other client: %+v, want its own full bucket", res)
+	}
+
+	// One token is back after 1/Rate
+	store.buckets["client"].last = time.Now().Add(-150 * time.Millisecond)
+	if res, _ := store.Take(ctx, "client", limit); !res.Allowed {
+		t.Error("bucket did not refill")
+	}
+
+	store.buckets["other"].last = time.Now().Add(-time.Second)
+	store.sweep(time.Now())
+	if _, ok := store.buckets["other"]; ok {
+		t.Error("refilled bucket was not swept")
+	}
+}
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("store unavailable")
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	router := NewRouter()
+	router.Use(RateLimitMiddleware(RateLimit{Rate: 1, Burst: 1}, RateLimitOptions{}))
+	router.GET("/", simpleHandler("ok"))
+
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("10.0.0.1:1234")
+	if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "1" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("first request: %d, headers %v", w.Code, w.Header())
+	}
+	w = serve("10.0.0.1:5678")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("second request: %d, Retry-After %q, want 429 and 1", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w = serve("10.0.0.2:1234"); w.Code != http.StatusOK {
+		t.Errorf("other client: %d, want 200", w.Code)
+	}
+
+	failOpen := NewRouter()
+	failOpen.Use(RateLimitMiddleware(RateLimit{Rate: 1, Burst: 1}, RateLimitOptions{Store: failingRateLimitStore{}}))
+	failOpen.GET("/", simpleHandler("ok"))
+	w = httptest.NewRecorder()
+	failOpen.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("failing store: %d, want requests let through", w.Code)
+	}
+}
@@ -0,0 +1,131 @@
+package shttp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"time"
+)
+
+// liveConfig is the subset of Config that can be changed while the server runs.
+type liveConfig struct {
+	requestTimeout     time.Duration
+	maintenance        bool
+	maintenanceMessage string
+	readOnly           bool
+	readOnlyMessage    string
+	allowedOrigins     []string
+}
+
+func newLiveConfig(c *Config) *liveConfig {
+	msg := c.MaintenanceMessage
+	if msg == "" {
+		msg = "Service under maintenance"
+	}
+	return &liveConfig{
+		requestTimeout:     c.DefaultRequestTimeout,
+		maintenance:        c.MaintenanceMode,
+		maintenanceMessage: msg,
+		readOnly:           c.ReadOnlyMode,
+		readOnlyMessage:    c.ReadOnlyMessage,
+		allowedOrigins:     slices.Clone(c.AllowedOrigins),
+	}
+}
+
+// ApplyConfig applies the runtime-changeable settings of newCfg to a running
+// server: DefaultRequestTimeout (for new requests), MaintenanceMode,
+// MaintenanceMessage, ReadOnlyMode, ReadOnlyMessage and AllowedOrigins.
+// These settings are all replaced, not merged: fields left unset in newCfg
+// reset them, so a config that only sets AllowedOrigins also removes the
+// request timeout and turns maintenance and read-only mode off. Pass a
+// complete config, or use SetReadOnly to change that mode alone. Other
+// fields (address, server timeouts, logger) only take effect on restart
+// and are ignored.
+func (s *Server) ApplyConfig(newCfg *Config) error {
+	if newCfg == nil {
+		return errors.New("shttp: ApplyConfig called with nil config")
+	}
+	if newCfg.DefaultRequestTimeout < 0 {
+		return errors.New("shttp: DefaultRequestTimeout must not be negative")
+	}
+
+	live := newLiveConfig(newCfg)
+	s.liveMu.Lock()
+	s.live.Store(live)
+	s.Router().SetDefaultTimeout(live.requestTimeout)
+	s.Router().SetReadOnly(live.readOnly, live.readOnlyMessage)
+	s.liveMu.Unlock()
+	s.logger.Infof(s.ctx, "[server.config] Applied runtime config request_timeout=%s maintenance=%t read_only=%t allowed_origins=%v",
+		live.requestTimeout, live.maintenance, live.readOnly, live.allowedOrigins)
+	return nil
+}
+
+// SetReadOnly turns read-only mode on or off without touching the other
+// runtime settings, e.g. from an admin endpoint at the start of database
+// maintenance (see Config.ReadOnlyMode). An empty message selects the
+// default explanation.
+func (s *Server) SetReadOnly(enabled bool, message string) {
+	s.liveMu.Lock()
+	live := *s.live.Load()
+	live.readOnly, live.readOnlyMessage = enabled, message
+	s.live.Store(&live)
+	s.Router().SetReadOnly(enabled, message)
+	s.liveMu.Unlock()
+	s.logger.Infof(s.ctx, "[server.config] Read-only mode set to %t", enabled)
+}
+
+// CORSMiddleware returns a CORS middleware whose allowed origins follow
+// Config.AllowedOrigins, including changes made with ApplyConfig.
+func (s *Server) CORSMiddleware() Middleware {
+	return corsMiddleware(func() []string { return s.live.Load().allowedOrigins })
+}
+
+// WatchConfigFile polls path every interval and, on the first poll and
+// whenever its modification time changes, decodes it with decode and applies
+// the result with ApplyConfig. It blocks until ctx is done. Decode or apply
+// errors are logged and the previous settings stay in effect. A non-positive
+// interval is an error.
+func (s *Server) WatchConfigFile(ctx context.Context, path string, interval time.Duration, decode func([]byte) (*Config, error)) error {
+	return s.watchFile(ctx, "[server.config]", path, interval, func(data []byte) error {
+		cfg, err := decode(data)
+		if err != nil {
+			return err
+		}
+		return s.ApplyConfig(cfg)
+	})
+}
+
+// watchFile polls path every interval and calls load with its contents on
+// the first poll and whenever its modification time changes, until ctx is
+// done. Failures are logged with prefix.
+func (s *Server) watchFile(ctx context.Context, prefix, path string, interval time.Duration, load func(data []byte) error) error {
+	if interval <= 0 {
+		return errors.New("shttp: the file watch interval must be positive")
+	}
+	var lastMod time.Time
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().Equal(lastMod) {
+			continue
+		}
+		lastMod = info.ModTime()
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			s.logger.Errorf(ctx, "%s Reading %s failed: %v", prefix, path, err)
+			continue
+		}
+		if err := load(data); err != nil {
+			s.logger.Errorf(ctx, "%s Reloading %s failed: %v", prefix, path, err)
+		}
+	}
+}
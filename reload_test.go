@@ -0,0 +1,185 @@
+package shttp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andres-vara/slogr"
+)
+
+func TestServerApplyConfig(t *testing.T) {
+	server := New(context.Background(), &Config{Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	server.Use(server.CORSMiddleware())
+	server.GET("/test", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if _, ok := ctx.Deadline(); ok {
+			w.Header().Set("X-Deadline", "set")
+		}
+		w.Write([]byte("ok"))
+		return nil
+	})
+
+	tests := []struct {
+		name           string
+		config         *Config
+		wantStatusCode int
+		wantHeaders    map[string]string
+	}{
+		{
+			name:           "Initial config",
+			config:         &Config{},
+			wantStatusCode: http.StatusOK,
+			wantHeaders:    map[string]string{"Access-Control-Allow-Origin": "", "X-Deadline": ""},
+		},
+		{
+			name:           "Origins and timeout applied",
+			config:         &Config{AllowedOrigins: []string{"https://example.com"}, DefaultRequestTimeout: time.Second},
+			wantStatusCode: http.StatusOK,
+			wantHeaders:    map[string]string{"Access-Control-Allow-Origin": "https://example.com", "X-Deadline": "set"},
+		},
+		{
+			name:           "Maintenance mode",
+			config:         &Config{MaintenanceMode: true},
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantHeaders:    map[string]string{"Retry-After": "120"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := server.ApplyConfig(tt.config); err != nil {
+				t.Fatalf("ApplyConfig() error = %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Origin", "https://example.com")
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("Status code = %v, want %v", w.Code, tt.wantStatusCode)
+			}
+			for k, v := range tt.wantHeaders {
+				if got := w.Header().Get(k); got != v {
+					t.Errorf("Header %q = %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+
+	if err := server.ApplyConfig(nil); err == nil {
+		t.Error("ApplyConfig(nil) error = nil, want error")
+	}
+}
+
+func TestServerReadOnlyMode(t *testing.T) {
+	server := New(context.Background(), &Config{
+		Logger:          slogr.New(io.Discard, slogr.DefaultOptions()),
+		ReadOnlyMode:    true,
+		ReadOnlyMessage: "database maintenance until 02:00 UTC",
+	})
+	ok := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Write([]byte("ok"))
+		return nil
+	}
+	server.GET("/items", ok)
+	server.POST("/items", ok)
+	server.POST("/login", ok, AllowInReadOnly())
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/items", http.StatusOK},
+		{http.MethodPost, "/items", http.StatusServiceUnavailable},
+		{http.MethodPost, "/login", http.StatusOK},
+		{http.MethodPost, "/missing", http.StatusNotFound},
+		{http.MethodDelete, "/items", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		if w := do(tt.method, tt.path); w.Code != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, w.Code, tt.want)
+		}
+	}
+
+	w := do(http.MethodPost, "/items")
+	if !strings.Contains(w.Body.String(), "database maintenance") || w.Header().Get("Retry-After") != "120" {
+		t.Errorf("rejection = %q, Retry-After %q; want the message and 120", w.Body.String(), w.Header().Get("Retry-After"))
+	}
+
+	server.SetReadOnly(false, "")
+	if w := do(http.MethodPost, "/items"); w.Code != http.StatusOK {
+		t.Errorf("POST after SetReadOnly(false) = %d, want 200", w.Code)
+	}
+	if err := server.ApplyConfig(&Config{ReadOnlyMode: true}); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+	if w := do(http.MethodPost, "/items"); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), defaultReadOnlyMessage) {
+		t.Errorf("POST after ApplyConfig = %d %q, want 503 with the default message", w.Code, w.Body.String())
+	}
+}
+
+func TestServerReadOnlyModeConcurrentUpdates(t *testing.T) {
+	server := New(context.Background(), &Config{Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			server.ApplyConfig(&Config{ReadOnlyMode: i%2 == 0})
+		}()
+		go func() {
+			defer wg.Done()
+			server.SetReadOnly(i%2 == 1, "")
+		}()
+	}
+	wg.Wait()
+	if live, router := server.live.Load().readOnly, server.Router().readOnly.Load() != nil; live != router {
+		t.Errorf("read-only mode: server %t, router %t", live, router)
+	}
+}
+
+func TestServerWatchConfigFile(t *testing.T) {
+	server := New(context.Background(), &Config{Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"MaintenanceMode":true}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.WatchConfigFile(ctx, path, 5*time.Millisecond, func(data []byte) (*Config, error) {
+		var cfg Config
+		err := json.Unmarshal(data, &cfg)
+		return &cfg, err
+	})
+
+	waitFor(t, func() bool { return server.live.Load().maintenance })
+
+	// Ensure a distinct modification time before rewriting the file.
+	later := time.Now().Add(time.Second)
+	if err := os.WriteFile(path, []byte(`{"MaintenanceMode":false}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, later, later)
+
+	waitFor(t, func() bool { return !server.live.Load().maintenance })
+
+	if err := server.WatchConfigFile(ctx, path, 0, nil); err == nil {
+		t.Error("WatchConfigFile() with a zero interval succeeded")
+	}
+}
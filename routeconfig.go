@@ -0,0 +1,232 @@
+package shttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RoutesConfig is a declarative route table, decoded from JSON or YAML and
+// turned into a Router by RouteRegistry.Build:
+//
+//	{
+//	  "middleware": ["requestid", "logging"],
+//	  "routes": [
+//	    {"method": "GET", "path": "/users/{id}", "handler": "users.get", "timeout": "2s"},
+//	    {"method": "POST", "path": "/users", "handler": "users.create",
+//	     "middleware": ["auth"], "metadata": {"team": "identity"}}
+//	  ]
+//	}
+type RoutesConfig struct {
+	// Names of the middleware applied to every route, outermost first
+	Middleware []string `json:"middleware,omitempty" yaml:"middleware,omitempty"`
+
+	Routes []RouteConfig `json:"routes" yaml:"routes"`
+}
+
+// RouteConfig declares one route of a RoutesConfig.
+type RouteConfig struct {
+	// HTTP method; empty or "ANY" matches every method
+	Method string `json:"method,omitempty" yaml:"method,omitempty"`
+	Path   string `json:"path" yaml:"path"`
+
+	// Name of the handler in the RouteRegistry
+	Handler string `json:"handler" yaml:"handler"`
+
+	// Names of middleware applied to this route only, outermost first
+	Middleware []string `json:"middleware,omitempty" yaml:"middleware,omitempty"`
+
+	// Route name for URL building (see Name)
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Request timeout as a duration ("5s"), or "none" for NoTimeout
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	// "required" or "public" (see RequireAuth and Public)
+	Auth string `json:"auth,omitempty" yaml:"auth,omitempty"`
+
+	// Kill switch key (see KillSwitch)
+	KillSwitch string `json:"killSwitch,omitempty" yaml:"killSwitch,omitempty"`
+
+	Docs     string            `json:"docs,omitempty" yaml:"docs,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// RouteRegistry names the handlers and middleware a RoutesConfig can refer
+// to, so gateway-style deployments change routing by editing configuration
+// rather than code. It is safe for concurrent use.
+type RouteRegistry struct {
+	mu         sync.RWMutex
+	handlers   map[string]Handler
+	middleware map[string]Middleware
+}
+
+// NewRouteRegistry creates an empty RouteRegistry.
+func NewRouteRegistry() *RouteRegistry {
+	return &RouteRegistry{
+		handlers:   make(map[string]Handler),
+		middleware: make(map[string]Middleware),
+	}
+}
+
+// Handler registers handler under name.
+func (reg *RouteRegistry) Handler(name string, handler Handler) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.handlers[name] = handler
+}
+
+// Middleware registers middleware under name.
+func (reg *RouteRegistry) Middleware(name string, middleware Middleware) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.middleware[name] = middleware
+}
+
+// Build creates a new Router from cfg. Every problem of the configuration
+// (unknown handler or middleware names, invalid timeouts, conflicting
+// patterns, ...) is reported, joined in the returned error, and no router
+// is returned then.
+func (reg *RouteRegistry) Build(cfg *RoutesConfig) (router *Router, err error) {
+	if cfg == nil {
+		return nil, errors.New("shttp: nil routes config")
+	}
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	var errs []error
+	router = NewRouter()
+	router.Use(reg.lookupMiddleware(cfg.Middleware, "", &errs)...)
+	for i, rc := range cfg.Routes {
+		where := fmt.Sprintf("route %d (%s %s)", i, rc.Method, rc.Path)
+		handler, ok := reg.handlers[rc.Handler]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown handler %q", where, rc.Handler))
+			continue
+		}
+		opts, optErrs := rc.options(where)
+		if len(optErrs) > 0 {
+			errs = append(errs, optErrs...)
+			continue
+		}
+
+		method := strings.ToUpper(rc.Method)
+		if method == "ANY" {
+			method = ""
+		}
+		scope := router
+		if len(rc.Middleware) > 0 {
+			scope = router.newScope()
+			scope.Use(reg.lookupMiddleware(rc.Middleware, where, &errs)...)
+		}
+		if err := registerRoute(scope, method, rc.Path, handler, opts); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", where, err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return router, nil
+}
+
+// lookupMiddleware resolves middleware names, recording unknown ones.
+func (reg *RouteRegistry) lookupMiddleware(names []string, where string, errs *[]error) []Middleware {
+	mws := make([]Middleware, 0, len(names))
+	for _, name := range names {
+		mw, ok := reg.middleware[name]
+		if !ok {
+			if where == "" {
+				where = "routes config"
+			}
+			*errs = append(*errs, fmt.Errorf("%s: unknown middleware %q", where, name))
+			continue
+		}
+		mws = append(mws, mw)
+	}
+	return mws
+}
+
+// options converts the settings of rc to route options.
+func (rc RouteConfig) options(where string) ([]RouteOption, []error) {
+	var opts []RouteOption
+	var errs []error
+	switch rc.Timeout {
+	case "":
+	case "none":
+		opts = append(opts, NoTimeout())
+	default:
+		d, err := time.ParseDuration(rc.Timeout)
+		if err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("%s: invalid timeout %q", where, rc.Timeout))
+		} else {
+			opts = append(opts, Timeout(d))
+		}
+	}
+	switch rc.Auth {
+	case "":
+	case "required":
+		opts = append(opts, RequireAuth())
+	case "public":
+		opts = append(opts, Public())
+	default:
+		errs = append(errs, fmt.Errorf("%s: invalid auth %q", where, rc.Auth))
+	}
+	if rc.Name != "" {
+		opts = append(opts, Name(rc.Name))
+	}
+	if rc.KillSwitch != "" {
+		opts = append(opts, KillSwitch(rc.KillSwitch))
+	}
+	if rc.Docs != "" {
+		opts = append(opts, Docs(rc.Docs))
+	}
+	for k, v := range rc.Metadata {
+		opts = append(opts, WithMetadata(k, v))
+	}
+	return opts, errs
+}
+
+// registerRoute registers a route, turning the panics of invalid or
+// conflicting patterns into errors.
+func registerRoute(r *Router, method, path string, handler Handler, opts []RouteOption) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("%v", rec)
+		}
+	}()
+	if method == "" {
+		r.ANY(path, handler, opts...)
+	} else {
+		r.Handle(method, path, handler, opts...)
+	}
+	return nil
+}
+
+// LoadRoutes builds a router from cfg with reg and swaps it into the server
+// (see SwapRouter). On error the current routes stay in effect.
+func (s *Server) LoadRoutes(reg *RouteRegistry, cfg *RoutesConfig) error {
+	router, err := reg.Build(cfg)
+	if err != nil {
+		return err
+	}
+	return s.SwapRouter(router)
+}
+
+// WatchRoutesFile polls path every interval and, on the first poll and
+// whenever its modification time changes, decodes it into a RoutesConfig
+// with decode (json.Unmarshal, or a YAML Unmarshal function) and loads it
+// with LoadRoutes. It blocks until ctx is done. Invalid route files are
+// logged and the previous routes stay in effect. A non-positive interval is
+// an error.
+func (s *Server) WatchRoutesFile(ctx context.Context, path string, interval time.Duration, reg *RouteRegistry, decode func(data []byte, v any) error) error {
+	return s.watchFile(ctx, "[server.routes]", path, interval, func(data []byte) error {
+		var cfg RoutesConfig
+		if err := decode(data, &cfg); err != nil {
+			return err
+		}
+		return s.LoadRoutes(reg, &cfg)
+	})
+}
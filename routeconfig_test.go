@@ -0,0 +1,116 @@
+package shttp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andres-vara/slogr"
+)
+
+func testRouteRegistry() *RouteRegistry {
+	reg := NewRouteRegistry()
+	reg.Handler("users.get", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		_, err := io.WriteString(w, "user "+PathValue(r, "id"))
+		return err
+	})
+	reg.Handler("health", simpleHandler("ok"))
+	tag := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				w.Header().Add("X-Middleware", name)
+				return next(ctx, w, r)
+			}
+		}
+	}
+	reg.Middleware("global", tag("global"))
+	reg.Middleware("auth", tag("auth"))
+	return reg
+}
+
+func TestRouteRegistryBuild(t *testing.T) {
+	const config = `{
+		"middleware": ["global"],
+		"routes": [
+			{"method": "get", "path": "/users/{id}", "handler": "users.get", "middleware": ["auth"], "name": "user", "timeout": "2s"},
+			{"path": "/health", "handler": "health", "timeout": "none", "metadata": {"team": "sre"}}
+		]
+	}`
+	var cfg RoutesConfig
+	if err := json.Unmarshal([]byte(config), &cfg); err != nil {
+		t.Fatalf("decoding config: %v", err)
+	}
+	router, err := testRouteRegistry().Build(&cfg)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	tests := []struct {
+		method, path   string
+		want           string
+		wantMiddleware []string
+	}{
+		{http.MethodGet, "/users/42", "user 42", []string{"global", "auth"}},
+		{http.MethodPost, "/health", "ok", []string{"global"}},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Body.String() != tt.want {
+			t.Errorf("%s %s = %q, want %q", tt.method, tt.path, w.Body.String(), tt.want)
+		}
+		if got := w.Header().Values("X-Middleware"); strings.Join(got, ",") != strings.Join(tt.wantMiddleware, ",") {
+			t.Errorf("%s %s middleware = %v, want %v", tt.method, tt.path, got, tt.wantMiddleware)
+		}
+	}
+	if got, err := router.URL("user", map[string]string{"id": "7"}); err != nil || got != "/users/7" {
+		t.Errorf("URL(user) = %q, %v", got, err)
+	}
+}
+
+func TestRouteRegistryBuildErrors(t *testing.T) {
+	cfg := &RoutesConfig{
+		Middleware: []string{"missing-mw"},
+		Routes: []RouteConfig{
+			{Method: "GET", Path: "/a", Handler: "missing"},
+			{Method: "GET", Path: "/b", Handler: "health", Timeout: "soon"},
+			{Method: "GET", Path: "/c", Handler: "health", Auth: "maybe"},
+			{Method: "GET", Path: "/{x}", Handler: "health"},
+			{Method: "GET", Path: "/{y}", Handler: "health"},
+		},
+	}
+	_, err := testRouteRegistry().Build(cfg)
+	if err == nil {
+		t.Fatal("Build() error = nil")
+	}
+	for _, want := range []string{`unknown middleware "missing-mw"`, `unknown handler "missing"`, `invalid timeout "soon"`, `invalid auth "maybe"`, "conflicts"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Build() error = %v, want it to mention %s", err, want)
+		}
+	}
+}
+
+func TestServerLoadRoutes(t *testing.T) {
+	server := New(context.Background(), &Config{Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	server.GET("/old", simpleHandler("old"))
+	reg := testRouteRegistry()
+
+	if err := server.LoadRoutes(reg, &RoutesConfig{Routes: []RouteConfig{{Path: "/health", Handler: "missing"}}}); err == nil {
+		t.Error("LoadRoutes() with an unknown handler error = nil")
+	}
+	if err := server.LoadRoutes(reg, &RoutesConfig{Routes: []RouteConfig{{Method: "GET", Path: "/health", Handler: "health"}}}); err != nil {
+		t.Fatalf("LoadRoutes() error = %v", err)
+	}
+
+	for path, want := range map[string]int{"/health": http.StatusOK, "/old": http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Errorf("GET %s = %d, want %d", path, w.Code, want)
+		}
+	}
+}
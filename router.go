@@ -1,35 +1,503 @@
 package shttp
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"math"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/andres-vara/slogr"
 )
 
 // Router handles HTTP routing
 type Router struct {
-	// The underlying http.ServeMux
-	mux *http.ServeMux
+	// Routing tree matching request paths to patterns, guarded by mu
+	tree routeNode
+
+	// Guards the middleware stack and the route tables, which may change
+	// at runtime via Use, Remove and Replace
+	mu sync.RWMutex
 
 	// Middleware stack
 	middleware []Middleware
+
+	// Registered routes, in registration order
+	routes []*route
+
+	// Routes grouped by pattern; each pattern is inserted in the tree once
+	paths map[string]*pathRoutes
+
+	// Set for scoped routers, which register into their root's route table
+	// and apply their own middleware only to the routes added through them
+	parent *Router
+
+	// Path prefix of a group, prepended to the routes added through it
+	prefix string
+
+	// Deadline applied to every request unless the route overrides it
+	defaultTimeout atomic.Int64
+
+	// Set when DetectUseAfterReturn is enabled
+	leakLogger atomic.Pointer[slogr.Logger]
+
+	// Writes the response for handler errors, set with SetErrorHandler
+	errorHandler atomic.Pointer[ErrorHandler]
+
+	// Answer requests matching no route and requests whose method matches
+	// no route of their path, set with SetNotFoundHandler and
+	// SetMethodNotAllowedHandler
+	notFoundHandler         atomic.Pointer[Handler]
+	methodNotAllowedHandler atomic.Pointer[Handler]
+
+	// Message answering requests with unsafe methods while read-only mode
+	// is on, nil while it is off (see SetReadOnly)
+	readOnly atomic.Pointer[string]
+
+	// Kill switches turned on with Kill, by key; replaced, never modified
+	killed atomic.Pointer[map[string]KillSwitchState]
+
+	// Match methods while matching paths instead of dispatching on the
+	// method once the pattern is found (see UseMethodPatterns)
+	methodPatterns bool
+}
+
+// pathRoutes groups the routes registered for one pattern.
+type pathRoutes struct {
+	// Pattern shared by the routes, the names of its parameters and whether
+	// it ends with a named {name...} wildcard
+	pattern string
+	params  []string
+	named   bool
+
+	// Routes by HTTP method, plus the registration order of the methods
+	methods map[string]*route
+	order   []string
+
+	// Method-agnostic route registered with ANY
+	any *route
+
+	// Methods the pattern has a route for ("" for ANY), in method pattern
+	// mode
+	registered map[string]bool
+}
+
+// route describes a registered route.
+type route struct {
+	// HTTP method, empty for routes registered with ANY
+	method string
+
+	// Normalized pattern and the names of its parameters
+	pattern string
+	params  []string
+
+	// Handler invoked for matching requests
+	handler Handler
+
+	// Whether retrying the request is safe even if the method is not idempotent
+	idempotent bool
+
+	// Per-route deadline overriding the router default; noTimeout opts out
+	timeout   time.Duration
+	noTimeout bool
+
+	// Accepted request content types, documentation URL and free-form
+	// metadata, reported in OPTIONS discovery responses
+	consumes []string
+	docs     string
+	metadata map[string]string
+
+	// Query and header parameters validated before the handler runs
+	paramSpecs []ParamSpec
+
+	// Name used to build URLs to the route with Router.URL
+	name string
+
+	// Authentication requirement enforced by AuthMiddleware
+	auth authRequirement
+
+	// Maximum request body size in bytes, 0 for no limit
+	maxBodySize int64
+
+	// Whether the route keeps accepting every method in read-only mode
+	readOnlyAllowed bool
+
+	// Kill switch disabling the route at runtime (see KillSwitch)
+	killSwitch string
+}
+
+// String describes the route as "METHOD /path", with ANY for routes
+// registered for every method.
+func (rt *route) String() string {
+	method := rt.method
+	if method == "" {
+		method = "ANY"
+	}
+	return method + " " + rt.pattern
+}
+
+// RouteOption configures a route at registration time.
+type RouteOption func(*route)
+
+// Idempotent marks a route as safe to retry although its method is not
+// idempotent by definition (e.g. a POST deduplicated by an idempotency key).
+func Idempotent() RouteOption {
+	return func(rt *route) {
+		rt.idempotent = true
+	}
+}
+
+// Timeout overrides the router's default request timeout for the route.
+func Timeout(d time.Duration) RouteOption {
+	return func(rt *route) {
+		rt.timeout = d
+	}
+}
+
+// NoTimeout exempts the route from the router's default request timeout,
+// e.g. for long-lived streaming responses. The exemption is deliberately
+// explicit so every route without a deadline is visible at registration.
+func NoTimeout() RouteOption {
+	return func(rt *route) {
+		rt.noTimeout = true
+	}
+}
+
+// AllowInReadOnly keeps the route accepting every method while the router
+// is in read-only mode (see Router.SetReadOnly), e.g. for login or admin
+// endpoints that do not write to the database under maintenance.
+func AllowInReadOnly() RouteOption {
+	return func(rt *route) {
+		rt.readOnlyAllowed = true
+	}
+}
+
+// requestTimeout returns the deadline for a request to the route, zero for none.
+func (rt *route) requestTimeout(defaultTimeout time.Duration) time.Duration {
+	switch {
+	case rt.noTimeout:
+		return 0
+	case rt.timeout > 0:
+		return rt.timeout
+	default:
+		return defaultTimeout
+	}
+}
+
+// Consumes declares the request content types accepted by the route.
+func Consumes(contentTypes ...string) RouteOption {
+	return func(rt *route) {
+		rt.consumes = append(rt.consumes, contentTypes...)
+	}
+}
+
+// Docs links the route to its documentation.
+func Docs(url string) RouteOption {
+	return func(rt *route) {
+		rt.docs = url
+	}
+}
+
+// WithMetadata attaches a free-form key/value pair to the route, such as
+// its authentication scheme or owning team.
+func WithMetadata(key, value string) RouteOption {
+	return func(rt *route) {
+		if rt.metadata == nil {
+			rt.metadata = make(map[string]string)
+		}
+		rt.metadata[key] = value
+	}
+}
+
+// MaxBodySize limits the route's request bodies to n bytes. Requests
+// declaring a larger Content-Length are refused with 413 before the handler
+// runs; reading past the limit otherwise fails with a 413 HTTPError.
+func MaxBodySize(n int64) RouteOption {
+	return func(rt *route) {
+		rt.maxBodySize = n
+	}
+}
+
+// limitBody wraps handler with the enforcement of a body size limit.
+func limitBody(n int64, handler Handler) Handler {
+	errTooLarge := NewHTTPError(http.StatusRequestEntityTooLarge, "request body too large")
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.ContentLength > n {
+			return errTooLarge
+		}
+		limited := *r
+		limited.Body = struct {
+			io.Reader
+			io.Closer
+		}{&limitReader{r: r.Body, remaining: n, err: errTooLarge}, r.Body}
+		return handler(ctx, w, &limited)
+	}
+}
+
+// retrySafe reports whether a client may safely retry a request to this route.
+func (rt *route) retrySafe(method string) bool {
+	if rt.idempotent {
+		return true
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
 }
 
 // NewRouter creates a new router
 func NewRouter() *Router {
 	return &Router{
-		mux: http.NewServeMux(),
+		paths: make(map[string]*pathRoutes),
+	}
+}
+
+// newScope returns a router sharing r's route table whose own middleware
+// (added with Use) only applies to routes registered through it.
+func (r *Router) newScope() *Router {
+	return &Router{parent: r, prefix: r.prefix}
+}
+
+// Group returns a router for routes sharing the path prefix: routes
+// registered on it are mounted under prefix, and middleware added to it with
+// Use only applies to them (after the middleware of the enclosing routers).
+// Groups can be nested. A route registered at "/" (or "") maps to the prefix
+// itself; use "/{$}" for the prefix with a trailing slash.
+//
+//	api := r.Group("/api/v1")
+//	api.Use(authMiddleware)
+//	api.GET("/users/{id}", getUser) // GET /api/v1/users/{id}
+func (r *Router) Group(prefix string) *Router {
+	g := r.newScope()
+	g.prefix = r.prefix + strings.TrimSuffix(strings.TrimSpace(prefix), "/")
+	return g
+}
+
+// fullPath prepends the group prefix to path.
+func (r *Router) fullPath(path string) string {
+	if r.prefix == "" {
+		return path
+	}
+	if path == "" || path == "/" {
+		return r.prefix
 	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return r.prefix + path
 }
 
-// ServeHTTP implements the http.Handler interface
+// root returns the router owning the route table.
+func (r *Router) root() *Router {
+	for r.parent != nil {
+		r = r.parent
+	}
+	return r
+}
+
+// scoped wraps handler with the middleware of r and of its ancestors below
+// the root; the root's middleware is applied when the route is served.
+func (r *Router) scoped(handler Handler) Handler {
+	if r.parent == nil {
+		return handler
+	}
+	return r.parent.scoped(func(ctx context.Context, w http.ResponseWriter, req *http.Request) error {
+		return r.applyMiddleware(handler)(ctx, w, req)
+	})
+}
+
+// inherit copies the runtime settings of old, the router r replaces, keeping
+// the handlers r sets itself (see Server.SwapRouter).
+func (r *Router) inherit(old *Router) {
+	r.defaultTimeout.Store(old.defaultTimeout.Load())
+	r.readOnly.Store(old.readOnly.Load())
+	r.killed.Store(old.killed.Load())
+	r.leakLogger.CompareAndSwap(nil, old.leakLogger.Load())
+	r.errorHandler.CompareAndSwap(nil, old.errorHandler.Load())
+	r.notFoundHandler.CompareAndSwap(nil, old.notFoundHandler.Load())
+	r.methodNotAllowedHandler.CompareAndSwap(nil, old.methodNotAllowedHandler.Load())
+}
+
+// SetDefaultTimeout sets the deadline applied to the context of every request
+// whose route does not use Timeout or NoTimeout. Zero disables it. It is safe
+// to call while serving; new requests pick up the change.
+func (r *Router) SetDefaultTimeout(d time.Duration) {
+	r.root().defaultTimeout.Store(int64(d))
+}
+
+// defaultReadOnlyMessage answers unsafe requests in read-only mode unless
+// SetReadOnly is given a message.
+const defaultReadOnlyMessage = "Service is temporarily read-only"
+
+// readOnlyRetryAfter is the Retry-After hint of read-only rejections, as for
+// maintenance mode.
+const readOnlyRetryAfter = 2 * time.Minute
+
+// SetReadOnly turns read-only mode on or off. While it is on, requests with
+// methods other than GET, HEAD and OPTIONS are answered with 503 Service
+// Unavailable and message (a default explanation when empty), except on
+// routes registered with AllowInReadOnly. The rejection goes through the
+// middleware chain and the error handler. It is safe to call while serving.
+func (r *Router) SetReadOnly(enabled bool, message string) {
+	if !enabled {
+		r.root().readOnly.Store(nil)
+		return
+	}
+	if message == "" {
+		message = defaultReadOnlyMessage
+	}
+	r.root().readOnly.Store(&message)
+}
+
+// readOnlyRejection returns the handler answering an unsafe request to rt
+// in read-only mode, or nil when the request may go through.
+func (r *Router) readOnlyRejection(rt *route, req *http.Request) Handler {
+	message := r.readOnly.Load()
+	if message == nil || rt.readOnlyAllowed {
+		return nil
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+	return func(ctx context.Context, w http.ResponseWriter, req *http.Request) error {
+		return HTTPError{Message: *message, StatusCode: http.StatusServiceUnavailable, RetryAfter: readOnlyRetryAfter}
+	}
+}
+
+// maxInlineParams is the number of path parameter values a lookup collects
+// without allocating.
+const maxInlineParams = 8
+
+// ServeHTTP implements the http.Handler interface. Like ServeMux, it
+// redirects requests for unclean paths to their cleaned form and "/tree" to
+// "/tree/" when only the latter is registered, and sets the request's
+// Pattern and path values (see http.Request.PathValue).
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	// In Go 1.22+, the standard mux can handle path parameters
-	// Let the mux handle the request directly to preserve path parameters
-	r.mux.ServeHTTP(w, req)
+	r = r.root()
+	var buf [maxInlineParams]string
+	res := r.lookup(req, buf[:0])
+	switch {
+	case res.redirect != "":
+		http.Redirect(w, req, res.redirect, http.StatusMovedPermanently)
+		return
+	case res.pr == nil && len(res.allow) > 0:
+		r.methodNotAllowed(w, req, req.URL.Path, res.allow)
+		return
+	case res.pr == nil:
+		r.notFound(w, req)
+		return
+	}
+
+	req.Pattern = res.pr.pattern
+	if res.method != "" {
+		req.Pattern = res.method + " " + res.pr.pattern
+	}
+	for i, name := range res.pr.params {
+		req.SetPathValue(name, res.matches[i])
+	}
+	if r.methodPatterns {
+		r.serveMethod(res.pr, res.method, w, req)
+	} else {
+		r.dispatch(res.pr, w, req)
+	}
+}
+
+// lookupResult is the outcome of matching a request against the tree.
+type lookupResult struct {
+	// Matched pattern and its parameter values, nil when nothing matched
+	pr      *pathRoutes
+	matches []string
+
+	// Method the pattern matched for, in method pattern mode ("" for ANY)
+	method string
+
+	// URL to redirect to instead of serving the request
+	redirect string
+
+	// Methods allowed on the path when no pattern matched the request
+	// method, in method pattern mode
+	allow []string
+}
+
+// lookup matches req against the routing tree, appending the parameter
+// values to matches.
+func (r *Router) lookup(req *http.Request, matches []string) lookupResult {
+	escaped := req.URL.EscapedPath()
+	path := escaped
+	if req.Method != http.MethodConnect {
+		path = cleanPath(path)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	n, method, matches := r.matchMethod(req.Method, path, matches)
+	if !n.exact(path) && path != "" && !strings.HasSuffix(path, "/") {
+		// Redirect "/tree" to "/tree/" when that matches exactly
+		withSlash := path + "/"
+		if n, _, _ := r.matchMethod(req.Method, withSlash, nil); n.exact(withSlash) {
+			return lookupResult{redirect: redirectURL(withSlash, req.URL.RawQuery)}
+		}
+	}
+	if path != escaped {
+		return lookupResult{redirect: redirectURL(path, req.URL.RawQuery)}
+	}
+	if n == nil {
+		var allow []string
+		if r.methodPatterns {
+			set := make(map[string]bool)
+			r.tree.methods(path, set)
+			if set[http.MethodGet] {
+				set[http.MethodHead] = true
+			}
+			delete(set, "")
+			allow = slices.Sorted(maps.Keys(set))
+		}
+		return lookupResult{allow: allow}
+	}
+	return lookupResult{pr: n.routes, matches: matches, method: method}
+}
+
+// matchMethod matches path, only considering in method pattern mode the
+// patterns registered for method, then for GET when method is HEAD, then
+// for any method, like ServeMux does.
+func (r *Router) matchMethod(method, path string, matches []string) (*routeNode, string, []string) {
+	if !r.methodPatterns {
+		n, values := r.tree.match(path, matchFilter{}, matches)
+		return n, "", values
+	}
+	if n, values := r.tree.match(path, matchFilter{method: method, only: true}, matches); n != nil {
+		return n, method, values
+	}
+	if method == http.MethodHead {
+		if n, values := r.tree.match(path, matchFilter{method: http.MethodGet, only: true}, matches); n != nil {
+			return n, http.MethodGet, values
+		}
+	}
+	n, values := r.tree.match(path, matchFilter{only: true}, matches)
+	return n, "", values
+}
+
+// redirectURL builds a redirect target from an escaped path and a raw query.
+func redirectURL(escaped, rawQuery string) string {
+	u := &url.URL{Path: pathUnescape(escaped), RawPath: escaped, RawQuery: rawQuery}
+	return u.String()
 }
 
 // applyMiddleware wraps the given handler with all middleware
 func (r *Router) applyMiddleware(handler Handler) Handler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	// Apply all middleware in reverse order
 	// This creates a processing pipeline where the first middleware in the stack is the outermost wrapper
 	result := handler
@@ -39,97 +507,396 @@ func (r *Router) applyMiddleware(handler Handler) Handler {
 	return result
 }
 
-// Handle registers a handler for the given method and path.
-func (r *Router) Handle(method, path string, handler Handler) {
-	r.mux.HandleFunc(path, func(w http.ResponseWriter, req *http.Request) {
-		if req.Method != method {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
+// Handle registers a handler for the given method and path. The path is
+// validated and normalized at registration (see ValidatePattern); invalid
+// patterns panic.
+func (r *Router) Handle(method, path string, handler Handler, opts ...RouteOption) {
+	r.root().addRoute(method, r.fullPath(path), r.scoped(handler), opts)
+}
 
-		// If the registered pattern contains path parameters, extract them
-		// from the actual request path and inject them into the request context.
-		reqToUse := req
-		if strings.Contains(path, "{") && strings.Contains(path, "}") {
-			if params := extractPathParams(path, req.URL.Path); len(params) > 0 {
-				reqToUse = SetPathValues(req, params)
+// addRoute records a route in the route table and inserts its pattern in
+// the routing tree the first time the pattern is seen.
+func (r *Router) addRoute(method, path string, handler Handler, opts []RouteOption) *route {
+	rt := newRoute(method, path, handler, opts)
+	path = rt.pattern
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if rt.name != "" {
+		for _, other := range r.routes {
+			if other.name == rt.name && other.pattern != rt.pattern {
+				panic(fmt.Sprintf("shttp: route name %q already used for %q", rt.name, other.pattern))
 			}
 		}
+	}
+
+	pr, ok := r.paths[path]
+	if !ok {
+		pr = &pathRoutes{
+			pattern:    path,
+			params:     rt.params,
+			named:      strings.HasSuffix(path, "...}"),
+			methods:    make(map[string]*route),
+			registered: make(map[string]bool),
+		}
+		r.tree.insert(path, pr)
+		r.paths[path] = pr
+	}
+	if r.methodPatterns {
+		pr.registered[method] = true
+	}
+	r.routes = append(r.routes, rt)
+	if method == "" {
+		pr.any = rt
+	} else {
+		if _, exists := pr.methods[method]; !exists {
+			pr.order = append(pr.order, method)
+		}
+		pr.methods[method] = rt
+	}
+	return rt
+}
+
+// UseMethodPatterns switches the router to ServeMux-style method patterns
+// ("GET /users/{id}"): the method is matched along with the path, so a
+// request falls through to a less specific pattern registered for its
+// method instead of stopping at the most specific path. Requests for a known
+// path with an unregistered method get 405 with an Allow header, GET routes
+// also answer HEAD, and OPTIONS discovery is not available. Remove and
+// Replace keep working; requests for a removed route fall through to a less
+// specific pattern, as if it was never registered. It must be called before
+// any route is registered.
+func (r *Router) UseMethodPatterns() {
+	r = r.root()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.paths) > 0 {
+		panic("shttp: UseMethodPatterns must be called before registering routes")
+	}
+	r.methodPatterns = true
+}
+
+// serveMethod serves a request matched in method pattern mode with the
+// route currently registered for the matched method.
+func (r *Router) serveMethod(pr *pathRoutes, method string, w http.ResponseWriter, req *http.Request) {
+	r.mu.RLock()
+	rt := pr.any
+	if method != "" {
+		rt = pr.methods[method]
+	}
+	r.mu.RUnlock()
+
+	if rt == nil {
+		// Removed since the lookup
+		r.notFound(w, req)
+		return
+	}
+	r.serve(rt, w, req)
+}
+
+// newRoute builds a route and applies its options. Invalid patterns panic
+// with a descriptive error (see ValidatePattern), like ServeMux does.
+func newRoute(method, path string, handler Handler, opts []RouteOption) *route {
+	pattern, params, err := parsePattern(path)
+	if err != nil {
+		panic(err)
+	}
+	rt := &route{method: method, pattern: pattern, params: params, handler: handler}
+	for _, opt := range opts {
+		opt(rt)
+	}
+	if len(rt.paramSpecs) > 0 {
+		rt.handler = validateParams(rt.paramSpecs, rt.handler)
+	}
+	if rt.maxBodySize > 0 {
+		rt.handler = limitBody(rt.maxBodySize, rt.handler)
+	}
+	return rt
+}
+
+// Remove unregisters the route for method and path (an empty method removes
+// the ANY route). It reports whether a route was removed. Requests for a
+// pattern without any remaining route receive 404.
+func (r *Router) Remove(method, path string) bool {
+	path, err := ValidatePattern(r.fullPath(path))
+	if err != nil {
+		return false
+	}
+	r = r.root()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pr, ok := r.paths[path]
+	if !ok {
+		return false
+	}
+	var removed *route
+	if method == "" {
+		removed, pr.any = pr.any, nil
+	} else if rt, exists := pr.methods[method]; exists {
+		removed = rt
+		delete(pr.methods, method)
+		pr.order = slices.DeleteFunc(pr.order, func(m string) bool { return m == method })
+	}
+	if removed == nil {
+		return false
+	}
+	delete(pr.registered, method)
+	r.routes = slices.DeleteFunc(r.routes, func(rt *route) bool { return rt == removed })
+	return true
+}
+
+// Replace swaps the handler (and options) of the route registered for method
+// and path, keeping its position in the route table. It reports whether a
+// route was found; use Handle to register new routes.
+func (r *Router) Replace(method, path string, handler Handler, opts ...RouteOption) bool {
+	rt := newRoute(method, r.fullPath(path), r.scoped(handler), opts)
+	path = rt.pattern
+	r = r.root()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-		ctx := reqToUse.Context()
-		handlerWithMiddleware := r.applyMiddleware(handler)
-
-		// Create a new response writer to track whether the header has been written.
-		rw := &responseWriter{ResponseWriter: w}
-
-		// Call the handler with the wrapped response writer.
-		if err := handlerWithMiddleware(ctx, rw, reqToUse); err != nil {
-			// If the header has not been written, write the error to the response.
-			if !rw.wroteHeader {
-				if httpErr, ok := err.(HTTPError); ok {
-					http.Error(w, httpErr.Message, httpErr.StatusCode)
-				} else {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-				}
+	pr, ok := r.paths[path]
+	if !ok {
+		return false
+	}
+	var old *route
+	if method == "" {
+		old = pr.any
+	} else {
+		old = pr.methods[method]
+	}
+	if old == nil {
+		return false
+	}
+	if method == "" {
+		pr.any = rt
+	} else {
+		pr.methods[method] = rt
+	}
+	r.routes[slices.Index(r.routes, old)] = rt
+	return true
+}
+
+// routeList returns a snapshot of the registered routes.
+func (r *Router) routeList() []*route {
+	r = r.root()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.routes)
+}
+
+// dispatch selects the route for the request method among the routes
+// registered for a pattern.
+func (r *Router) dispatch(pr *pathRoutes, w http.ResponseWriter, req *http.Request) {
+	r.mu.RLock()
+	rt, ok := pr.methods[req.Method]
+	if !ok {
+		rt = pr.any
+	}
+	empty := len(pr.methods) == 0 && pr.any == nil
+	r.mu.RUnlock()
+
+	if rt != nil {
+		r.serve(rt, w, req)
+		return
+	}
+	if empty {
+		// Every route for this pattern was removed
+		r.notFound(w, req)
+		return
+	}
+	if req.Method == http.MethodOptions {
+		// Discovery runs through the middleware chain so CORS preflight
+		// handling still takes precedence.
+		// Preflight requests carry no credentials, so discovery is public.
+		r.serve(&route{method: http.MethodOptions, pattern: pr.pattern, handler: discoveryHandler(r, pr), auth: authPublic, readOnlyAllowed: true}, w, req)
+		return
+	}
+
+	// Same methods as the OPTIONS discovery response
+	r.mu.RLock()
+	allow := append(slices.Clone(pr.order), http.MethodOptions)
+	r.mu.RUnlock()
+	r.methodNotAllowed(w, req, pr.pattern, allow)
+}
+
+// SetNotFoundHandler sets the handler answering requests that match no
+// route, e.g. to return JSON or a custom page. It runs through the
+// middleware chain like any route, so request IDs and logging apply; errors
+// it returns go to the error handler. nil restores the default, which
+// answers 404 page not found. It is safe to call while serving.
+func (r *Router) SetNotFoundHandler(h Handler) {
+	if h == nil {
+		r.root().notFoundHandler.Store(nil)
+		return
+	}
+	r.root().notFoundHandler.Store(&h)
+}
+
+// notFound answers a request that matched no route.
+func (r *Router) notFound(w http.ResponseWriter, req *http.Request) {
+	handler := defaultNotFound
+	if h := r.notFoundHandler.Load(); h != nil {
+		handler = *h
+	}
+	// Like the 405 response, a 404 reveals nothing worth authenticating for,
+	// and stays a 404 in read-only mode.
+	r.serve(&route{method: req.Method, pattern: req.URL.Path, handler: handler, auth: authPublic, readOnlyAllowed: true}, w, req)
+}
+
+func defaultNotFound(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return NewHTTPError(http.StatusNotFound, "404 page not found")
+}
+
+// SetMethodNotAllowedHandler sets the handler answering requests whose path
+// matches a route but whose method does not. It runs through the middleware
+// chain like any route, with the Allow header already set; errors it returns
+// go to the error handler. nil restores the default, which answers 405
+// Method Not Allowed. It is safe to call while serving.
+func (r *Router) SetMethodNotAllowedHandler(h Handler) {
+	if h == nil {
+		r.root().methodNotAllowedHandler.Store(nil)
+		return
+	}
+	r.root().methodNotAllowedHandler.Store(&h)
+}
+
+// methodNotAllowed answers a request for pattern with an unregistered
+// method, listing the allowed ones in the Allow header.
+func (r *Router) methodNotAllowed(w http.ResponseWriter, req *http.Request, pattern string, allow []string) {
+	w.Header().Set("Allow", strings.Join(allow, ", "))
+	handler := defaultMethodNotAllowed
+	if h := r.methodNotAllowedHandler.Load(); h != nil {
+		handler = *h
+	}
+	// Allowed methods are public, as in the OPTIONS discovery response.
+	r.serve(&route{method: req.Method, pattern: pattern, handler: handler, auth: authPublic, readOnlyAllowed: true}, w, req)
+}
+
+func defaultMethodNotAllowed(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return NewHTTPError(http.StatusMethodNotAllowed, "Method not allowed")
+}
+
+// serve runs the route's handler through the middleware chain and writes
+// the error response if the handler fails before writing one. A body the
+// handler left unread, e.g. when returning early on a validation error, is
+// not drained here: net/http discards it (up to 256 KiB, closing the
+// connection beyond) once the response is sent, and skips it when an
+// "Expect: 100-continue" client was never asked for it.
+func (r *Router) serve(rt *route, w http.ResponseWriter, req *http.Request) {
+	// If the registered pattern contains path parameters, take the values
+	// matched by the mux and inject them into the request context.
+	reqToUse := req
+	if len(rt.params) > 0 {
+		reqToUse = SetPathValues(req, pathParams(req, rt.params))
+	}
+
+	// Expose the router to handlers for URL reversal (see URLFor), and the
+	// route to middleware consulting its options (see AuthMiddleware)
+	ctx := context.WithValue(reqToUse.Context(), routerKey{}, r)
+	ctx = context.WithValue(ctx, routeKey{}, rt)
+	if timeout := rt.requestTimeout(time.Duration(r.defaultTimeout.Load())); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+		reqToUse = reqToUse.WithContext(ctx)
+	}
+	handler := rt.handler
+	if rt.killSwitch != "" {
+		handler = r.killSwitchGuard(rt.killSwitch, handler)
+	}
+	if reject := r.readOnlyRejection(rt, req); reject != nil {
+		handler = reject
+	}
+	handlerWithMiddleware := r.applyMiddleware(fallbackRescue(handler))
+
+	// In leak detection mode, the handler only gets guarded access to the
+	// body and writer, revoked once it returns.
+	if logger := r.leakLogger.Load(); logger != nil {
+		guard := &leakGuard{ctx: ctx, logger: logger, route: rt}
+		defer guard.returned.Store(true)
+		guarded := *reqToUse
+		guarded.Body = &guardedBody{ReadCloser: reqToUse.Body, guard: guard}
+		reqToUse = &guarded
+		w = &guardedWriter{ResponseWriter: w, guard: guard}
+	}
+
+	// Create a new response writer to track whether the header has been written.
+	rw := &responseWriter{ResponseWriter: w}
+
+	// Call the handler with the wrapped response writer.
+	if err := handlerWithMiddleware(ctx, rw, reqToUse); err != nil {
+		// If the header has not been written, write the error to the response.
+		if !rw.wroteHeader {
+			if h := r.errorHandler.Load(); h != nil {
+				setRetryHeaders(w, req, rt, err)
+				(*h)(ctx, w, req, err)
+			} else {
+				writeError(w, req, rt, err)
 			}
 		}
-	})
+	}
+}
+
+// writeError writes err as the response. Upstream/availability failures
+// (502, 503, 504) carry retry hints so clients can retry correctly.
+func writeError(w http.ResponseWriter, req *http.Request, rt *route, err error) {
+	setRetryHeaders(w, req, rt, err)
+	DefaultErrorHandler(req.Context(), w, req, err)
+}
+
+// setRetryHeaders sets the retry hints of 502, 503 and 504 responses.
+func setRetryHeaders(w http.ResponseWriter, req *http.Request, rt *route, err error) {
+	status := statusFromError(err)
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		retryAfter := defaultRetryAfter
+		if httpErr, ok := err.(HTTPError); ok && httpErr.RetryAfter > 0 {
+			retryAfter = httpErr.RetryAfter
+		}
+		// Round up: "Retry-After: 0" would invite an immediate retry
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+		w.Header().Set("Idempotency-Safe", strconv.FormatBool(rt.retrySafe(req.Method)))
+	}
 }
 
 // GET registers a GET route handler
-func (r *Router) GET(path string, handler Handler) {
-	r.Handle(http.MethodGet, path, handler)
+func (r *Router) GET(path string, handler Handler, opts ...RouteOption) {
+	r.Handle(http.MethodGet, path, handler, opts...)
 }
 
 // POST registers a POST route handler
-func (r *Router) POST(path string, handler Handler) {
-	r.Handle(http.MethodPost, path, handler)
+func (r *Router) POST(path string, handler Handler, opts ...RouteOption) {
+	r.Handle(http.MethodPost, path, handler, opts...)
 }
 
 // PUT registers a PUT route handler
-func (r *Router) PUT(path string, handler Handler) {
-	r.Handle(http.MethodPut, path, handler)
+func (r *Router) PUT(path string, handler Handler, opts ...RouteOption) {
+	r.Handle(http.MethodPut, path, handler, opts...)
 }
 
 // DELETE registers a DELETE route handler
-func (r *Router) DELETE(path string, handler Handler) {
-	r.Handle(http.MethodDelete, path, handler)
+func (r *Router) DELETE(path string, handler Handler, opts ...RouteOption) {
+	r.Handle(http.MethodDelete, path, handler, opts...)
 }
 
 // PATCH registers a PATCH route handler
-func (r *Router) PATCH(path string, handler Handler) {
-	r.Handle(http.MethodPatch, path, handler)
+func (r *Router) PATCH(path string, handler Handler, opts ...RouteOption) {
+	r.Handle(http.MethodPatch, path, handler, opts...)
 }
 
 // ANY registers a handler for all HTTP methods on a path.
 // Internally it registers a single handler without method filtering.
-func (r *Router) ANY(path string, handler Handler) {
-	r.mux.HandleFunc(path, func(w http.ResponseWriter, req *http.Request) {
-		reqToUse := req
-		if strings.Contains(path, "{") && strings.Contains(path, "}") {
-			if params := extractPathParams(path, req.URL.Path); len(params) > 0 {
-				reqToUse = SetPathValues(req, params)
-			}
-		}
-
-		ctx := reqToUse.Context()
-		handlerWithMiddleware := r.applyMiddleware(handler)
-
-		// Wrap the response writer to track header writes.
-		rw := &responseWriter{ResponseWriter: w}
-		if err := handlerWithMiddleware(ctx, rw, reqToUse); err != nil {
-			if !rw.wroteHeader {
-				if httpErr, ok := err.(HTTPError); ok {
-					http.Error(w, httpErr.Message, httpErr.StatusCode)
-				} else {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-				}
-			}
-		}
-	})
+func (r *Router) ANY(path string, handler Handler, opts ...RouteOption) {
+	r.root().addRoute("", r.fullPath(path), r.scoped(handler), opts)
 }
 
 // Use adds middleware to the router
 func (r *Router) Use(middleware ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.middleware = append(r.middleware, middleware...)
 }
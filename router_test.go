@@ -1,12 +1,19 @@
 package shttp
 
 import (
+	"bufio"
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httptrace"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/andres-vara/slogr"
 )
@@ -179,7 +186,7 @@ func TestServerRouting(t *testing.T) {
 			w := httptest.NewRecorder()
 
 			// Serve the request
-			server.router.ServeHTTP(w, req)
+			server.Router().ServeHTTP(w, req)
 
 			// Check the response
 			if w.Code != tt.wantStatusCode {
@@ -192,3 +199,561 @@ func TestServerRouting(t *testing.T) {
 		})
 	}
 }
+
+func TestRouterRetryHints(t *testing.T) {
+	unavailable := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return NewHTTPError(http.StatusServiceUnavailable, "overloaded")
+	}
+
+	tests := []struct {
+		name           string
+		setupRouter    func(*Router)
+		requestMethod  string
+		wantRetryAfter string
+		wantSafe       string
+	}{
+		{
+			name: "GET is retry safe",
+			setupRouter: func(r *Router) {
+				r.GET("/test", unavailable)
+			},
+			requestMethod:  http.MethodGet,
+			wantRetryAfter: "1",
+			wantSafe:       "true",
+		},
+		{
+			name: "POST is not retry safe",
+			setupRouter: func(r *Router) {
+				r.POST("/test", unavailable)
+			},
+			requestMethod:  http.MethodPost,
+			wantRetryAfter: "1",
+			wantSafe:       "false",
+		},
+		{
+			name: "POST marked idempotent with custom Retry-After",
+			setupRouter: func(r *Router) {
+				r.POST("/test", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					return HTTPError{Message: "upstream down", StatusCode: http.StatusBadGateway, RetryAfter: 30 * time.Second}
+				}, Idempotent())
+			},
+			requestMethod:  http.MethodPost,
+			wantRetryAfter: "30",
+			wantSafe:       "true",
+		},
+		{
+			name: "Sub-second Retry-After rounds up",
+			setupRouter: func(r *Router) {
+				r.GET("/test", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					return HTTPError{Message: "overloaded", StatusCode: http.StatusServiceUnavailable, RetryAfter: 200 * time.Millisecond}
+				})
+			},
+			requestMethod:  http.MethodGet,
+			wantRetryAfter: "1",
+			wantSafe:       "true",
+		},
+		{
+			name: "No hints for other errors",
+			setupRouter: func(r *Router) {
+				r.GET("/test", errorHandler("boom"))
+			},
+			requestMethod:  http.MethodGet,
+			wantRetryAfter: "",
+			wantSafe:       "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewRouter()
+			tt.setupRouter(router)
+
+			req := httptest.NewRequest(tt.requestMethod, "/test", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+			if got := w.Header().Get("Idempotency-Safe"); got != tt.wantSafe {
+				t.Errorf("Idempotency-Safe = %q, want %q", got, tt.wantSafe)
+			}
+		})
+	}
+}
+
+func TestRouterRemoveReplace(t *testing.T) {
+	tests := []struct {
+		name           string
+		change         func(*Router) bool
+		wantChanged    bool
+		requestMethod  string
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:           "Remove one method keeps the other",
+			change:         func(r *Router) bool { return r.Remove(http.MethodPost, "/items") },
+			wantChanged:    true,
+			requestMethod:  http.MethodGet,
+			wantStatusCode: http.StatusOK,
+			wantBody:       "list",
+		},
+		{
+			name:           "Removed method is not allowed",
+			change:         func(r *Router) bool { return r.Remove(http.MethodPost, "/items") },
+			wantChanged:    true,
+			requestMethod:  http.MethodPost,
+			wantStatusCode: http.StatusMethodNotAllowed,
+			wantBody:       "Method not allowed\n",
+		},
+		{
+			name: "Removing every method yields 404",
+			change: func(r *Router) bool {
+				return r.Remove(http.MethodGet, "/items") && r.Remove(http.MethodPost, "/items")
+			},
+			wantChanged:    true,
+			requestMethod:  http.MethodGet,
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "404 page not found\n",
+		},
+		{
+			name:           "Remove unknown route",
+			change:         func(r *Router) bool { return r.Remove(http.MethodDelete, "/items") },
+			wantChanged:    false,
+			requestMethod:  http.MethodGet,
+			wantStatusCode: http.StatusOK,
+			wantBody:       "list",
+		},
+		{
+			name:           "Replace swaps the handler",
+			change:         func(r *Router) bool { return r.Replace(http.MethodGet, "/items", simpleHandler("list v2")) },
+			wantChanged:    true,
+			requestMethod:  http.MethodGet,
+			wantStatusCode: http.StatusOK,
+			wantBody:       "list v2",
+		},
+		{
+			name:           "Replace unknown route",
+			change:         func(r *Router) bool { return r.Replace(http.MethodGet, "/other", simpleHandler("x")) },
+			wantChanged:    false,
+			requestMethod:  http.MethodGet,
+			wantStatusCode: http.StatusOK,
+			wantBody:       "list",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewRouter()
+			router.GET("/items", simpleHandler("list"))
+			router.POST("/items", simpleHandler("created"))
+
+			if got := tt.change(router); got != tt.wantChanged {
+				t.Fatalf("change reported %v, want %v", got, tt.wantChanged)
+			}
+
+			req := httptest.NewRequest(tt.requestMethod, "/items", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("Status code = %v, want %v", w.Code, tt.wantStatusCode)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("Body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestRouterDefaultTimeout(t *testing.T) {
+	reportDeadline := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			w.Write([]byte("none"))
+			return nil
+		}
+		if time.Until(deadline) > time.Minute {
+			w.Write([]byte("long"))
+		} else {
+			w.Write([]byte("short"))
+		}
+		return nil
+	}
+
+	router := NewRouter()
+	router.SetDefaultTimeout(time.Second)
+	router.GET("/default", reportDeadline)
+	router.GET("/override", reportDeadline, Timeout(time.Hour))
+	router.GET("/stream", reportDeadline, NoTimeout())
+
+	tests := []struct {
+		path     string
+		wantBody string
+	}{
+		{path: "/default", wantBody: "short"},
+		{path: "/override", wantBody: "long"},
+		{path: "/stream", wantBody: "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("Body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestRouterUnreadBody(t *testing.T) {
+	router := NewRouter()
+	router.POST("/items", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return NewHTTPError(http.StatusBadRequest, "invalid")
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	t.Run("keep-alive connection reused", func(t *testing.T) {
+		var reused []bool
+		trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) { reused = append(reused, info.Reused) }}
+		for range 2 {
+			req, _ := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace),
+				http.MethodPost, ts.URL+"/items", strings.NewReader(strings.Repeat("x", 4096)))
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if len(reused) != 2 || !reused[1] {
+			t.Errorf("connection reuse = %v, want the second request on the first connection", reused)
+		}
+	})
+
+	t.Run("Expect 100-continue body not requested", func(t *testing.T) {
+		conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		fmt.Fprintf(conn, "POST /items HTTP/1.1\r\nHost: test\r\nContent-Length: 1048576\r\nExpect: 100-continue\r\n\r\n")
+		status, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(status, "HTTP/1.1 400") {
+			t.Errorf("first response line = %q, want the 400 without 100 Continue", status)
+		}
+	})
+}
+
+func TestRouterMethodPatterns(t *testing.T) {
+	router := NewRouter()
+	router.UseMethodPatterns()
+	router.GET("/items", simpleHandler("list"))
+	router.POST("/items", simpleHandler("create"))
+	router.ANY("/any", simpleHandler("any"))
+	router.PUT("/any", simpleHandler("put"))
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+		wantAllow  string
+	}{
+		{name: "GET route", method: http.MethodGet, path: "/items", wantStatus: http.StatusOK, wantBody: "list"},
+		{name: "POST route", method: http.MethodPost, path: "/items", wantStatus: http.StatusOK, wantBody: "create"},
+		{name: "Unregistered method gets 405 with Allow", method: http.MethodDelete, path: "/items", wantStatus: http.StatusMethodNotAllowed, wantAllow: "GET, HEAD, POST"},
+		{name: "Unknown path gets 404", method: http.MethodGet, path: "/missing", wantStatus: http.StatusNotFound},
+		{name: "Method route beats ANY", method: http.MethodPut, path: "/any", wantStatus: http.StatusOK, wantBody: "put"},
+		{name: "ANY route", method: http.MethodPatch, path: "/any", wantStatus: http.StatusOK, wantBody: "any"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if got := w.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+		})
+	}
+
+	router.Remove(http.MethodGet, "/items")
+	router.Replace(http.MethodPost, "/items", simpleHandler("create v2"))
+	for method, want := range map[string]int{http.MethodGet: http.StatusMethodNotAllowed, http.MethodPost: http.StatusOK} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/items", nil))
+		if w.Code != want {
+			t.Errorf("%s after Remove/Replace = %d, want %d", method, w.Code, want)
+		}
+	}
+	router.GET("/items", simpleHandler("list v2"))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items", nil))
+	if w.Body.String() != "list v2" {
+		t.Errorf("re-registered GET = %q, want %q", w.Body.String(), "list v2")
+	}
+
+	// A removed route no longer shadows less specific patterns
+	router.GET("/items/{id}", simpleHandler("item"))
+	router.GET("/items/new", simpleHandler("new"))
+	router.Remove(http.MethodGet, "/items/new")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items/new", nil))
+	if w.Body.String() != "item" {
+		t.Errorf("GET after removing the specific pattern = %q, want %q", w.Body.String(), "item")
+	}
+}
+
+func TestRouterGroup(t *testing.T) {
+	tag := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				w.Header().Add("X-Middleware", name)
+				return next(ctx, w, r)
+			}
+		}
+	}
+
+	router := NewRouter()
+	router.Use(tag("root"))
+	router.GET("/health", simpleHandler("ok"))
+
+	api := router.Group("/api/v1/")
+	api.Use(tag("api"))
+	api.GET("/users/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Write([]byte("user " + PathValue(r, "id")))
+		return nil
+	})
+	api.GET("/", simpleHandler("api index"))
+
+	admin := api.Group("/admin")
+	admin.Use(tag("admin"))
+	admin.POST("/reindex", simpleHandler("reindexed"))
+
+	tests := []struct {
+		method         string
+		path           string
+		wantStatus     int
+		wantBody       string
+		wantMiddleware []string
+	}{
+		{http.MethodGet, "/health", http.StatusOK, "ok", []string{"root"}},
+		{http.MethodGet, "/api/v1/users/7", http.StatusOK, "user 7", []string{"root", "api"}},
+		{http.MethodGet, "/api/v1", http.StatusOK, "api index", []string{"root", "api"}},
+		{http.MethodPost, "/api/v1/admin/reindex", http.StatusOK, "reindexed", []string{"root", "api", "admin"}},
+		// Unmatched requests only go through the root middleware
+		{http.MethodGet, "/users/7", http.StatusNotFound, "", []string{"root"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if got := w.Header().Values("X-Middleware"); !slices.Equal(got, tt.wantMiddleware) {
+				t.Errorf("middleware = %v, want %v", got, tt.wantMiddleware)
+			}
+		})
+	}
+
+	if !admin.Remove(http.MethodPost, "/reindex") {
+		t.Error("Remove through the group did not find the prefixed route")
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	router := NewRouter()
+	router.POST("/upload", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%d bytes", len(data))
+		return nil
+	}, MaxBodySize(8))
+
+	tests := []struct {
+		name          string
+		body          io.Reader
+		contentLength int64
+		wantStatus    int
+	}{
+		{"within limit", strings.NewReader("12345678"), 8, http.StatusOK},
+		{"declared too large", strings.NewReader("123456789"), 9, http.StatusRequestEntityTooLarge},
+		{"streamed too large", io.MultiReader(strings.NewReader("12345"), strings.NewReader("67890")), -1, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/upload", tt.body)
+			req.ContentLength = tt.contentLength
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRouterErrorHandler(t *testing.T) {
+	errNotFound := errors.New("order not found")
+	jsonErrors := func(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, errNotFound):
+			status = http.StatusNotFound
+		case statusFromError(err) != http.StatusInternalServerError:
+			status = statusFromError(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"error":%q}`, err.Error())
+	}
+
+	server := New(context.Background(), &Config{Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	server.SetErrorHandler(jsonErrors)
+	server.GET("/orders/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return fmt.Errorf("loading %s: %w", PathValue(r, "id"), errNotFound)
+	})
+	server.Group("/api").GET("/busy", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return NewHTTPError(http.StatusServiceUnavailable, "overloaded")
+	})
+
+	tests := []struct {
+		path           string
+		wantStatus     int
+		wantBody       string
+		wantRetryAfter string
+	}{
+		{"/orders/7", http.StatusNotFound, `{"error":"loading 7: order not found"}`, ""},
+		{"/api/busy", http.StatusServiceUnavailable, `{"error":"overloaded"}`, "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			server.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantStatus || w.Body.String() != tt.wantBody {
+				t.Errorf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+		})
+	}
+
+	server.SetErrorHandler(nil)
+	w := httptest.NewRecorder()
+	server.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/7", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("after reset: status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestRouterMethodNotAllowed(t *testing.T) {
+	for _, methodPatterns := range []bool{false, true} {
+		t.Run(fmt.Sprintf("methodPatterns=%v", methodPatterns), func(t *testing.T) {
+			router := NewRouter()
+			if methodPatterns {
+				router.UseMethodPatterns()
+			}
+			var seen []string
+			router.Use(func(next Handler) Handler {
+				return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					seen = append(seen, r.Method)
+					return next(ctx, w, r)
+				}
+			})
+			router.POST("/items", simpleHandler("create"))
+			router.GET("/items", simpleHandler("list"))
+
+			wantAllow := "POST, GET, OPTIONS"
+			if methodPatterns {
+				wantAllow = "GET, HEAD, POST"
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/items", nil))
+			if w.Code != http.StatusMethodNotAllowed || w.Body.String() != "Method not allowed\n" {
+				t.Errorf("got %d %q, want 405 Method not allowed", w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Allow"); got != wantAllow {
+				t.Errorf("Allow = %q, want %q", got, wantAllow)
+			}
+			if !slices.Equal(seen, []string{http.MethodDelete}) {
+				t.Errorf("middleware saw %v, want the 405 request", seen)
+			}
+
+			router.SetMethodNotAllowedHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return JSON(w, http.StatusMethodNotAllowed, map[string]string{"allow": w.Header().Get("Allow")})
+			})
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/items", nil))
+			if want := fmt.Sprintf(`{"allow":%q}`, wantAllow); w.Code != http.StatusMethodNotAllowed || strings.TrimSpace(w.Body.String()) != want {
+				t.Errorf("custom handler: got %d %s, want 405 %s", w.Code, w.Body.String(), want)
+			}
+
+			router.SetMethodNotAllowedHandler(nil)
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/items", nil))
+			if w.Body.String() != "Method not allowed\n" {
+				t.Errorf("after reset: body = %q", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRouterNotFound(t *testing.T) {
+	server := New(context.Background(), &Config{
+		Logger: slogr.New(io.Discard, slogr.DefaultOptions()),
+		NotFoundHandler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			return JSON(w, http.StatusNotFound, map[string]string{"error": "no route for " + r.URL.Path, "request_id": GetRequestID(ctx)})
+		},
+	})
+	server.Use(RequestIDMiddleware())
+	server.GET("/items/{id}", simpleHandler("item"))
+	server.Router().Remove(http.MethodGet, "/items/{id}")
+
+	for _, path := range []string{"/missing", "/items/1"} {
+		w := httptest.NewRecorder()
+		server.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		requestID := w.Header().Get("X-Request-ID")
+		want := fmt.Sprintf(`{"error":"no route for %s","request_id":%q}`, path, requestID)
+		if w.Code != http.StatusNotFound || strings.TrimSpace(w.Body.String()) != want {
+			t.Errorf("%s: got %d %s, want 404 %s", path, w.Code, w.Body.String(), want)
+		}
+		if requestID == "" {
+			t.Errorf("%s: 404 did not go through the middleware chain", path)
+		}
+	}
+
+	server.SetNotFoundHandler(nil)
+	w := httptest.NewRecorder()
+	server.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if w.Code != http.StatusNotFound || w.Body.String() != "404 page not found\n" {
+		t.Errorf("after reset: got %d %q", w.Code, w.Body.String())
+	}
+}
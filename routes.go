@@ -0,0 +1,236 @@
+package shttp
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	// HTTP method, empty for routes registered with ANY
+	Method string
+
+	// Normalized pattern and the names of its parameters
+	Pattern string
+	Params  []string
+
+	// Name given with the Name option
+	Name string
+
+	// Whether the route was registered with Public or RequireAuth
+	Public       bool
+	RequiresAuth bool
+
+	// Per-route deadline (0 when the router default applies) or whether
+	// the route opted out of deadlines with NoTimeout
+	Timeout   time.Duration
+	NoTimeout bool
+
+	// Maximum request body size, 0 for no limit
+	MaxBodySize int64
+
+	// Declared with Consumes, Docs and WithMetadata
+	Consumes []string
+	Docs     string
+	Metadata map[string]string
+}
+
+// info returns the description of the route.
+func (rt *route) info() RouteInfo {
+	return RouteInfo{
+		Method:       rt.method,
+		Pattern:      rt.pattern,
+		Params:       slices.Clone(rt.params),
+		Name:         rt.name,
+		Public:       rt.auth == authPublic,
+		RequiresAuth: rt.auth == authRequired,
+		Timeout:      rt.timeout,
+		NoTimeout:    rt.noTimeout,
+		MaxBodySize:  rt.maxBodySize,
+		Consumes:     slices.Clone(rt.consumes),
+		Docs:         rt.docs,
+		Metadata:     rt.metadata,
+	}
+}
+
+// Match reports the route that would handle a request for method and path
+// (which may include a query), with the values of its path parameters,
+// without running it. Matching is the one applied to real requests. It
+// reports false when no route would run: no pattern matches, the method is
+// not allowed, or the router would answer with a redirect (e.g. to the
+// cleaned path).
+func (r *Router) Match(method, path string) (RouteInfo, map[string]string, bool) {
+	r = r.root()
+	req, err := http.NewRequest(method, path, nil)
+	if err != nil {
+		return RouteInfo{}, nil, false
+	}
+	res := r.lookup(req, nil)
+	if res.pr == nil || res.redirect != "" {
+		return RouteInfo{}, nil, false
+	}
+
+	r.mu.RLock()
+	var rt *route
+	if r.methodPatterns {
+		rt = res.pr.any
+		if res.method != "" {
+			rt = res.pr.methods[res.method]
+		}
+	} else if rt = res.pr.methods[method]; rt == nil {
+		rt = res.pr.any
+	}
+	r.mu.RUnlock()
+	if rt == nil {
+		return RouteInfo{}, nil, false
+	}
+
+	params := make(map[string]string, len(rt.params))
+	for i, name := range res.pr.params {
+		params[name] = res.matches[i]
+	}
+	return rt.info(), params, true
+}
+
+// Routes returns the registered routes, in registration order.
+func (r *Router) Routes() []RouteInfo {
+	routes := r.routeList()
+	infos := make([]RouteInfo, len(routes))
+	for i, rt := range routes {
+		infos[i] = rt.info()
+	}
+	return infos
+}
+
+// RouteFormat is an output format of ExportRoutes.
+type RouteFormat string
+
+const (
+	RoutesJSON     RouteFormat = "json"
+	RoutesCSV      RouteFormat = "csv"
+	RoutesMarkdown RouteFormat = "markdown"
+)
+
+// exportedRoute is a route as written by ExportRoutes.
+type exportedRoute struct {
+	Method      string            `json:"method"`
+	Pattern     string            `json:"pattern"`
+	Name        string            `json:"name,omitempty"`
+	Auth        string            `json:"auth,omitempty"`
+	Timeout     string            `json:"timeout,omitempty"`
+	MaxBodySize int64             `json:"max_body_size,omitempty"`
+	Consumes    []string          `json:"consumes,omitempty"`
+	Docs        string            `json:"docs,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// routeColumns are the CSV and markdown columns of ExportRoutes.
+var routeColumns = []string{"method", "pattern", "name", "auth", "timeout", "max_body_size", "consumes", "docs", "metadata"}
+
+// row returns the route's values in routeColumns order.
+func (e exportedRoute) row() []string {
+	maxBody := ""
+	if e.MaxBodySize > 0 {
+		maxBody = strconv.FormatInt(e.MaxBodySize, 10)
+	}
+	metadata := make([]string, 0, len(e.Metadata))
+	for _, k := range slices.Sorted(maps.Keys(e.Metadata)) {
+		metadata = append(metadata, k+"="+e.Metadata[k])
+	}
+	return []string{e.Method, e.Pattern, e.Name, e.Auth, e.Timeout, maxBody,
+		strings.Join(e.Consumes, " "), e.Docs, strings.Join(metadata, " ")}
+}
+
+// ExportRoutes renders the route table as JSON, CSV or a markdown table,
+// sorted by pattern then method so the output diffs cleanly between
+// versions. It is meant for documentation and change review, e.g. behind a
+// flag of the application binary:
+//
+//	if *printRoutes != "" {
+//		out, err := server.Router().ExportRoutes(shttp.RouteFormat(*printRoutes))
+//		...
+//	}
+//
+// Routes registered with ANY are listed with the method ANY; the auth
+// column is "public", "required" or empty when undeclared.
+func (r *Router) ExportRoutes(format RouteFormat) ([]byte, error) {
+	infos := r.Routes()
+	routes := make([]exportedRoute, len(infos))
+	for i, info := range infos {
+		e := exportedRoute{
+			Method:      info.Method,
+			Pattern:     info.Pattern,
+			Name:        info.Name,
+			MaxBodySize: info.MaxBodySize,
+			Consumes:    info.Consumes,
+			Docs:        info.Docs,
+			Metadata:    info.Metadata,
+		}
+		if e.Method == "" {
+			e.Method = "ANY"
+		}
+		switch {
+		case info.Public:
+			e.Auth = "public"
+		case info.RequiresAuth:
+			e.Auth = "required"
+		}
+		switch {
+		case info.NoTimeout:
+			e.Timeout = "none"
+		case info.Timeout > 0:
+			e.Timeout = info.Timeout.String()
+		}
+		routes[i] = e
+	}
+	slices.SortStableFunc(routes, func(a, b exportedRoute) int {
+		return cmp.Or(cmp.Compare(a.Pattern, b.Pattern), cmp.Compare(a.Method, b.Method))
+	})
+
+	var buf bytes.Buffer
+	switch format {
+	case RoutesJSON:
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(routes); err != nil {
+			return nil, err
+		}
+	case RoutesCSV:
+		cw := csv.NewWriter(&buf)
+		cw.Write(routeColumns)
+		for _, e := range routes {
+			cw.Write(e.row())
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return nil, err
+		}
+	case RoutesMarkdown:
+		writeMarkdownRow(&buf, routeColumns)
+		buf.WriteString("|" + strings.Repeat(" --- |", len(routeColumns)) + "\n")
+		for _, e := range routes {
+			writeMarkdownRow(&buf, e.row())
+		}
+	default:
+		return nil, fmt.Errorf("shttp: unknown route format %q (want json, csv or markdown)", format)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeMarkdownRow writes a markdown table row, escaping pipes in cells.
+func writeMarkdownRow(buf *bytes.Buffer, cells []string) {
+	buf.WriteString("|")
+	for _, cell := range cells {
+		buf.WriteString(" " + strings.ReplaceAll(cell, "|", `\|`) + " |")
+	}
+	buf.WriteString("\n")
+}
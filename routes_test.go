@@ -0,0 +1,146 @@
+package shttp
+
+import (
+	"maps"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRouterMatch(t *testing.T) {
+	newRouter := func(methodPatterns bool) *Router {
+		r := NewRouter()
+		if methodPatterns {
+			r.UseMethodPatterns()
+		}
+		r.GET("/users/{id}", simpleHandler("user"), Name("user"))
+		r.GET("/users/me", simpleHandler("me"), Public())
+		r.ANY("/files/{path...}", simpleHandler("file"))
+		r.Group("/api").POST("/orders", simpleHandler("order"), MaxBodySize(1<<20))
+		return r
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		wantOK      bool
+		wantMethod  string
+		wantPattern string
+		wantParams  map[string]string
+	}{
+		{"parameter", http.MethodGet, "/users/42", true, http.MethodGet, "/users/{id}", map[string]string{"id": "42"}},
+		{"literal wins over parameter", http.MethodGet, "/users/me", true, http.MethodGet, "/users/me", map[string]string{}},
+		{"query ignored", http.MethodGet, "/users/42?full=1", true, http.MethodGet, "/users/{id}", map[string]string{"id": "42"}},
+		{"escaped parameter", http.MethodGet, "/users/a%2Fb", true, http.MethodGet, "/users/{id}", map[string]string{"id": "a/b"}},
+		{"wildcard for any method", http.MethodDelete, "/files/a/b.txt", true, "", "/files/{path...}", map[string]string{"path": "a/b.txt"}},
+		{"group prefix", http.MethodPost, "/api/orders", true, http.MethodPost, "/api/orders", map[string]string{}},
+		{"method not allowed", http.MethodDelete, "/users/42", false, "", "", nil},
+		{"no pattern", http.MethodGet, "/missing", false, "", "", nil},
+		{"redirect to cleaned path", http.MethodGet, "/users/x/../42", false, "", "", nil},
+		{"invalid method", "BAD METHOD", "/users/42", false, "", "", nil},
+	}
+
+	for _, mode := range []bool{false, true} {
+		router := newRouter(mode)
+		for _, tt := range tests {
+			name := tt.name
+			if mode {
+				name += " (method patterns)"
+			}
+			t.Run(name, func(t *testing.T) {
+				info, params, ok := router.Match(tt.method, tt.path)
+				if ok != tt.wantOK {
+					t.Fatalf("Match() ok = %v, want %v", ok, tt.wantOK)
+				}
+				if info.Method != tt.wantMethod || info.Pattern != tt.wantPattern {
+					t.Errorf("Match() route = %q %q, want %q %q", info.Method, info.Pattern, tt.wantMethod, tt.wantPattern)
+				}
+				if !maps.Equal(params, tt.wantParams) {
+					t.Errorf("Match() params = %v, want %v", params, tt.wantParams)
+				}
+			})
+		}
+	}
+
+	info, _, _ := newRouter(false).Match(http.MethodGet, "/users/1")
+	if info.Name != "user" || len(info.Params) != 1 || info.Params[0] != "id" {
+		t.Errorf("Match() info = %+v, want name and params of the route", info)
+	}
+}
+
+func TestRouterExportRoutes(t *testing.T) {
+	router := NewRouter()
+	router.POST("/users", simpleHandler("created"), RequireAuth(), MaxBodySize(1024), Consumes("application/json"), Timeout(5*time.Second))
+	router.GET("/users/{id}", simpleHandler("user"), Name("user"), WithMetadata("team", "identity"), WithMetadata("tier", "1"))
+	router.GET("/health", simpleHandler("ok"), Public(), NoTimeout(), Docs("https://example.com/a|b"))
+	router.ANY("/files/{path...}", simpleHandler("file"))
+
+	tests := []struct {
+		format RouteFormat
+		want   string
+	}{
+		{RoutesCSV, `method,pattern,name,auth,timeout,max_body_size,consumes,docs,metadata
+ANY,/files/{path...},,,,,,,
+GET,/health,,public,none,,,https://example.com/a|b,
+POST,/users,,required,5s,1024,application/json,,
+GET,/users/{id},user,,,,,,team=identity tier=1
+`},
+		{RoutesMarkdown, `| method | pattern | name | auth | timeout | max_body_size | consumes | docs | metadata |
+| --- | --- | --- | --- | --- | --- | --- | --- | --- |
+| ANY | /files/{path...} |  |  |  |  |  |  |  |
+| GET | /health |  | public | none |  |  | https://example.com/a\|b |  |
+| POST | /users |  | required | 5s | 1024 | application/json |  |  |
+| GET | /users/{id} | user |  |  |  |  |  | team=identity tier=1 |
+`},
+		{RoutesJSON, `[
+  {
+    "method": "ANY",
+    "pattern": "/files/{path...}"
+  },
+  {
+    "method": "GET",
+    "pattern": "/health",
+    "auth": "public",
+    "timeout": "none",
+    "docs": "https://example.com/a|b"
+  },
+  {
+    "method": "POST",
+    "pattern": "/users",
+    "auth": "required",
+    "timeout": "5s",
+    "max_body_size": 1024,
+    "consumes": [
+      "application/json"
+    ]
+  },
+  {
+    "method": "GET",
+    "pattern": "/users/{id}",
+    "name": "user",
+    "metadata": {
+      "team": "identity",
+      "tier": "1"
+    }
+  }
+]
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			got, err := router.ExportRoutes(tt.format)
+			if err != nil {
+				t.Fatalf("ExportRoutes() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ExportRoutes() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := router.ExportRoutes("yaml"); err == nil {
+		t.Error("ExportRoutes(yaml) error = nil, want unknown format error")
+	}
+}
@@ -0,0 +1,93 @@
+package shttp
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// SecurityFinding is a potential weakness reported by Server.SecurityReport.
+type SecurityFinding struct {
+	// Category of the check: "auth", "body-limit", "cors", "timeout" or "tls"
+	Check string
+
+	// Route concerned, as "METHOD /path", empty for server-wide findings
+	Route string
+
+	// Description of the weakness
+	Message string
+}
+
+// String formats the finding for logs.
+func (f SecurityFinding) String() string {
+	if f.Route == "" {
+		return fmt.Sprintf("[%s] %s", f.Check, f.Message)
+	}
+	return fmt.Sprintf("[%s] %s: %s", f.Check, f.Route, f.Message)
+}
+
+// SecurityReport audits the server configuration and routes, listing:
+//   - routes without an authentication declaration (RequireAuth or Public)
+//   - routes accepting a body (POST, PUT, PATCH and ANY) without MaxBodySize
+//   - a wildcard in Config.AllowedOrigins
+//   - missing server timeouts, which leave slow clients holding connections
+//   - TLS settings allowing versions below 1.2 or insecure cipher suites
+//
+// Findings are hints for review rather than errors. Call it before Start to
+// print them, or in a test to fail the build on new ones:
+//
+//	for _, f := range server.SecurityReport() {
+//		logger.Warn(ctx, "[server.security] "+f.String())
+//	}
+func (s *Server) SecurityReport() []SecurityFinding {
+	var findings []SecurityFinding
+	add := func(check, route, format string, args ...any) {
+		findings = append(findings, SecurityFinding{Check: check, Route: route, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, rt := range s.Router().routeList() {
+		if rt.auth == authDefault {
+			add("auth", rt.String(), "no authentication requirement declared (RequireAuth or Public)")
+		}
+		if rt.maxBodySize == 0 && acceptsBody(rt.method) {
+			add("body-limit", rt.String(), "request body size is not limited (MaxBodySize)")
+		}
+	}
+
+	if slices.Contains(s.live.Load().allowedOrigins, "*") {
+		add("cors", "", "AllowedOrigins allows every origin")
+	}
+
+	hs := s.server
+	if hs.ReadHeaderTimeout == 0 && hs.ReadTimeout == 0 {
+		add("timeout", "", "no read timeout: clients can hold connections by sending headers slowly")
+	}
+	if hs.WriteTimeout == 0 && s.Router().defaultTimeout.Load() == 0 {
+		add("timeout", "", "no write or request timeout: requests can run forever")
+	}
+	if hs.IdleTimeout == 0 && hs.ReadTimeout == 0 {
+		add("timeout", "", "no idle timeout: keep-alive connections are never closed")
+	}
+
+	if cfg := hs.TLSConfig; cfg != nil {
+		if cfg.MinVersion != 0 && cfg.MinVersion < tls.VersionTLS12 {
+			add("tls", "", "minimum version %s is below TLS 1.2", tls.VersionName(cfg.MinVersion))
+		}
+		for _, suite := range tls.InsecureCipherSuites() {
+			if slices.Contains(cfg.CipherSuites, suite.ID) {
+				add("tls", "", "insecure cipher suite %s enabled", suite.Name)
+			}
+		}
+	}
+	return findings
+}
+
+// acceptsBody reports whether requests with method usually carry a body.
+func acceptsBody(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, "":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package shttp
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/andres-vara/slogr"
+)
+
+func TestSecurityReport(t *testing.T) {
+	newServer := func(config *Config) *Server {
+		config.Logger = slogr.New(io.Discard, slogr.DefaultOptions())
+		return New(context.Background(), config)
+	}
+
+	t.Run("weak configuration", func(t *testing.T) {
+		s := newServer(&Config{AllowedOrigins: []string{"*"}})
+		s.GET("/items", simpleHandler("items"), RequireAuth())
+		s.POST("/items", simpleHandler("created"))
+		s.HTTPServer().TLSConfig = &tls.Config{
+			MinVersion:   tls.VersionTLS10,
+			CipherSuites: []uint16{tls.TLS_RSA_WITH_RC4_128_SHA},
+		}
+
+		var got []string
+		for _, f := range s.SecurityReport() {
+			got = append(got, f.String())
+		}
+		want := []string{
+			"[auth] POST /items: no authentication requirement declared (RequireAuth or Public)",
+			"[body-limit] POST /items: request body size is not limited (MaxBodySize)",
+			"[cors] AllowedOrigins allows every origin",
+			"[timeout] no read timeout: clients can hold connections by sending headers slowly",
+			"[timeout] no write or request timeout: requests can run forever",
+			"[timeout] no idle timeout: keep-alive connections are never closed",
+			"[tls] minimum version TLS 1.0 is below TLS 1.2",
+			"[tls] insecure cipher suite TLS_RSA_WITH_RC4_128_SHA enabled",
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("SecurityReport() =\n%q\nwant\n%q", got, want)
+		}
+	})
+
+	t.Run("hardened configuration", func(t *testing.T) {
+		s := newServer(&Config{
+			ReadTimeout:           5 * time.Second,
+			IdleTimeout:           time.Minute,
+			DefaultRequestTimeout: 10 * time.Second,
+			AllowedOrigins:        []string{"https://example.com"},
+		})
+		s.GET("/health", simpleHandler("ok"), Public())
+		s.POST("/items", simpleHandler("created"), RequireAuth(), MaxBodySize(1<<20))
+
+		if findings := s.SecurityReport(); len(findings) != 0 {
+			t.Errorf("SecurityReport() = %v, want no findings", findings)
+		}
+	})
+}
@@ -2,13 +2,28 @@ package shttp
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/andres-vara/shttp/health"
 	"github.com/andres-vara/slogr"
 )
 
+// defaultShutdownTimeout is how long Run waits for the server to drain when
+// Config.ShutdownTimeout is not set.
+const defaultShutdownTimeout = 10 * time.Second
+
 // Server represents an HTTP server with structured configuration
 type Server struct {
 	// The underlying http.Server
@@ -17,12 +32,69 @@ type Server struct {
 	// Server configuration
 	config *Config
 
-	// Router for handling requests
-	router *Router
+	// Router for handling requests, replaced by SwapRouter
+	router atomic.Pointer[Router]
 
 	// Logger instance
 	logger *slogr.Logger
 
+	// Tracks goroutines started via Go
+	goroutines *goroutineTracker
+
+	// Long-lived connections closed gracefully on Shutdown
+	conns *connRegistry
+
+	// States of the client connections, reported by Shutdown, and whether
+	// the ConnState hook recording them is installed
+	connStates    *connStates
+	connStateOnce sync.Once
+
+	// Fans out messages sent with Publish
+	pubsub *pubSub
+
+	// Degraded-mode switches of the handlers built with Degraded
+	degradations *degradations
+
+	// Checkers mounted with EnableHealth, failing readiness on Shutdown
+	healthCheckers []*health.Checker
+
+	// Paths of the probes mounted with EnableHealth, served in maintenance
+	// mode
+	probePaths []string
+
+	// Closed once the server has fully stopped
+	stopped  chan struct{}
+	stopOnce sync.Once
+
+	// Settings that can be changed at runtime with ApplyConfig. Writers
+	// hold liveMu, so the router's copies of the settings stay in sync.
+	live   atomic.Pointer[liveConfig]
+	liveMu sync.Mutex
+
+	// Values registered with Provide, exposed to handlers via Get/MustGet
+	providers providers
+
+	// Lifecycle hooks run before listening and after shutdown
+	startHooks []func(ctx context.Context) error
+	stopHooks  []func(ctx context.Context) error
+
+	// Address of the listener, once bound
+	addr atomic.Pointer[net.Addr]
+
+	// HTTP/3 server started by StartQUIC, and the Alt-Svc header value
+	// advertising it
+	http3  atomic.Pointer[HTTP3Server]
+	altSvc atomic.Value
+
+	// Plain HTTP server of StartAutoTLS answering ACME challenges
+	challenge atomic.Pointer[http.Server]
+
+	// Requests currently being served
+	inFlight atomic.Int64
+
+	// Hooks run once each response has been flushed
+	afterResponse []func(ctx context.Context, summary ResponseSummary)
+
 	ctx context.Context
 }
 
@@ -31,6 +103,14 @@ type Config struct {
 	// Address to listen on (e.g., ":8080")
 	Addr string
 
+	// Network of Addr: "tcp" (default), "tcp4", "tcp6" or "unix"
+	Network string
+
+	// Path of a Unix domain socket to listen on instead of Addr. A stale
+	// socket file, refusing connections, is removed before binding, and the
+	// file is removed on Shutdown.
+	UnixSocketPath string
+
 	// Read timeout for the server
 	ReadTimeout time.Duration
 
@@ -49,6 +129,104 @@ type Config struct {
 	// LoggerOptions for customizing logger creation (level, handler type, etc.)
 	// If provided and Logger is nil, a new logger will be created with these options
 	LoggerOptions *slogr.Options
+
+	// How long a goroutine started via Server.Go may keep running after its
+	// request finished before it is reported as leaked (default 30s)
+	GoroutineLeakThreshold time.Duration
+
+	// Timeout applied by the router to the context of every request (0
+	// disables it). Routes can override it with the Timeout and NoTimeout
+	// options. Can be changed at runtime with ApplyConfig.
+	DefaultRequestTimeout time.Duration
+
+	// When enabled every request is answered with 503 Service Unavailable.
+	// Can be changed at runtime with ApplyConfig.
+	MaintenanceMode bool
+
+	// Optional response body used while MaintenanceMode is enabled
+	MaintenanceMessage string
+
+	// When enabled requests with methods other than GET, HEAD and OPTIONS
+	// are answered with 503 and ReadOnlyMessage, except on routes registered
+	// with AllowInReadOnly. Can be changed at runtime with ApplyConfig or
+	// SetReadOnly.
+	ReadOnlyMode    bool
+	ReadOnlyMessage string
+
+	// Origins allowed by Server.CORSMiddleware.
+	// Can be changed at runtime with ApplyConfig.
+	AllowedOrigins []string
+
+	// Resolves per-request feature flags for Server.FeatureFlagMiddleware
+	FlagProvider FlagProvider
+
+	// Carries messages sent with Server.Publish to every instance (default:
+	// in-process only)
+	Broker Broker
+
+	// Answers requests matching no route (see Router.SetNotFoundHandler)
+	NotFoundHandler Handler
+
+	// Answers requests whose method matches no route of their path
+	// (see Router.SetMethodNotAllowedHandler)
+	MethodNotAllowedHandler Handler
+
+	// Let the router match methods using "METHOD /path" patterns
+	// (see Router.UseMethodPatterns)
+	MethodPatterns bool
+
+	// Debug mode: log handlers that use the request body or response writer
+	// after returning (see Router.DetectUseAfterReturn)
+	DetectUseAfterReturn bool
+
+	// Also serve HTTP/2 over cleartext connections (h2c with prior
+	// knowledge) next to HTTP/1, for load balancers and gRPC-style clients
+	// speaking HTTP/2 to a plain listener. The HTTP/1.1 "Upgrade: h2c"
+	// handshake is not supported.
+	EnableH2C bool
+
+	// HTTP/2 tuning (maximum concurrent streams, frame and buffer sizes,
+	// ping timeouts; nil for the defaults). Idle HTTP/2 connections are
+	// closed after IdleTimeout like HTTP/1 ones.
+	HTTP2 *http.HTTP2Config
+
+	// Builds the HTTP/3 server used by StartQUIC for the UDP address and
+	// handler given (see HTTP3Server)
+	HTTP3 func(addr string, handler http.Handler) HTTP3Server
+
+	// Builds the certificate manager used by StartAutoTLS for the domains
+	// given, caching certificates and keys in cacheDir (see CertManager)
+	AutoTLS func(domains []string, cacheDir string) CertManager
+
+	// Directory StartAutoTLS caches certificates in, so restarts do not
+	// request new ones (default "shttp-autotls" in the user cache directory)
+	AutoTLSCacheDir string
+
+	// Address of the plain HTTP listener StartAutoTLS answers HTTP-01
+	// challenges on (default ":80")
+	AutoTLSHTTPAddr string
+
+	// Certificate authorities client certificates are verified against
+	// when serving TLS (mutual TLS). Handlers read the verified identity
+	// with GetClientCert once ClientCertMiddleware runs.
+	ClientCAs *x509.CertPool
+
+	// Whether TLS clients must present a certificate (default
+	// tls.RequireAndVerifyClientCert when ClientCAs is set; use
+	// tls.VerifyClientCertIfGiven to also accept clients without one)
+	ClientAuth tls.ClientAuthType
+
+	// How long Shutdown keeps accepting requests after failing the
+	// readiness probes of EnableHealth, before it closes the listeners, so
+	// load balancers polling /readyz notice and stop routing to the
+	// instance first (0 for none). Set it above the probe period times the
+	// failure threshold of the load balancer.
+	ShutdownDelay time.Duration
+
+	// How long Shutdown lets in-flight requests and tracked connections
+	// drain before closing the remaining connections, when its context has
+	// no deadline (default 10s)
+	ShutdownTimeout time.Duration
 }
 
 // DefaultConfig returns a default server configuration
@@ -61,6 +239,9 @@ func DefaultConfig() *Config {
 		MaxHeaderBytes: 1 << 20, // 1MB
 		Logger:         slogr.New(os.Stdout, slogr.DefaultOptions()),
 		LoggerOptions:  nil, // Use Logger if provided
+
+		GoroutineLeakThreshold: defaultGoroutineLeakThreshold,
+		ShutdownTimeout:        defaultShutdownTimeout,
 	}
 }
 
@@ -80,89 +261,556 @@ func New(ctx context.Context, config *Config) *Server {
 		config.Logger = slogr.New(os.Stdout, slogr.DefaultOptions())
 	}
 
+	leakThreshold := config.GoroutineLeakThreshold
+	if leakThreshold <= 0 {
+		leakThreshold = defaultGoroutineLeakThreshold
+	}
+	goroutines := &goroutineTracker{threshold: leakThreshold, logger: config.Logger}
+	conns := newConnRegistry(config.Logger)
+	pubsub := newPubSub(config.Broker, config.Logger)
+
 	// Create router
 	router := NewRouter()
 
 	// Create server
 	server := &http.Server{
 		Addr:           config.Addr,
-		Handler:        router,
 		ReadTimeout:    config.ReadTimeout,
 		WriteTimeout:   config.WriteTimeout,
 		IdleTimeout:    config.IdleTimeout,
 		MaxHeaderBytes: config.MaxHeaderBytes,
+		// Make the goroutine tracker, connection registry and pub/sub hub
+		// available to handlers via shttp.Go, shttp.TrackConnection and
+		// shttp.Subscribe
+		BaseContext: func(net.Listener) context.Context {
+			ctx := context.WithValue(context.Background(), goroutineTrackerKey{}, goroutines)
+			ctx = context.WithValue(ctx, connRegistryKey{}, conns)
+			return context.WithValue(ctx, pubSubKey{}, pubsub)
+		},
+	}
+
+	server.HTTP2 = config.HTTP2
+	if config.ClientCAs != nil || config.ClientAuth != tls.NoClientCert {
+		clientAuth := config.ClientAuth
+		if clientAuth == tls.NoClientCert {
+			clientAuth = tls.RequireAndVerifyClientCert
+		}
+		server.TLSConfig = &tls.Config{ClientCAs: config.ClientCAs, ClientAuth: clientAuth}
+	}
+	if config.EnableH2C {
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		server.Protocols = protocols
+	}
+
+	s := &Server{
+		server:       server,
+		config:       config,
+		logger:       config.Logger,
+		goroutines:   goroutines,
+		conns:        conns,
+		connStates:   newConnStates(),
+		pubsub:       pubsub,
+		degradations: newDegradations(),
+		stopped:      make(chan struct{}),
+		ctx:          ctx,
+	}
+	s.router.Store(router)
+	s.live.Store(newLiveConfig(config))
+	router.SetDefaultTimeout(config.DefaultRequestTimeout)
+	router.SetReadOnly(config.ReadOnlyMode, config.ReadOnlyMessage)
+	if config.MethodPatterns {
+		router.UseMethodPatterns()
+	}
+	if config.NotFoundHandler != nil {
+		router.SetNotFoundHandler(config.NotFoundHandler)
+	}
+	if config.MethodNotAllowedHandler != nil {
+		router.SetMethodNotAllowedHandler(config.MethodNotAllowedHandler)
+	}
+	if config.DetectUseAfterReturn {
+		router.DetectUseAfterReturn(config.Logger)
+	}
+	server.Handler = s
+	return s
+}
+
+// withBaseValues returns ctx with the values http.Server's BaseContext puts
+// in the contexts of TCP requests (goroutine tracker, connection registry
+// and pub/sub hub), for requests served otherwise. Values ctx carries take
+// precedence.
+func (s *Server) withBaseValues(ctx context.Context) context.Context {
+	return valuesContext{Context: ctx, values: s.server.BaseContext(nil)}
+}
+
+// valuesContext is a context whose values fall back to those of another.
+type valuesContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c valuesContext) Value(key any) any {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.values.Value(key)
+}
+
+// ServeHTTP implements the http.Handler interface. It exposes the values
+// registered with Provide, applies maintenance mode (except to the health
+// probes) and dispatches to the router.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	n := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+
+	ctx := context.WithValue(req.Context(), providersKey{}, &s.providers)
+	req = req.WithContext(context.WithValue(ctx, concurrencyKey{}, n))
+
+	if altSvc, _ := s.altSvc.Load().(string); altSvc != "" && req.ProtoMajor < 3 {
+		w.Header().Set("Alt-Svc", altSvc)
 	}
 
-	return &Server{
-		server: server,
-		config: config,
-		router: router,
-		logger: config.Logger,
-		ctx:    ctx,
+	live := s.live.Load()
+	if live.maintenance && !slices.Contains(s.probePaths, req.URL.Path) {
+		w.Header().Set("Retry-After", "120")
+		http.Error(w, live.maintenanceMessage, http.StatusServiceUnavailable)
+		return
+	}
+	if len(s.afterResponse) == 0 {
+		s.Router().ServeHTTP(w, req)
+		return
 	}
+
+	start := time.Now()
+	rw := wrapResponseWriter(w)
+	s.Router().ServeHTTP(rw, req)
+	s.runAfterResponse(req, rw, time.Since(start))
 }
 
-// Start starts the server and begins listening for requests
+// Stats is a point-in-time snapshot of server runtime statistics
+type Stats struct {
+	// Goroutines started via Go that are still running
+	Goroutines int64
+
+	// Goroutines still running longer than Config.GoroutineLeakThreshold
+	// after their request finished
+	LeakedGoroutines int64
+
+	// Requests currently being served
+	InFlightRequests int64
+
+	// Connections registered with TrackConnection that are still open
+	TrackedConnections int64
+}
+
+// Stats returns a snapshot of the server's runtime statistics
+func (s *Server) Stats() Stats {
+	return Stats{
+		Goroutines:       s.goroutines.running.Load(),
+		LeakedGoroutines: s.goroutines.leaked.Load(),
+		InFlightRequests: s.inFlight.Load(),
+
+		TrackedConnections: int64(s.conns.len()),
+	}
+}
+
+// Start starts the server and begins listening for requests on
+// Config.Addr, or on Config.UnixSocketPath.
+// It returns nil once the server has been stopped with Shutdown.
 func (s *Server) Start() error {
-	s.logger.Infof(s.ctx, "[server.start] Starting server on %s", s.config.Addr)
-	return s.server.ListenAndServe()
+	return s.start(nil, "server", s.server.Serve)
 }
 
-// StartTLS starts the server with TLS support
+// StartTLS starts the server with TLS support.
+// It returns nil once the server has been stopped with Shutdown.
 func (s *Server) StartTLS(certFile, keyFile string) error {
-	s.logger.Infof(s.ctx, "[server.start] Starting TLS server on %s", s.config.Addr)
-	return s.server.ListenAndServeTLS(certFile, keyFile)
+	return s.start(nil, "TLS server", func(l net.Listener) error {
+		return s.server.ServeTLS(l, certFile, keyFile)
+	})
+}
+
+// Serve is Start on a listener created by the caller: one inherited from a
+// process manager (systemd socket activation), a listener with custom
+// options, or an in-memory listener in tests. The listener is closed when
+// the server stops.
+func (s *Server) Serve(l net.Listener) error {
+	return s.start(l, "server", s.server.Serve)
+}
+
+// ServeTLS is StartTLS on a listener created by the caller.
+func (s *Server) ServeTLS(l net.Listener, certFile, keyFile string) error {
+	return s.start(l, "TLS server", func(l net.Listener) error {
+		return s.server.ServeTLS(l, certFile, keyFile)
+	})
+}
+
+// start listens as configured when l is nil, runs the start hooks, then
+// serves l.
+func (s *Server) start(l net.Listener, kind string, serve func(net.Listener) error) error {
+	l, err := s.bind(l, kind)
+	if err != nil {
+		return err
+	}
+	return s.serveResult(serve(l))
 }
 
-// Shutdown gracefully shuts down the server
+// StartAsync starts the server like Start, but returns as soon as the
+// listener is bound, so Addr reports the port chosen for an Addr of ":0".
+// Start hook and listen errors are returned directly; the channel receives
+// the result Start would have returned once the server stops, then is
+// closed.
+//
+//	errc, err := server.StartAsync()
+//	if err != nil {
+//		return err
+//	}
+//	baseURL := "http://" + server.Addr().String()
+func (s *Server) StartAsync() (<-chan error, error) {
+	l, err := s.bind(nil, "server")
+	if err != nil {
+		return nil, err
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.serveResult(s.server.Serve(l))
+		close(errc)
+	}()
+	return errc, nil
+}
+
+// bind returns the listener to serve, l or a new one as configured when l
+// is nil, then runs the start hooks. Listening first keeps the hooks from
+// acquiring resources no shutdown would release when the address is taken;
+// connections wait in the backlog until the hooks are done.
+func (s *Server) bind(l net.Listener, kind string) (net.Listener, error) {
+	s.logRoutes()
+	if l == nil {
+		var err error
+		if l, err = s.listen(); err != nil {
+			return nil, s.serveResult(err)
+		}
+	}
+	if err := s.runStartHooks(); err != nil {
+		l.Close()
+		return nil, err
+	}
+	// Wrap rather than replace a ConnState set through HTTPServer, once
+	// however many times the server is started
+	s.connStateOnce.Do(func() {
+		s.server.ConnState = s.connStates.hook(s.server.ConnState)
+	})
+	addr := l.Addr()
+	s.addr.Store(&addr)
+	s.logger.Infof(s.ctx, "[server.start] Starting %s on %s", kind, addr)
+	return l, nil
+}
+
+// Addr returns the address the server listens on, with the actual port when
+// Config.Addr asked for any (":0"). It is nil until the server is listening;
+// see StartAsync.
+func (s *Server) Addr() net.Addr {
+	if addr := s.addr.Load(); addr != nil {
+		return *addr
+	}
+	return nil
+}
+
+// listen creates the listener configured by Network, Addr and
+// UnixSocketPath.
+func (s *Server) listen() (net.Listener, error) {
+	network, addr := s.config.Network, s.config.Addr
+	if s.config.UnixSocketPath != "" {
+		network, addr = "unix", s.config.UnixSocketPath
+	}
+	switch network {
+	case "":
+		network = "tcp"
+	case "unix":
+		// A socket file left by a process that did not shut down cleanly
+		// would make the bind fail. Only remove it when nothing accepts
+		// connections on it, so a running server keeps its socket.
+		if info, err := os.Stat(addr); err == nil && info.Mode()&os.ModeSocket != 0 {
+			conn, err := net.DialTimeout("unix", addr, time.Second)
+			if err == nil {
+				conn.Close()
+			} else if errors.Is(err, syscall.ECONNREFUSED) {
+				os.Remove(addr)
+			}
+		}
+	}
+	if addr == "" && network != "unix" {
+		addr = ":http"
+	}
+	return net.Listen(network, addr)
+}
+
+// logRoutes reports an empty route table, which is not an error since
+// routes may be mounted later, and lists the public routes for review.
+func (s *Server) logRoutes() {
+	if len(s.Router().routeList()) == 0 {
+		s.logger.Warn(s.ctx, "[server.start] No routes registered, every request will return 404")
+	}
+	if public := s.Router().PublicRoutes(); len(public) > 0 {
+		s.logger.Infof(s.ctx, "[server.start] Public routes (no authentication): %s", strings.Join(public, ", "))
+	}
+}
+
+// serveResult maps the error returned by the http.Server serve loop.
+// http.ErrServerClosed means Shutdown was requested and is not an error;
+// any other error means the server stopped on its own.
+func (s *Server) serveResult(err error) error {
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	s.markStopped()
+	return err
+}
+
+// OnStart registers a hook run by Start and StartTLS once the listener is
+// bound and before the server accepts connections, e.g. to open a database
+// pool or start a queue consumer. Hooks do not run when listening fails.
+// Hooks run in registration order, interleaved with the OnStart methods of
+// the modules mounted with Register; the first error aborts the start and
+// is returned by Start. Register hooks before starting the server.
+func (s *Server) OnStart(hook func(ctx context.Context) error) {
+	s.startHooks = append(s.startHooks, hook)
+}
+
+// OnShutdown registers a hook run by Shutdown once the server stopped
+// serving requests, e.g. to drain a queue consumer or close a database pool
+// the handlers were using. Hooks run in reverse registration order, so
+// resources are released in the reverse order they were acquired with
+// OnStart, and receive Shutdown's context; their errors are logged and
+// returned by Shutdown. Register hooks before starting the server.
+func (s *Server) OnShutdown(hook func(ctx context.Context) error) {
+	s.stopHooks = append(s.stopHooks, hook)
+}
+
+// runStartHooks runs the start hooks in registration order, stopping at the
+// first error. A failed start leaves the server stopped.
+func (s *Server) runStartHooks() error {
+	for _, hook := range s.startHooks {
+		if err := hook(s.ctx); err != nil {
+			s.logger.Errorf(s.ctx, "[server.start] Start hook failed: %v", err)
+			s.markStopped()
+			return err
+		}
+	}
+	return nil
+}
+
+// Run starts the server and blocks until ctx is done or the process
+// receives SIGINT or SIGTERM, then shuts it down gracefully, giving
+// in-flight requests Config.ShutdownTimeout to complete. It returns the
+// first error of the start or the shutdown; nil after a clean stop.
+//
+//	if err := server.Run(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+func (s *Server) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	started := make(chan error, 1)
+	go func() { started <- s.Start() }()
+	select {
+	case err := <-started:
+		// The start failed, or Shutdown was called directly
+		return err
+	case <-ctx.Done():
+	}
+	// A second signal kills the process as usual
+	stop()
+
+	s.logger.Infof(s.ctx, "[server.run] Stopping")
+	// Shutdown bounds the drain with Config.ShutdownTimeout
+	shutdownErr := s.Shutdown(context.WithoutCancel(ctx))
+	if err := <-started; err != nil {
+		return err
+	}
+	return shutdownErr
+}
+
+// Shutdown gracefully shuts down the server, then runs the stop hooks in
+// reverse registration order. It fails the readiness probes of
+// EnableHealth and disables keep-alives so clients stop reusing their
+// connections, keeps serving for Config.ShutdownDelay (or until ctx is
+// done) so load balancers notice, then stops accepting connections and asks
+// the connections registered with TrackConnection to close. In-flight
+// requests and tracked connections are then given until ctx is done, or
+// Config.ShutdownTimeout when ctx has no deadline; the connections still
+// open after this grace period are closed forcibly. The stop hooks run with
+// ctx. All errors are returned joined.
 func (s *Server) Shutdown(ctx context.Context) error {
-	s.logger.Infof(s.ctx, "[server.shutdown] Shutting down server")
-	return s.server.Shutdown(ctx)
+	open, active := s.connStates.counts()
+	s.logger.Info(s.ctx, "[server.shutdown] Shutting down server",
+		"open_connections", open, "active_connections", active, "tracked_connections", s.conns.len())
+	defer s.markStopped()
+
+	for _, checker := range s.healthCheckers {
+		checker.SetShuttingDown(true)
+	}
+	s.server.SetKeepAlivesEnabled(false)
+	if delay := s.config.ShutdownDelay; delay > 0 {
+		s.logger.Info(s.ctx, "[server.shutdown] Failing readiness before closing listeners", "delay", delay.String())
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+		open, _ = s.connStates.counts()
+	}
+
+	drainCtx := ctx
+	if _, ok := ctx.Deadline(); !ok {
+		timeout := s.config.ShutdownTimeout
+		if timeout <= 0 {
+			timeout = defaultShutdownTimeout
+		}
+		var cancel context.CancelFunc
+		drainCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	s.conns.goingAway(drainCtx)
+	errs := []error{s.drain(drainCtx, open), s.shutdownHTTP3(drainCtx), s.shutdownChallengeServer(drainCtx), s.conns.wait(drainCtx)}
+	for i := len(s.stopHooks) - 1; i >= 0; i-- {
+		if err := s.stopHooks[i](ctx); err != nil {
+			s.logger.Errorf(s.ctx, "[server.shutdown] Stop hook failed: %v", err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// drain waits for the in-flight requests to complete until ctx is done,
+// then closes the connections left. open is the number of connections
+// when the shutdown started.
+func (s *Server) drain(ctx context.Context, open int) error {
+	err := s.server.Shutdown(ctx)
+	if err == nil {
+		s.logger.Info(s.ctx, "[server.shutdown] Drained connections", "drained_connections", open)
+		return nil
+	}
+	remaining, active := s.connStates.counts()
+	s.logger.Warn(s.ctx, "[server.shutdown] Grace period over, closing remaining connections",
+		"drained_connections", max(open-remaining, 0), "forced_connections", remaining, "active_connections", active)
+	return errors.Join(err, s.server.Close())
+}
+
+// Wait blocks until the server has fully stopped, either because Shutdown
+// completed or because Start/StartTLS failed.
+func (s *Server) Wait() {
+	<-s.stopped
+}
+
+func (s *Server) markStopped() {
+	s.stopOnce.Do(func() { close(s.stopped) })
+}
+
+// SetErrorHandler sets the handler writing the response when a route's
+// handler returns an error (see Router.SetErrorHandler).
+func (s *Server) SetErrorHandler(h ErrorHandler) {
+	s.Router().SetErrorHandler(h)
+}
+
+// SetNotFoundHandler sets the handler answering requests that match no
+// route (see Router.SetNotFoundHandler).
+func (s *Server) SetNotFoundHandler(h Handler) {
+	s.Router().SetNotFoundHandler(h)
+}
+
+// SetMethodNotAllowedHandler sets the handler answering requests with an
+// unregistered method (see Router.SetMethodNotAllowedHandler).
+func (s *Server) SetMethodNotAllowedHandler(h Handler) {
+	s.Router().SetMethodNotAllowedHandler(h)
 }
 
 // Router returns the server's router
 func (s *Server) Router() *Router {
-	return s.router
+	return s.router.Load()
+}
+
+// SwapRouter atomically replaces the server's router with r, a complete
+// router built off-line with NewRouter and its own routes and middleware,
+// e.g. from a reloaded route configuration. Requests already dispatched
+// finish on the previous router and new ones are served by r, so no request
+// is dropped.
+//
+// The server's runtime settings carry over to r: the default request
+// timeout, read-only mode, kill switches and use-after-return detection, and
+// the error, 404 and 405 handlers unless r sets its own. Routes, middleware
+// and modules registered on the previous router do not.
+func (s *Server) SwapRouter(r *Router) error {
+	if r == nil || r.parent != nil {
+		return errors.New("shttp: SwapRouter needs a router created with NewRouter")
+	}
+	if r.methodPatterns != s.config.MethodPatterns {
+		return errors.New("shttp: SwapRouter router must match Config.MethodPatterns")
+	}
+	r.inherit(s.router.Load())
+	s.router.Store(r)
+	s.logger.Infof(s.ctx, "[server.router] Swapped in a new router with %d routes", len(r.routeList()))
+	return nil
+}
+
+// HTTPServer returns the underlying *http.Server as an escape hatch for
+// settings shttp does not model (e.g. ReadHeaderTimeout, ConnState,
+// ErrorLog, TLSConfig). Changes must be made before Start. Addr, the
+// timeouts, MaxHeaderBytes and (for mutual TLS) TLSConfig are initialized
+// from Config; Handler and BaseContext are owned by shttp and must not be
+// replaced, otherwise routing, Provide and Go stop working.
+func (s *Server) HTTPServer() *http.Server {
+	return s.server
 }
 
 // GET registers a GET route handler
-func (s *Server) GET(path string, handler Handler) {
-	s.router.GET(path, handler)
+func (s *Server) GET(path string, handler Handler, opts ...RouteOption) {
+	s.Router().GET(path, handler, opts...)
 }
 
 // POST registers a POST route handler
-func (s *Server) POST(path string, handler Handler) {
-	s.router.POST(path, handler)
+func (s *Server) POST(path string, handler Handler, opts ...RouteOption) {
+	s.Router().POST(path, handler, opts...)
 }
 
 // PUT registers a PUT route handler
-func (s *Server) PUT(path string, handler Handler) {
-	s.router.PUT(path, handler)
+func (s *Server) PUT(path string, handler Handler, opts ...RouteOption) {
+	s.Router().PUT(path, handler, opts...)
 }
 
 // DELETE registers a DELETE route handler
-func (s *Server) DELETE(path string, handler Handler) {
-	s.router.DELETE(path, handler)
+func (s *Server) DELETE(path string, handler Handler, opts ...RouteOption) {
+	s.Router().DELETE(path, handler, opts...)
 }
 
 // PATCH registers a PATCH route handler
-func (s *Server) PATCH(path string, handler Handler) {
-	s.router.PATCH(path, handler)
+func (s *Server) PATCH(path string, handler Handler, opts ...RouteOption) {
+	s.Router().PATCH(path, handler, opts...)
 }
 
 // ANY registers a method-agnostic route
-func (s *Server) ANY(path string, handler Handler) {
-	s.router.ANY(path, handler)
+func (s *Server) ANY(path string, handler Handler, opts ...RouteOption) {
+	s.Router().ANY(path, handler, opts...)
 }
 
 // Handle registers a handler for the given method and path
-func (s *Server) Handle(method, path string, handler Handler) {
-	s.router.Handle(method, path, handler)
+func (s *Server) Handle(method, path string, handler Handler, opts ...RouteOption) {
+	s.Router().Handle(method, path, handler, opts...)
+}
+
+// Group returns a router for routes sharing a path prefix, with its own
+// middleware (see Router.Group)
+func (s *Server) Group(prefix string) *Router {
+	return s.Router().Group(prefix)
 }
 
 // Use adds one or more middleware to the server (variadic approach)
 func (s *Server) Use(middleware ...Middleware) {
-	s.router.Use(middleware...)
+	s.Router().Use(middleware...)
 }
 
 // GetLogger returns the logger instance used by the server
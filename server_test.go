@@ -3,9 +3,13 @@ package shttp
 import (
 	"context"
 	"errors"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -53,8 +57,8 @@ func TestNew(t *testing.T) {
 			}
 
 			// Check that the router was created
-			if server.router == nil {
-				t.Error("New() server.router is nil")
+			if server.Router() == nil {
+				t.Error("New() server.Router() is nil")
 			}
 
 			// Check that the logger was set
@@ -191,3 +195,491 @@ func TestRouterMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestServerGoLeakDetection(t *testing.T) {
+	server := New(context.Background(), &Config{
+		Logger:                 slogr.New(io.Discard, slogr.DefaultOptions()),
+		GoroutineLeakThreshold: 10 * time.Millisecond,
+	})
+
+	reqCtx, finishRequest := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	server.Go(reqCtx, func() { <-release })
+
+	if got := server.Stats().Goroutines; got != 1 {
+		t.Fatalf("Stats().Goroutines = %d, want 1", got)
+	}
+
+	// Finishing the request starts the leak clock.
+	finishRequest()
+	waitFor(t, func() bool { return server.Stats().LeakedGoroutines == 1 })
+
+	// Once the goroutine returns it is no longer counted.
+	close(release)
+	waitFor(t, func() bool { return server.Stats() == Stats{} })
+}
+
+// waitFor polls cond until it is true or the test times out.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestServerStartReturnsNilAfterShutdown(t *testing.T) {
+	server := New(context.Background(), &Config{
+		Addr:   "127.0.0.1:0",
+		Logger: slogr.New(io.Discard, slogr.DefaultOptions()),
+	})
+
+	startErr := make(chan error, 1)
+	go func() { startErr <- server.Start() }()
+
+	// Give the listener a moment to come up before shutting down.
+	time.Sleep(20 * time.Millisecond)
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	select {
+	case err := <-startErr:
+		if err != nil {
+			t.Errorf("Start() error = %v, want nil after clean shutdown", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Start() did not return after Shutdown")
+	}
+
+	waited := make(chan struct{})
+	go func() { server.Wait(); close(waited) }()
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("Wait() did not return after Shutdown")
+	}
+}
+
+func TestServerStartFailureUnblocksWait(t *testing.T) {
+	server := New(context.Background(), &Config{
+		Addr:   "invalid-address",
+		Logger: slogr.New(io.Discard, slogr.DefaultOptions()),
+	})
+
+	if err := server.Start(); err == nil {
+		t.Fatal("Start() error = nil, want listen error")
+	}
+	server.Wait()
+}
+
+func TestNewLoggerOptions(t *testing.T) {
+	config := &Config{
+		Addr: ":0",
+		LoggerOptions: &slogr.Options{
+			HandlerType: slogr.HandlerTypeJSON,
+		},
+	}
+	server := New(context.Background(), config)
+
+	if server.GetLogger() == nil {
+		t.Fatal("GetLogger() = nil, want logger built from LoggerOptions")
+	}
+	if config.Logger != server.GetLogger() {
+		t.Error("Config.Logger was not populated with the logger built from LoggerOptions")
+	}
+
+	// An explicit Logger takes precedence over LoggerOptions.
+	explicit := slogr.New(io.Discard, slogr.DefaultOptions())
+	server = New(context.Background(), &Config{Logger: explicit, LoggerOptions: slogr.DefaultOptions()})
+	if server.GetLogger() != explicit {
+		t.Error("GetLogger() did not return the explicitly configured Logger")
+	}
+}
+
+func TestServerHTTPServer(t *testing.T) {
+	server := New(context.Background(), &Config{Addr: "127.0.0.1:0", ReadTimeout: time.Second})
+	hs := server.HTTPServer()
+	if hs.Addr != "127.0.0.1:0" || hs.ReadTimeout != time.Second {
+		t.Errorf("http.Server not initialized from Config: Addr %q, ReadTimeout %s", hs.Addr, hs.ReadTimeout)
+	}
+	if hs.Handler != server {
+		t.Error("http.Server does not dispatch to the shttp server")
+	}
+
+	hs.ReadHeaderTimeout = 2 * time.Second
+	if server.HTTPServer().ReadHeaderTimeout != 2*time.Second {
+		t.Error("changes to the returned http.Server are not kept")
+	}
+}
+
+func TestServerSwapRouter(t *testing.T) {
+	server := New(context.Background(), &Config{Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	server.GET("/v1", simpleHandler("v1"))
+	server.GET("/stable", simpleHandler("old"))
+	server.SetNotFoundHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return NewHTTPError(http.StatusNotFound, "custom not found")
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	// Keep requests flowing while the router is swapped
+	stop := make(chan struct{})
+	failures := make(chan string, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			resp, err := http.Get(ts.URL + "/stable")
+			if err != nil {
+				failures <- err.Error()
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				failures <- resp.Status
+				return
+			}
+		}
+	}()
+
+	next := NewRouter()
+	next.GET("/v2", simpleHandler("v2"))
+	next.GET("/stable", simpleHandler("new"))
+	next.POST("/v2", simpleHandler("v2"))
+	server.SetReadOnly(true, "")
+	time.Sleep(10 * time.Millisecond)
+	if err := server.SwapRouter(next); err != nil {
+		t.Fatalf("SwapRouter() error = %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(stop)
+	<-done
+	select {
+	case failure := <-failures:
+		t.Errorf("request failed during the swap: %s", failure)
+	default:
+	}
+
+	tests := []struct {
+		method, path string
+		want         int
+		wantBody     string
+	}{
+		{http.MethodGet, "/v2", http.StatusOK, "v2"},
+		{http.MethodGet, "/stable", http.StatusOK, "new"},
+		{http.MethodGet, "/v1", http.StatusNotFound, "custom not found"},
+		{http.MethodPost, "/v2", http.StatusServiceUnavailable, defaultReadOnlyMessage},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.want || !strings.Contains(w.Body.String(), tt.wantBody) {
+			t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.path, w.Code, w.Body.String(), tt.want, tt.wantBody)
+		}
+	}
+
+	if err := server.SwapRouter(nil); err == nil {
+		t.Error("SwapRouter(nil) error = nil")
+	}
+	if err := server.SwapRouter(next.Group("/api")); err == nil {
+		t.Error("SwapRouter(group) error = nil")
+	}
+}
+
+func TestServerRun(t *testing.T) {
+	t.Run("stops when the context is done", func(t *testing.T) {
+		events := &eventLog{}
+		server := New(context.Background(), &Config{
+			Addr:   "127.0.0.1:0",
+			Logger: slogr.New(io.Discard, slogr.DefaultOptions()),
+		})
+		server.OnStart(func(context.Context) error { events.add("start"); return nil })
+		server.OnShutdown(func(context.Context) error { events.add("stop"); return nil })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- server.Run(ctx) }()
+		waitFor(t, func() bool { return len(events.list()) == 1 })
+		cancel()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Run() error = %v, want nil", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Run() did not return after the context was canceled")
+		}
+		if got := events.list(); len(got) != 2 || got[1] != "stop" {
+			t.Errorf("events = %v, want the shutdown hooks to have run", got)
+		}
+	})
+
+	t.Run("returns start errors", func(t *testing.T) {
+		server := New(context.Background(), &Config{
+			Addr:   "invalid-address",
+			Logger: slogr.New(io.Discard, slogr.DefaultOptions()),
+		})
+		if err := server.Run(context.Background()); err == nil {
+			t.Error("Run() error = nil, want the listen error")
+		}
+	})
+}
+
+func TestServerListeners(t *testing.T) {
+	newServer := func(config *Config) *Server {
+		config.Logger = slogr.New(io.Discard, slogr.DefaultOptions())
+		server := New(context.Background(), config)
+		server.GET("/", simpleHandler("hello"))
+		return server
+	}
+	get := func(t *testing.T, client *http.Client, url string) {
+		t.Helper()
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("GET %s: %v", url, err)
+		}
+		defer resp.Body.Close()
+		if body, _ := io.ReadAll(resp.Body); string(body) != "hello" {
+			t.Errorf("GET %s = %q, want hello", url, body)
+		}
+	}
+
+	t.Run("Serve", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := newServer(&Config{})
+		done := make(chan error, 1)
+		go func() { done <- server.Serve(l) }()
+
+		get(t, http.DefaultClient, "http://"+l.Addr().String()+"/")
+		if err := server.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown() error = %v", err)
+		}
+		if err := <-done; err != nil {
+			t.Errorf("Serve() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("Unix socket", func(t *testing.T) {
+		socket := filepath.Join(t.TempDir(), "shttp.sock")
+		// A socket file left behind by a previous process
+		stale, err := net.Listen("unix", socket)
+		if err != nil {
+			t.Skipf("unix sockets unavailable: %v", err)
+		}
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		stale.Close()
+
+		server := newServer(&Config{UnixSocketPath: socket})
+		done := make(chan error, 1)
+		go func() { done <- server.Start() }()
+		waitFor(t, func() bool {
+			conn, err := net.Dial("unix", socket)
+			if err == nil {
+				conn.Close()
+			}
+			return err == nil
+		})
+
+		client := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		}}
+		get(t, client, "http://unix/")
+		if err := server.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown() error = %v", err)
+		}
+		if err := <-done; err != nil {
+			t.Errorf("Start() error = %v, want nil", err)
+		}
+		if _, err := os.Stat(socket); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("socket file left after Shutdown: %v", err)
+		}
+	})
+
+	t.Run("Unix socket in use", func(t *testing.T) {
+		socket := filepath.Join(t.TempDir(), "shttp.sock")
+		running, err := net.Listen("unix", socket)
+		if err != nil {
+			t.Skipf("unix sockets unavailable: %v", err)
+		}
+		defer running.Close()
+
+		if err := newServer(&Config{UnixSocketPath: socket}).Start(); err == nil {
+			t.Fatal("Start() on a socket in use succeeded")
+		}
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			t.Fatalf("socket of the running server removed: %v", err)
+		}
+		conn.Close()
+	})
+}
+
+func TestServerStartAsync(t *testing.T) {
+	server := New(context.Background(), &Config{
+		Addr:   "127.0.0.1:0",
+		Logger: slogr.New(io.Discard, slogr.DefaultOptions()),
+	})
+	server.GET("/", simpleHandler("hello"))
+	if addr := server.Addr(); addr != nil {
+		t.Errorf("Addr() before start = %v, want nil", addr)
+	}
+
+	errc, err := server.StartAsync()
+	if err != nil {
+		t.Fatalf("StartAsync() error = %v", err)
+	}
+	addr := server.Addr()
+	if addr == nil || strings.HasSuffix(addr.String(), ":0") {
+		t.Fatalf("Addr() = %v, want the bound port", addr)
+	}
+	resp, err := http.Get("http://" + addr.String() + "/")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("serve error = %v, want nil", err)
+	}
+	if _, open := <-errc; open {
+		t.Error("channel not closed after the server stopped")
+	}
+
+	failing := New(context.Background(), &Config{
+		Addr:   "invalid-address",
+		Logger: slogr.New(io.Discard, slogr.DefaultOptions()),
+	})
+	if _, err := failing.StartAsync(); err == nil {
+		t.Error("StartAsync() on an invalid address error = nil")
+	}
+	failing.Wait()
+}
+
+func TestServerShutdownGracePeriod(t *testing.T) {
+	logs := &syncBuffer{}
+	server := New(context.Background(), &Config{
+		Addr:            "127.0.0.1:0",
+		Logger:          slogr.New(logs, slogr.DefaultOptions()),
+		ShutdownTimeout: 50 * time.Millisecond,
+	})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	server.GET("/slow", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		close(started)
+		<-release
+		return nil
+	})
+	server.GET("/fast", simpleHandler("fast"))
+
+	if _, err := server.StartAsync(); err != nil {
+		t.Fatalf("StartAsync() error = %v", err)
+	}
+	base := "http://" + server.Addr().String()
+
+	// An idle keep-alive connection drains right away
+	resp, err := http.Get(base + "/fast")
+	if err != nil {
+		t.Fatalf("GET /fast: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	slowErr := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(base + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+		slowErr <- err
+	}()
+	<-started
+
+	start := time.Now()
+	if err := server.Shutdown(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Shutdown() took %s, want about the 50ms grace period", elapsed)
+	}
+	if err := <-slowErr; err == nil {
+		t.Error("request in flight past the grace period was not cut off")
+	}
+	if got := logs.String(); !strings.Contains(got, "forced_connections=1") {
+		t.Errorf("logs do not report the forced connection:\n%s", got)
+	}
+}
+
+func TestServerH2C(t *testing.T) {
+	server := New(context.Background(), &Config{
+		Addr:      "127.0.0.1:0",
+		Logger:    slogr.New(io.Discard, slogr.DefaultOptions()),
+		EnableH2C: true,
+		HTTP2:     &http.HTTP2Config{MaxConcurrentStreams: 50},
+	})
+	server.GET("/proto", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		_, err := io.WriteString(w, r.Proto)
+		return err
+	})
+	if _, err := server.StartAsync(); err != nil {
+		t.Fatalf("StartAsync() error = %v", err)
+	}
+	defer server.Shutdown(context.Background())
+	url := "http://" + server.Addr().String() + "/proto"
+
+	h2c := new(http.Protocols)
+	h2c.SetUnencryptedHTTP2(true)
+	http1 := new(http.Protocols)
+	http1.SetHTTP1(true)
+	for _, tt := range []struct {
+		protocols *http.Protocols
+		want      string
+	}{
+		{h2c, "HTTP/2.0"},
+		{http1, "HTTP/1.1"},
+	} {
+		client := &http.Client{Transport: &http.Transport{Protocols: tt.protocols}}
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("GET over %s: %v", tt.want, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != tt.want {
+			t.Errorf("request served over %s, want %s", body, tt.want)
+		}
+	}
+}
+
+// TestDefaultServeMuxUntouched guards against the root package importing
+// packages that register handlers on http.DefaultServeMux, such as
+// net/http/pprof and expvar (see shttpdebug).
+func TestDefaultServeMuxUntouched(t *testing.T) {
+	for _, path := range []string{"/debug/pprof/", "/debug/vars"} {
+		if _, pattern := http.DefaultServeMux.Handler(httptest.NewRequest(http.MethodGet, path, nil)); pattern != "" {
+			t.Errorf("http.DefaultServeMux serves %s with pattern %q", path, pattern)
+		}
+	}
+}
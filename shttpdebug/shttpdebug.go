@@ -0,0 +1,57 @@
+// Package shttpdebug mounts the runtime profiling handlers of net/http/pprof
+// and the expvar handler on a shttp server. It lives apart from shttp
+// because importing those packages registers their handlers on
+// http.DefaultServeMux, a side effect only the programs using it should
+// have.
+//
+//	shttpdebug.Enable(server, "/debug", shttp.AuthMiddleware(verify))
+package shttpdebug
+
+import (
+	"context"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/andres-vara/shttp"
+)
+
+// Enable mounts the pprof and expvar handlers under prefix on server:
+// Enable(server, "/debug") serves the profile index at /debug/pprof/,
+// profiles such as /debug/pprof/heap, and the published variables at
+// /debug/vars, so `go tool pprof` works against the server's own listener.
+// They expose the internals of the process: on a server reachable from
+// outside, pass middleware restricting access (e.g. AuthMiddleware or an IP
+// allowlist); it applies to these routes only.
+//
+// Like any program importing net/http/pprof and expvar, the process also
+// has these handlers registered on http.DefaultServeMux; never serve
+// DefaultServeMux on a public listener.
+func Enable(server *shttp.Server, prefix string, middleware ...shttp.Middleware) {
+	g := server.Router().Group(prefix)
+	g.Use(middleware...)
+	// CPU profiles and traces run for ?seconds= (30s by default)
+	g.GET("/pprof/{name...}", pprofHandler, shttp.NoTimeout())
+	g.POST("/pprof/symbol", shttp.WrapHandlerFunc(pprof.Symbol))
+	g.GET("/vars", shttp.WrapHTTPHandler(expvar.Handler()))
+}
+
+// pprofHandler serves the pprof index and the profile named by the
+// {name...} wildcard.
+func pprofHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	switch name := shttp.PathValue(r, "name"); name {
+	case "":
+		pprof.Index(w, r)
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "profile":
+		pprof.Profile(w, r)
+	case "symbol":
+		pprof.Symbol(w, r)
+	case "trace":
+		pprof.Trace(w, r)
+	default:
+		pprof.Handler(name).ServeHTTP(w, r)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package shttpdebug
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andres-vara/shttp"
+	"github.com/andres-vara/slogr"
+)
+
+func TestEnable(t *testing.T) {
+	server := shttp.New(context.Background(), &shttp.Config{Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	server.GET("/", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		_, err := io.WriteString(w, "home")
+		return err
+	})
+	Enable(server, "/admin/debug", func(next shttp.Handler) shttp.Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if r.Header.Get("X-Debug-Token") != "secret" {
+				return shttp.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+			}
+			return next(ctx, w, r)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		token    string
+		want     int
+		wantBody string
+	}{
+		{name: "Index", path: "/admin/debug/pprof/", token: "secret", want: http.StatusOK, wantBody: "goroutine"},
+		{name: "Named profile", path: "/admin/debug/pprof/goroutine?debug=1", token: "secret", want: http.StatusOK, wantBody: "goroutine profile"},
+		{name: "Unknown profile", path: "/admin/debug/pprof/nope", token: "secret", want: http.StatusNotFound},
+		{name: "Vars", path: "/admin/debug/vars", token: "secret", want: http.StatusOK, wantBody: `"memstats"`},
+		{name: "Without credentials", path: "/admin/debug/vars", want: http.StatusUnauthorized},
+		{name: "Other routes unaffected", path: "/", want: http.StatusOK, wantBody: "home"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("X-Debug-Token", tt.token)
+			}
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, req)
+			if w.Code != tt.want || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("GET %s = %d %.80q, want %d containing %q", tt.path, w.Code, w.Body.String(), tt.want, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// Package shttpfuzz provides native Go fuzz targets for shttp routers,
+// request binders and route patterns. Call them from a Fuzz function in a
+// _test.go file, with seeds describing your own routes and payloads:
+//
+//	func FuzzRoutes(f *testing.F) {
+//		shttpfuzz.FuzzRouteMatch(f, newServer(), "/users/42", "/orders/7/items")
+//	}
+//
+// and run them with go test -fuzz=FuzzRoutes.
+package shttpfuzz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/andres-vara/shttp"
+)
+
+// NewRequest builds a request for fuzzed input, returning nil instead of
+// panicking like httptest.NewRequest when the method or target is invalid.
+func NewRequest(method, target string, body []byte) *http.Request {
+	if method == "" || strings.ContainsAny(method, " \t\r\n") {
+		return nil
+	}
+	u, err := url.ParseRequestURI(target)
+	if err != nil || u.Path == "" {
+		return nil
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", bytes.NewReader(body))
+	req.Method = method
+	req.URL = u
+	req.RequestURI = target
+	return req
+}
+
+// FuzzRouteMatch fuzzes the routing of h, usually a shttp.Router or Server,
+// with arbitrary methods and request targets. The handler must not panic and
+// must answer with a valid status code. seeds are request targets, tried
+// with GET and POST.
+func FuzzRouteMatch(f *testing.F, h http.Handler, seeds ...string) {
+	f.Helper()
+	for _, seed := range append(seeds, "/", "/a/b/../c", "/%2e%2e/x", "//double") {
+		f.Add(http.MethodGet, seed)
+		f.Add(http.MethodPost, seed)
+	}
+	f.Fuzz(func(t *testing.T, method, target string) {
+		req := NewRequest(method, target, nil)
+		if req == nil {
+			t.Skip()
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code < 100 || w.Code > 599 {
+			t.Errorf("%s %s: invalid status %d", method, target, w.Code)
+		}
+	})
+}
+
+// FuzzBindJSON fuzzes bind, e.g. shttp.Bind or a custom binder, with
+// arbitrary JSON bodies decoded into a new T. The binder must not panic and
+// must reject malformed input with a 4xx shttp.HTTPError, never an error the
+// router would answer with 500. A nil bind uses shttp.Bind.
+func FuzzBindJSON[T any](f *testing.F, bind func(r *http.Request, v any) error, seeds ...[]byte) {
+	f.Helper()
+	if bind == nil {
+		bind = shttp.Bind
+	}
+	for _, seed := range append(seeds, []byte(`{}`), []byte(`[]`), []byte(`{"a":`), []byte(`null`)) {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, body []byte) {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		var v T
+		err := bind(req, &v)
+		if err == nil {
+			return
+		}
+		var httpErr shttp.HTTPError
+		if !errors.As(err, &httpErr) || httpErr.StatusCode < 400 || httpErr.StatusCode > 499 {
+			t.Errorf("body %q: error %v is not a 4xx HTTPError", body, err)
+		}
+	})
+}
+
+// FuzzPathParams fuzzes route patterns and request paths. Every pattern
+// accepted by shttp.ValidatePattern must register without panicking, and
+// when a request matches it, shttp.PathValue must return the values the
+// ServeMux matched. seeds are patterns.
+func FuzzPathParams(f *testing.F, seeds ...string) {
+	f.Helper()
+	for _, seed := range append(seeds, "/users/{id}", "/files/{path...}", "/{a}/{b}/{$}") {
+		f.Add(seed, "/users/42")
+	}
+	f.Fuzz(func(t *testing.T, pattern, target string) {
+		normalized, err := shttp.ValidatePattern(pattern)
+		if err != nil {
+			return
+		}
+		params := patternParams(normalized)
+
+		var mismatch error
+		router := shttp.NewRouter()
+		handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			for _, name := range params {
+				if got, want := shttp.PathValue(r, name), r.PathValue(name); got != want {
+					mismatch = fmt.Errorf("PathValue(%q) = %q, ServeMux matched %q", name, got, want)
+				}
+			}
+			return nil
+		}
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					t.Fatalf("ValidatePattern accepted %q but registering it panicked: %v", pattern, p)
+				}
+			}()
+			router.GET(pattern, handler)
+		}()
+
+		req := NewRequest(http.MethodGet, target, nil)
+		if req == nil {
+			return
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		if mismatch != nil {
+			t.Errorf("pattern %q, target %q: %v", pattern, target, mismatch)
+		}
+	})
+}
+
+// patternParams returns the parameter names of a valid normalized pattern.
+func patternParams(pattern string) []string {
+	var params []string
+	for _, seg := range strings.Split(pattern, "/") {
+		name, ok := strings.CutPrefix(seg, "{")
+		if !ok || name == "$}" {
+			continue
+		}
+		name = strings.TrimSuffix(strings.TrimSuffix(name, "}"), "...")
+		params = append(params, name)
+	}
+	return params
+}
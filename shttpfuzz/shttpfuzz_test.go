@@ -0,0 +1,36 @@
+package shttpfuzz
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/andres-vara/shttp"
+)
+
+func FuzzRouter(f *testing.F) {
+	router := shttp.NewRouter()
+	ok := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Write([]byte(shttp.PathValue(r, "id")))
+		return nil
+	}
+	router.GET("/users/{id}", ok)
+	router.POST("/users", ok, shttp.MaxBodySize(1<<10))
+	router.ANY("/files/{path...}", ok)
+	FuzzRouteMatch(f, router, "/users/42", "/files/a/b.txt")
+}
+
+func FuzzBind(f *testing.F) {
+	type payload struct {
+		Name  string   `json:"name"`
+		Tags  []string `json:"tags"`
+		Count int      `json:"count"`
+	}
+	FuzzBindJSON[payload](f, func(r *http.Request, v any) error {
+		return shttp.BindWithOptions(r, v, shttp.StrictBindOptions())
+	}, []byte(`{"name":"a","tags":["x"],"count":1}`))
+}
+
+func FuzzPatterns(f *testing.F) {
+	FuzzPathParams(f, "/orders/{order}/items/{item}")
+}
@@ -0,0 +1,140 @@
+// Package shttpredis provides Redis-backed implementations of shttp's
+// extension points, for servers running as several replicas. It speaks the
+// Redis protocol directly and has no dependencies beyond shttp.
+//
+//	broker := shttpredis.NewBroker("redis:6379", shttpredis.Options{})
+//	server := shttp.New(ctx, &shttp.Config{Addr: ":8080", Broker: broker})
+//	server.Use(shttp.RateLimitMiddleware(shttp.RateLimit{Rate: 10, Burst: 20}, shttp.RateLimitOptions{
+//		Store: shttpredis.NewRateLimitStore("redis:6379", shttpredis.Options{}),
+//	}))
+package shttpredis
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"github.com/andres-vara/shttp"
+)
+
+// Options configures the connections to Redis.
+type Options struct {
+	// Credentials sent with AUTH; Username is only needed with Redis 6 ACLs
+	Username string
+	Password string
+
+	// Prefix of the Redis keys and channels used (default "shttp:")
+	Prefix string
+
+	// Timeout for establishing connections (default 5s)
+	DialTimeout time.Duration
+
+	// Timeout for a command, including waiting for a free connection and
+	// dialing (default 1s). A request's own deadline applies when earlier.
+	CommandTimeout time.Duration
+
+	// Connections running commands at once (default 4); subscriptions use
+	// their own
+	PoolSize int
+
+	// Enables TLS when set
+	TLSConfig *tls.Config
+}
+
+func (o Options) withDefaults() Options {
+	if o.Prefix == "" {
+		o.Prefix = "shttp:"
+	}
+	if o.DialTimeout <= 0 {
+		o.DialTimeout = 5 * time.Second
+	}
+	if o.CommandTimeout <= 0 {
+		o.CommandTimeout = time.Second
+	}
+	if o.PoolSize <= 0 {
+		o.PoolSize = 4
+	}
+	return o
+}
+
+// messageBuffer is the number of received messages buffered per
+// subscription.
+const messageBuffer = 64
+
+// Broker is an shttp.Broker on Redis pub/sub: topics are published on the
+// Redis channel Prefix+topic, so every replica subscribed to a topic
+// receives its messages. Each subscribed topic uses its own connection;
+// publishing shares a pool.
+type Broker struct {
+	addr string
+	opts Options
+
+	// Publishing connections
+	client *client
+}
+
+var _ shttp.Broker = (*Broker)(nil)
+
+// NewBroker creates a broker for the Redis server at addr ("host:port").
+// Connections are established on first use.
+func NewBroker(addr string, opts Options) *Broker {
+	opts = opts.withDefaults()
+	return &Broker{addr: addr, opts: opts, client: newClient(addr, opts)}
+}
+
+// Publish implements shttp.Broker.
+func (b *Broker) Publish(ctx context.Context, msg shttp.Message) error {
+	_, err := b.client.do(ctx, "PUBLISH", b.opts.Prefix+msg.Topic, string(msg.Data))
+	return err
+}
+
+// Subscribe implements shttp.Broker. The channel is closed when ctx is done
+// or the connection is lost.
+func (b *Broker) Subscribe(ctx context.Context, topic string) (<-chan shttp.Message, error) {
+	setupCtx, cancel := context.WithTimeout(ctx, b.opts.CommandTimeout)
+	defer cancel()
+	c, err := dial(setupCtx, b.addr, b.opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.do(setupCtx, "SUBSCRIBE", b.opts.Prefix+topic); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	msgs := make(chan shttp.Message, messageBuffer)
+	go func() {
+		defer close(msgs)
+		// Closing the connection unblocks the read below.
+		stop := context.AfterFunc(ctx, func() { c.Close() })
+		defer stop()
+		defer c.Close()
+		for {
+			reply, err := c.read()
+			if err != nil {
+				return
+			}
+			// Pushed messages are ["message", channel, payload]
+			push, ok := reply.([]any)
+			if !ok || len(push) != 3 {
+				continue
+			}
+			if kind, _ := push[0].([]byte); string(kind) != "message" {
+				continue
+			}
+			data, _ := push[2].([]byte)
+			select {
+			case msgs <- shttp.Message{Topic: topic, Data: data}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return msgs, nil
+}
+
+// Close closes the publishing connections. Subscriptions end with their
+// context.
+func (b *Broker) Close() error {
+	return b.client.close()
+}
@@ -0,0 +1,87 @@
+package shttpredis
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andres-vara/shttp"
+	"github.com/andres-vara/slogr"
+)
+
+func TestBrokerAcrossServers(t *testing.T) {
+	redis := newFakeRedis(t, "secret")
+	newServer := func() *shttp.Server {
+		return shttp.New(context.Background(), &shttp.Config{
+			Logger: slogr.New(io.Discard, slogr.DefaultOptions()),
+			Broker: NewBroker(redis.ln.Addr().String(), Options{Password: "secret"}),
+		})
+	}
+	a, b := newServer(), newServer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := b.Subscribe(ctx, "orders")
+	deadline := time.Now().Add(time.Second)
+	for redis.subscribers("shttp:orders") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("server did not subscribe to the broker")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := a.Publish("orders", map[string]int{"id": 7}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	select {
+	case msg := <-received:
+		if msg.Topic != "orders" || string(msg.Data) != `{"id":7}` {
+			t.Errorf("received %s %s", msg.Topic, msg.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message published on another server not received")
+	}
+}
+
+func TestBrokerAuthError(t *testing.T) {
+	redis := newFakeRedis(t, "secret")
+	broker := NewBroker(redis.ln.Addr().String(), Options{Password: "wrong"})
+	defer broker.Close()
+
+	err := broker.Publish(context.Background(), shttp.Message{Topic: "orders", Data: []byte("x")})
+	if err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("Publish() error = %v, want WRONGPASS", err)
+	}
+	if _, err := broker.Subscribe(context.Background(), "orders"); err == nil {
+		t.Error("Subscribe() with a wrong password succeeded")
+	}
+}
+
+func TestBrokerSubscriptionEndsWithContext(t *testing.T) {
+	redis := newFakeRedis(t, "")
+	broker := NewBroker(redis.ln.Addr().String(), Options{Prefix: "app:"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	msgs, err := broker.Subscribe(ctx, "news")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Publish(context.Background(), shttp.Message{Topic: "news", Data: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := <-msgs; string(msg.Data) != "hello" {
+		t.Errorf("received %q, want hello", msg.Data)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-msgs:
+		if ok {
+			t.Error("received a message after the context ended")
+		}
+	case <-time.After(time.Second):
+		t.Error("subscription channel not closed after the context ended")
+	}
+}
@@ -0,0 +1,103 @@
+package shttpredis
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andres-vara/shttp"
+)
+
+// tokenBucketScript takes a token from the bucket stored in the hash
+// KEYS[1], refilled at ARGV[1] tokens per second up to ARGV[2]. It runs
+// atomically on the Redis server and uses the server clock, so replicas
+// with skewed clocks share consistent buckets. It returns {allowed,
+// remaining tokens, milliseconds until the next token}.
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {allowed, math.floor(tokens), wait}
+`
+
+// tokenBucketSHA is the SHA1 digest EVALSHA refers to the script by.
+var tokenBucketSHA = func() string {
+	sum := sha1.Sum([]byte(tokenBucketScript))
+	return hex.EncodeToString(sum[:])
+}()
+
+// RateLimitStore is an shttp.RateLimitStore keeping token buckets in Redis,
+// so that a limit applies across all replicas. Buckets are stored under
+// Prefix+"ratelimit:"+key and expire once they would be full again.
+type RateLimitStore struct {
+	opts   Options
+	client *client
+}
+
+var _ shttp.RateLimitStore = (*RateLimitStore)(nil)
+
+// NewRateLimitStore creates a store for the Redis server at addr
+// ("host:port"). Connections are established on first use.
+func NewRateLimitStore(addr string, opts Options) *RateLimitStore {
+	opts = opts.withDefaults()
+	return &RateLimitStore{opts: opts, client: newClient(addr, opts)}
+}
+
+// Take implements shttp.RateLimitStore.
+func (s *RateLimitStore) Take(ctx context.Context, key string, limit shttp.RateLimit) (shttp.RateLimitResult, error) {
+	args := []string{
+		tokenBucketSHA, "1", s.opts.Prefix + "ratelimit:" + key,
+		strconv.FormatFloat(limit.Rate, 'f', -1, 64), strconv.Itoa(limit.Burst),
+	}
+	reply, err := s.client.do(ctx, append([]string{"EVALSHA"}, args...)...)
+	var replyErr redisError
+	if errors.As(err, &replyErr) && strings.HasPrefix(string(replyErr), "NOSCRIPT") {
+		// First use on this server: EVAL loads the script into its cache
+		args[0] = tokenBucketScript
+		reply, err = s.client.do(ctx, append([]string{"EVAL"}, args...)...)
+	}
+	if err != nil {
+		return shttp.RateLimitResult{}, err
+	}
+
+	values, ok := reply.([]any)
+	if !ok || len(values) != 3 {
+		return shttp.RateLimitResult{}, fmt.Errorf("redis: unexpected rate limit reply %v", reply)
+	}
+	allowed, _ := values[0].(int64)
+	remaining, _ := values[1].(int64)
+	wait, _ := values[2].(int64)
+	return shttp.RateLimitResult{
+		Allowed:    allowed == 1,
+		Remaining:  int(remaining),
+		RetryAfter: time.Duration(wait) * time.Millisecond,
+	}, nil
+}
+
+// Close closes the connections.
+func (s *RateLimitStore) Close() error {
+	return s.client.close()
+}
@@ -0,0 +1,42 @@
+package shttpredis
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/andres-vara/shttp"
+)
+
+func TestRateLimitStore(t *testing.T) {
+	redis := newFakeRedis(t, "")
+	store := NewRateLimitStore(redis.ln.Addr().String(), Options{})
+	defer store.Close()
+
+	limit := shttp.RateLimit{Rate: 1, Burst: 2}
+	want := []shttp.RateLimitResult{
+		{Allowed: true, Remaining: 1},
+		{Allowed: true, Remaining: 0},
+		{Allowed: false, RetryAfter: time.Second},
+	}
+	for i, w := range want {
+		res, err := store.Take(context.Background(), "10.0.0.1", limit)
+		if err != nil {
+			t.Fatalf("take %d: %v", i, err)
+		}
+		if res != w {
+			t.Errorf("take %d = %+v, want %+v", i, res, w)
+		}
+	}
+
+	// The script is loaded with EVAL once, then run by digest
+	redis.mu.Lock()
+	defer redis.mu.Unlock()
+	if want := []string{"EVALSHA", "EVAL", "EVALSHA", "EVALSHA"}; !slices.Equal(redis.commands, want) {
+		t.Errorf("commands = %v, want %v", redis.commands, want)
+	}
+	if _, ok := redis.tokens["shttp:ratelimit:10.0.0.1"]; !ok {
+		t.Errorf("bucket keys = %v, want shttp:ratelimit:10.0.0.1", redis.tokens)
+	}
+}
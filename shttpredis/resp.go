@@ -0,0 +1,209 @@
+package shttpredis
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisError is an error reply from the Redis server.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// client runs commands on a small pool of connections, dialed on demand.
+// Commands wait for a free connection no longer than their context allows,
+// and each runs within Options.CommandTimeout, so a stalled server cannot
+// block callers past their deadline.
+type client struct {
+	addr string
+	opts Options
+
+	// Holds a token per connection in use, up to Options.PoolSize
+	sem chan struct{}
+
+	mu     sync.Mutex
+	idle   []*conn
+	closed bool
+}
+
+func newClient(addr string, opts Options) *client {
+	return &client{addr: addr, opts: opts, sem: make(chan struct{}, opts.PoolSize)}
+}
+
+// do runs a command. Failed commands are not retried: a network error
+// leaves it unknown whether the server ran the command, and running a
+// token-taking script or PUBLISH twice is worse than one failure. The
+// broken connection is discarded, so the next command dials a new one.
+func (c *client) do(ctx context.Context, args ...string) (any, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.CommandTimeout)
+	defer cancel()
+	select {
+	case c.sem <- struct{}{}:
+		defer func() { <-c.sem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	conn := c.get()
+	if conn == nil {
+		var err error
+		if conn, err = dial(ctx, c.addr, c.opts); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := conn.do(ctx, args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		conn.Close()
+		return nil, err
+	}
+	c.put(conn)
+	return reply, err
+}
+
+// get returns an idle connection, or nil when there is none.
+func (c *client) get() *conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.idle) == 0 {
+		return nil
+	}
+	conn := c.idle[len(c.idle)-1]
+	c.idle = c.idle[:len(c.idle)-1]
+	return conn
+}
+
+// put makes conn available to the next command.
+func (c *client) put(conn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		conn.Close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
+
+// close closes the idle connections, and those in use once their command
+// completes.
+func (c *client) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	var errs []error
+	for _, conn := range c.idle {
+		errs = append(errs, conn.Close())
+	}
+	c.idle = nil
+	return errors.Join(errs...)
+}
+
+// conn is a connection speaking RESP, the Redis protocol.
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// dial connects to addr and authenticates when opts has credentials.
+func dial(ctx context.Context, addr string, opts Options) (*conn, error) {
+	dialer := &net.Dialer{Timeout: opts.DialTimeout}
+	var nc net.Conn
+	var err error
+	if opts.TLSConfig != nil {
+		nc, err = (&tls.Dialer{NetDialer: dialer, Config: opts.TLSConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		nc, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &conn{Conn: nc, r: bufio.NewReader(nc)}
+	if opts.Password != "" {
+		args := []string{"AUTH", opts.Password}
+		if opts.Username != "" {
+			args = []string{"AUTH", opts.Username, opts.Password}
+		}
+		if _, err := c.do(ctx, args...); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// do sends a command and reads its reply, within the deadline of ctx.
+func (c *conn) do(ctx context.Context, args ...string) (any, error) {
+	deadline, _ := ctx.Deadline()
+	c.SetDeadline(deadline)
+	defer c.SetDeadline(time.Time{})
+	if err := c.writeCommand(args...); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// writeCommand sends a command as an array of bulk strings.
+func (c *conn) writeCommand(args ...string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	_, err := io.WriteString(c.Conn, b.String())
+	return err
+}
+
+// read reads one reply: a string, an int64, a []byte, a []any, nil or a
+// redisError.
+func (c *conn) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		values := make([]any, n)
+		for i := range values {
+			if values[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
@@ -0,0 +1,209 @@
+package shttpredis
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is a minimal Redis server supporting AUTH, PUBLISH, SUBSCRIBE
+// and the token bucket script, enough to exercise the protocol code without
+// a real server.
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+
+	mu       sync.Mutex
+	subs     map[string][]*conn
+	commands []string
+
+	// Script cache and buckets of the emulated token bucket script
+	scripts map[string]bool
+	tokens  map[string]int
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{
+		ln:       ln,
+		password: password,
+		subs:     make(map[string][]*conn),
+		scripts:  make(map[string]bool),
+		tokens:   make(map[string]int),
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(&conn{Conn: nc, r: bufio.NewReader(nc)})
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) serve(c *conn) {
+	defer c.Close()
+	authed := f.password == ""
+	for {
+		reply, err := c.read()
+		if err != nil {
+			return
+		}
+		args, _ := reply.([]any)
+		if len(args) == 0 {
+			return
+		}
+		arg := func(i int) string {
+			b, _ := args[i].([]byte)
+			return string(b)
+		}
+		cmd := strings.ToUpper(arg(0))
+		f.mu.Lock()
+		f.commands = append(f.commands, cmd)
+		f.mu.Unlock()
+		switch {
+		case cmd == "AUTH":
+			if arg(len(args)-1) != f.password {
+				io.WriteString(c, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			authed = true
+			io.WriteString(c, "+OK\r\n")
+		case !authed:
+			io.WriteString(c, "-NOAUTH Authentication required.\r\n")
+		case cmd == "SUBSCRIBE":
+			f.mu.Lock()
+			f.subs[arg(1)] = append(f.subs[arg(1)], c)
+			f.mu.Unlock()
+			c.writeCommand("subscribe", arg(1))
+		case cmd == "PUBLISH":
+			f.mu.Lock()
+			subs := f.subs[arg(1)]
+			for _, sub := range subs {
+				sub.writeCommand("message", arg(1), arg(2))
+			}
+			f.mu.Unlock()
+			fmt.Fprintf(c, ":%d\r\n", len(subs))
+		case cmd == "EVALSHA" || cmd == "EVAL":
+			f.mu.Lock()
+			if cmd == "EVAL" && arg(1) == tokenBucketScript {
+				f.scripts[tokenBucketSHA] = true
+			} else if !f.scripts[arg(1)] {
+				f.mu.Unlock()
+				io.WriteString(c, "-NOSCRIPT No matching script.\r\n")
+				continue
+			}
+			// Emulate the script without refill: the bucket starts at burst
+			key := arg(3)
+			if _, ok := f.tokens[key]; !ok {
+				f.tokens[key], _ = strconv.Atoi(arg(5))
+			}
+			allowed, wait := 0, 1000
+			if f.tokens[key] > 0 {
+				f.tokens[key]--
+				allowed, wait = 1, 0
+			}
+			fmt.Fprintf(c, "*3\r\n:%d\r\n:%d\r\n:%d\r\n", allowed, f.tokens[key], wait)
+			f.mu.Unlock()
+		}
+	}
+}
+
+func (f *fakeRedis) subscribers(channel string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs[channel])
+}
+
+// rawServer accepts connections and passes each received command to
+// handle, which answers on the connection or not at all.
+func rawServer(t *testing.T, handle func(c *conn, cmd []any)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				c := &conn{Conn: nc, r: bufio.NewReader(nc)}
+				for {
+					reply, err := c.read()
+					if err != nil {
+						return
+					}
+					cmd, _ := reply.([]any)
+					handle(c, cmd)
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestClientStalledServer(t *testing.T) {
+	addr := rawServer(t, func(*conn, []any) {})
+	c := newClient(addr, Options{CommandTimeout: 100 * time.Millisecond, PoolSize: 1}.withDefaults())
+	defer c.close()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = c.do(context.Background(), "PING")
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err == nil {
+			t.Errorf("command %d succeeded against a stalled server", i)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("commands took %v, want them bounded by the command timeout", elapsed)
+	}
+}
+
+func TestClientDoesNotRetry(t *testing.T) {
+	var mu sync.Mutex
+	received := 0
+	addr := rawServer(t, func(c *conn, cmd []any) {
+		mu.Lock()
+		received++
+		mu.Unlock()
+		c.Close()
+	})
+	c := newClient(addr, Options{}.withDefaults())
+	defer c.close()
+
+	if _, err := c.do(context.Background(), "EVALSHA", "digest", "0"); err == nil {
+		t.Fatal("do() succeeded on a dropped connection")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if received != 1 {
+		t.Errorf("server received the command %d times, want 1", received)
+	}
+}
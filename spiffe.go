@@ -0,0 +1,73 @@
+package shttp
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+)
+
+// SPIFFEAuthorizer decides whether the workload identified by the SPIFFE
+// ID id may make the request. It returns an error to refuse it.
+type SPIFFEAuthorizer func(ctx context.Context, id string, r *http.Request) error
+
+// AllowSPIFFEIDs authorizes the workloads with the given SPIFFE IDs only.
+func AllowSPIFFEIDs(ids ...string) SPIFFEAuthorizer {
+	return func(ctx context.Context, id string, r *http.Request) error {
+		if slices.Contains(ids, id) {
+			return nil
+		}
+		return errors.New("workload not allowed")
+	}
+}
+
+// SPIFFEMiddleware authenticates workloads by the X.509 SVID they present
+// as client certificate (see Config.ClientCAs, here the trust bundle of the
+// SPIFFE trust domain) and authorizes them with authorize, keyed by their
+// SPIFFE ID. Requests without a verified SVID are answered with 401, those
+// authorize refuses with 403 unless it returns an HTTPError. Routes
+// registered with Public are let through, as by AuthMiddleware. Handlers
+// read the ID with GetClientCert(ctx).SPIFFEID.
+//
+//	server.Use(shttp.SPIFFEMiddleware(shttp.AllowSPIFFEIDs(
+//		"spiffe://example.org/billing",
+//		"spiffe://example.org/checkout",
+//	)))
+func SPIFFEMiddleware(authorize SPIFFEAuthorizer) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if rt, ok := ctx.Value(routeKey{}).(*route); ok && rt.auth == authPublic {
+				return next(ctx, w, r)
+			}
+			ctx, cert := withClientCert(ctx, r)
+			if cert == nil || cert.SPIFFEID == "" {
+				return NewHTTPError(http.StatusUnauthorized, "a SPIFFE client certificate is required")
+			}
+			AddLogAttrs(ctx, slog.String("spiffe_id", cert.SPIFFEID))
+			if err := authorize(ctx, cert.SPIFFEID, r); err != nil {
+				var httpErr HTTPError
+				if errors.As(err, &httpErr) {
+					return err
+				}
+				return NewHTTPError(http.StatusForbidden, "workload "+cert.SPIFFEID+" is not allowed")
+			}
+			return next(ctx, w, r)
+		}
+	}
+}
+
+// spiffeID returns the SPIFFE ID of an X.509 SVID: its only URI SAN, with
+// the spiffe scheme, a trust domain and nothing but a path besides.
+func spiffeID(cert *x509.Certificate) string {
+	if len(cert.URIs) != 1 {
+		return ""
+	}
+	u := cert.URIs[0]
+	if u.Scheme != "spiffe" || u.Host == "" || u.Port() != "" || u.User != nil ||
+		u.RawQuery != "" || u.Fragment != "" || u.Opaque != "" {
+		return ""
+	}
+	return u.String()
+}
@@ -0,0 +1,62 @@
+package shttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSPIFFEMiddleware(t *testing.T) {
+	ca := newTestCA(t)
+	leaf := func(uris ...string) *x509.Certificate {
+		cert, err := x509.ParseCertificate(ca.issue(t, "workload", uris...).Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	router := NewRouter()
+	router.Use(SPIFFEMiddleware(AllowSPIFFEIDs("spiffe://example.org/billing")))
+	router.GET("/invoices", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		_, err := w.Write([]byte(GetClientCert(ctx).SPIFFEID))
+		return err
+	})
+	router.GET("/healthz", simpleHandler("ok"), Public())
+
+	tests := []struct {
+		name       string
+		path       string
+		cert       *x509.Certificate
+		wantStatus int
+		wantBody   string
+	}{
+		{"allowed workload", "/invoices", leaf("spiffe://example.org/billing"), http.StatusOK, "spiffe://example.org/billing"},
+		{"other workload", "/invoices", leaf("spiffe://example.org/checkout"), http.StatusForbidden, ""},
+		{"other trust domain", "/invoices", leaf("spiffe://evil.example/billing"), http.StatusForbidden, ""},
+		{"no certificate", "/invoices", nil, http.StatusUnauthorized, ""},
+		{"not an SVID", "/invoices", leaf(), http.StatusUnauthorized, ""},
+		{"several URI SANs", "/invoices", leaf("spiffe://example.org/billing", "spiffe://example.org/admin"), http.StatusUnauthorized, ""},
+		{"query in the ID", "/invoices", leaf("spiffe://example.org/billing?admin=1"), http.StatusUnauthorized, ""},
+		{"public route", "/healthz", nil, http.StatusOK, "ok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.cert != nil {
+				req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tt.cert, ca.cert}}}
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package shttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// StaticOptions configures StaticHandler.
+type StaticOptions struct {
+	// File served for directory requests (default "index.html")
+	Index string
+
+	// List the contents of directories without an index file; otherwise
+	// they are answered with 404
+	Browse bool
+
+	// File served, with 200, for paths matching no file, as single page
+	// applications do with their client-side routes. Paths whose last
+	// segment has an extension (missing assets) still get 404.
+	Fallback string
+
+	// Cache-Control header of the files served (none by default). The
+	// fallback file is always served with "no-cache", so clients pick up new
+	// builds.
+	CacheControl string
+
+	// Serve one site per host: the files of each request come from the
+	// directory of fsys named after its Host header, without the port
+	// (sites/example.com/ for example.com:8080 when fsys is sites/).
+	// Index, Fallback and Browse apply within each site.
+	VirtualHosts bool
+
+	// With VirtualHosts, directory serving hosts without a directory of
+	// their own; they get 404 when empty
+	DefaultHost string
+}
+
+// StaticHandler serves the files of fsys named by the {path...} wildcard of
+// its route, with ETag, Last-Modified, conditional and Range request support
+// (see http.ServeContent). Static and SPA register it on a prefix.
+//
+// Files compressed ahead of time by the build pipeline are served in place
+// of the original when the client accepts their encoding: app.js.br with
+// "Content-Encoding: br", or app.js.gz with gzip, the brotli variant being
+// preferred. The response keeps the original file's Content-Type.
+func StaticHandler(fsys fs.FS, opts StaticOptions) Handler {
+	if opts.Index == "" {
+		opts.Index = "index.html"
+	}
+	root := fsys
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		fsys := root
+		if opts.VirtualHosts {
+			site, ok := staticSite(root, r.Host, opts.DefaultHost)
+			if !ok {
+				return NewHTTPError(http.StatusNotFound, "404 page not found")
+			}
+			fsys = site
+		}
+
+		name := path.Clean("/" + PathValue(r, "path"))[1:]
+		if name == "" {
+			name = "."
+		}
+
+		f, info, err := openStatic(fsys, name)
+		switch {
+		case errors.Is(err, fs.ErrNotExist) && opts.Fallback != "" && path.Ext(name) == "":
+			return serveStaticFallback(w, r, fsys, opts.Fallback)
+		case errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrInvalid):
+			return NewHTTPError(http.StatusNotFound, "404 page not found")
+		case err != nil:
+			return err
+		}
+		defer f.Close()
+
+		if !info.IsDir() {
+			return serveStaticFile(w, r, fsys, name, f, info, opts.CacheControl)
+		}
+
+		// Relative links in the index and listing need the trailing slash
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			target := path.Base(r.URL.Path) + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return nil
+		}
+		indexName := path.Join(name, opts.Index)
+		index, indexInfo, err := openStatic(fsys, indexName)
+		if err == nil && !indexInfo.IsDir() {
+			defer index.Close()
+			return serveStaticFile(w, r, fsys, indexName, index, indexInfo, opts.CacheControl)
+		}
+		if opts.Browse {
+			return listStaticDir(w, fsys, name)
+		}
+		if opts.Fallback != "" {
+			return serveStaticFallback(w, r, fsys, opts.Fallback)
+		}
+		return NewHTTPError(http.StatusNotFound, "404 page not found")
+	}
+}
+
+// staticSite returns the directory of fsys serving host, falling back to
+// defaultHost.
+func staticSite(fsys fs.FS, host, defaultHost string) (fs.FS, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, dir := range []string{host, defaultHost} {
+		// Hosts are client input: ".." or a slash must not reach other
+		// directories
+		if dir == "" || dir == "." || strings.Contains(dir, "/") || !fs.ValidPath(dir) {
+			continue
+		}
+		if info, err := fs.Stat(fsys, dir); err == nil && info.IsDir() {
+			site, err := fs.Sub(fsys, dir)
+			return site, err == nil
+		}
+	}
+	return nil, false
+}
+
+// openStatic opens name and returns its info.
+func openStatic(fsys fs.FS, name string) (fs.File, fs.FileInfo, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, info, nil
+}
+
+// staticEncodings are the content codings of the precompressed siblings
+// looked up for static files, by order of preference.
+var staticEncodings = []struct {
+	coding, ext string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// serveStaticFile serves the open file name of fsys with its validators, or
+// its precompressed sibling (app.js.br, app.js.gz) when there is one the
+// client accepts.
+func serveStaticFile(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string, f fs.File, info fs.FileInfo, cacheControl string) error {
+	if compressed, compressedInfo, coding, vary := openPrecompressed(fsys, name, r.Header.Get("Accept-Encoding")); vary {
+		// Caches must key the response on Accept-Encoding, whichever
+		// variant this client gets
+		w.Header().Add("Vary", "Accept-Encoding")
+		if compressed != nil {
+			defer compressed.Close()
+			// The type is the original file's, never sniffed from the
+			// compressed bytes
+			contentType := mime.TypeByExtension(path.Ext(name))
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+			w.Header().Set("Content-Type", contentType)
+			w.Header().Set("Content-Encoding", coding)
+			f, info = compressed, compressedInfo
+		}
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		// Files of some fs.FS implementations cannot seek; Range requests
+		// need it
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		content = bytes.NewReader(data)
+	}
+	w.Header().Set("ETag", staticETag(info))
+	if cacheControl != "" {
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+	http.ServeContent(w, r, path.Base(name), info.ModTime(), content)
+	return nil
+}
+
+// openPrecompressed looks for the precompressed siblings of name. vary
+// reports whether there is any; f is the preferred one the client accepts
+// according to acceptEncoding, nil if none.
+func openPrecompressed(fsys fs.FS, name, acceptEncoding string) (f fs.File, info fs.FileInfo, coding string, vary bool) {
+	for _, enc := range staticEncodings {
+		sibling, siblingInfo, err := openStatic(fsys, name+enc.ext)
+		if err != nil {
+			continue
+		}
+		if siblingInfo.IsDir() {
+			sibling.Close()
+			continue
+		}
+		vary = true
+		if f == nil && acceptsEncoding(acceptEncoding, enc.coding) {
+			f, info, coding = sibling, siblingInfo, enc.coding
+			continue
+		}
+		sibling.Close()
+	}
+	return f, info, coding, vary
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header accepts coding,
+// named or through "*", with a non-zero quality.
+func acceptsEncoding(header, coding string) bool {
+	accepted := false
+	for _, part := range strings.Split(header, ",") {
+		token, params, _ := strings.Cut(part, ";")
+		token = strings.TrimSpace(token)
+		if !strings.EqualFold(token, coding) && token != "*" {
+			continue
+		}
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.EqualFold(strings.TrimSpace(name), "q") {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+		if strings.EqualFold(token, coding) {
+			// An explicit entry overrides "*"
+			return q > 0
+		}
+		accepted = q > 0
+	}
+	return accepted
+}
+
+// serveStaticFallback serves the SPA fallback file.
+func serveStaticFallback(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string) error {
+	f, info, err := openStatic(fsys, name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return serveStaticFile(w, r, fsys, name, f, info, "no-cache")
+}
+
+// staticETag derives a strong validator from the file's size and
+// modification time, so files need not be read to answer conditional
+// requests.
+func staticETag(info fs.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+}
+
+// listStaticDir writes an HTML listing of a directory.
+func listStaticDir(w http.ResponseWriter, fsys fs.FS, name string) error {
+	entries, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+
+	var buf bytes.Buffer
+	buf.WriteString("<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n")
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if entry.IsDir() {
+			entryName += "/"
+		}
+		link := url.URL{Path: entryName}
+		fmt.Fprintf(&buf, "<a href=\"%s\">%s</a>\n", link.String(), html.EscapeString(entryName))
+	}
+	buf.WriteString("</pre>\n")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// Static serves the files under dir at prefix, e.g. Static("/assets", "./public")
+// serves ./public/app.js as /assets/app.js. Directories are served through
+// their index.html and are not listed; see StaticWithOptions.
+func (r *Router) Static(prefix, dir string, opts ...RouteOption) {
+	r.StaticWithOptions(prefix, os.DirFS(dir), StaticOptions{}, opts...)
+}
+
+// StaticWithOptions serves the files of fsys at prefix with explicit
+// options. fsys may be an embed.FS (use fs.Sub to strip its top directory).
+// GET and HEAD routes are registered on prefix/{path...}.
+func (r *Router) StaticWithOptions(prefix string, fsys fs.FS, opts StaticOptions, routeOpts ...RouteOption) {
+	pattern := strings.TrimSuffix(prefix, "/") + "/{path...}"
+	handler := StaticHandler(fsys, opts)
+	r.Handle(http.MethodGet, pattern, handler, routeOpts...)
+	r.Handle(http.MethodHead, pattern, handler, routeOpts...)
+}
+
+// SPA serves a single page application built into dir at prefix: existing
+// files are served as by Static, and every other path without an extension
+// gets indexFile, so client-side routes work on reload.
+func (r *Router) SPA(prefix, dir, indexFile string, opts ...RouteOption) {
+	r.StaticWithOptions(prefix, os.DirFS(dir), StaticOptions{Index: indexFile, Fallback: indexFile}, opts...)
+}
+
+// StaticSites serves one static site per host at prefix: the files for
+// example.com come from dir/example.com/ (see StaticOptions.VirtualHosts),
+// and hosts without a directory get 404.
+func (r *Router) StaticSites(prefix, dir string, opts ...RouteOption) {
+	r.StaticWithOptions(prefix, os.DirFS(dir), StaticOptions{VirtualHosts: true}, opts...)
+}
+
+// Static serves the files under dir at prefix (see Router.Static).
+func (s *Server) Static(prefix, dir string, opts ...RouteOption) {
+	s.Router().Static(prefix, dir, opts...)
+}
+
+// SPA serves a single page application at prefix (see Router.SPA).
+func (s *Server) SPA(prefix, dir, indexFile string, opts ...RouteOption) {
+	s.Router().SPA(prefix, dir, indexFile, opts...)
+}
+
+// StaticSites serves one static site per host at prefix (see
+// Router.StaticSites).
+func (s *Server) StaticSites(prefix, dir string, opts ...RouteOption) {
+	s.Router().StaticSites(prefix, dir, opts...)
+}
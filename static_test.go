@@ -0,0 +1,189 @@
+package shttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestStatic(t *testing.T) {
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("<h1>home</h1>"), ModTime: modTime},
+		"app.js":          {Data: []byte("console.log('app')"), ModTime: modTime},
+		"docs/guide.txt":  {Data: []byte("0123456789"), ModTime: modTime},
+		"docs/readme.txt": {Data: []byte("readme"), ModTime: modTime},
+	}
+
+	router := NewRouter()
+	router.StaticWithOptions("/assets", fsys, StaticOptions{CacheControl: "public, max-age=60"})
+	router.StaticWithOptions("/browse/", fsys, StaticOptions{Browse: true})
+	router.StaticWithOptions("/app", fsys, StaticOptions{Fallback: "index.html"})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		header     http.Header
+		wantStatus int
+		wantBody   string
+		wantHeader map[string]string
+	}{
+		{name: "file", path: "/assets/app.js", wantStatus: http.StatusOK, wantBody: "console.log('app')",
+			wantHeader: map[string]string{"Cache-Control": "public, max-age=60", "Content-Type": "text/javascript; charset=utf-8"}},
+		{name: "HEAD", method: http.MethodHead, path: "/assets/app.js", wantStatus: http.StatusOK},
+		{name: "directory index", path: "/assets/", wantStatus: http.StatusOK, wantBody: "<h1>home</h1>"},
+		{name: "directory redirect", path: "/assets/docs?x=1", wantStatus: http.StatusMovedPermanently,
+			wantHeader: map[string]string{"Location": "/assets/docs/?x=1"}},
+		{name: "directory not listed", path: "/assets/docs/", wantStatus: http.StatusNotFound},
+		{name: "missing file", path: "/assets/missing.js", wantStatus: http.StatusNotFound},
+		{name: "traversal", path: "/assets/%2e%2e/secret", wantStatus: http.StatusNotFound},
+		{name: "range", path: "/assets/docs/guide.txt", header: http.Header{"Range": {"bytes=2-4"}},
+			wantStatus: http.StatusPartialContent, wantBody: "234", wantHeader: map[string]string{"Content-Range": "bytes 2-4/10"}},
+		{name: "listing", path: "/browse/docs/", wantStatus: http.StatusOK,
+			wantBody: "<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n<a href=\"guide.txt\">guide.txt</a>\n<a href=\"readme.txt\">readme.txt</a>\n</pre>\n"},
+		{name: "SPA route", path: "/app/orders/7", wantStatus: http.StatusOK, wantBody: "<h1>home</h1>",
+			wantHeader: map[string]string{"Cache-Control": "no-cache"}},
+		{name: "SPA file", path: "/app/app.js", wantStatus: http.StatusOK, wantBody: "console.log('app')"},
+		{name: "SPA missing asset", path: "/app/missing.js", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, tt.path, nil)
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %q)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			for k, v := range tt.wantHeader {
+				if got := w.Header().Get(k); got != v {
+					t.Errorf("%s = %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestStaticConditional(t *testing.T) {
+	fsys := fstest.MapFS{"app.js": {Data: []byte("app"), ModTime: time.Now()}}
+	router := NewRouter()
+	router.StaticWithOptions("/", fsys, StaticOptions{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+	etag := w.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"`) || w.Header().Get("Last-Modified") == "" {
+		t.Fatalf("ETag = %q, Last-Modified = %q", etag, w.Header().Get("Last-Modified"))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match status = %d, want %d", w.Code, http.StatusNotModified)
+	}
+}
+
+func TestStaticPrecompressed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":       {Data: []byte("console.log('app')")},
+		"app.js.br":    {Data: []byte("br-bytes")},
+		"app.js.gz":    {Data: []byte("gzip-bytes")},
+		"style.css":    {Data: []byte("body{}")},
+		"style.css.gz": {Data: []byte("gzip-css")},
+		"logo.png":     {Data: []byte("png")},
+	}
+	router := NewRouter()
+	router.StaticWithOptions("/", fsys, StaticOptions{})
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		wantBody       string
+		wantEncoding   string
+		wantType       string
+		wantVary       bool
+	}{
+		{name: "brotli preferred", path: "/app.js", acceptEncoding: "gzip, deflate, br", wantBody: "br-bytes", wantEncoding: "br", wantType: "text/javascript; charset=utf-8", wantVary: true},
+		{name: "gzip only", path: "/app.js", acceptEncoding: "gzip", wantBody: "gzip-bytes", wantEncoding: "gzip", wantType: "text/javascript; charset=utf-8", wantVary: true},
+		{name: "brotli refused", path: "/app.js", acceptEncoding: "*, br;q=0", wantBody: "gzip-bytes", wantEncoding: "gzip", wantVary: true},
+		{name: "no encoding accepted", path: "/app.js", wantBody: "console.log('app')", wantType: "text/javascript; charset=utf-8", wantVary: true},
+		{name: "only the gzip sibling", path: "/style.css", acceptEncoding: "br, gzip", wantBody: "gzip-css", wantEncoding: "gzip", wantType: "text/css; charset=utf-8", wantVary: true},
+		{name: "no sibling", path: "/logo.png", acceptEncoding: "br, gzip", wantBody: "png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Body.String() != tt.wantBody || w.Header().Get("Content-Encoding") != tt.wantEncoding {
+				t.Errorf("got %q with encoding %q, want %q with %q", w.Body.String(), w.Header().Get("Content-Encoding"), tt.wantBody, tt.wantEncoding)
+			}
+			if tt.wantType != "" && w.Header().Get("Content-Type") != tt.wantType {
+				t.Errorf("Content-Type = %q, want %q", w.Header().Get("Content-Type"), tt.wantType)
+			}
+			if got := w.Header().Get("Vary") == "Accept-Encoding"; got != tt.wantVary {
+				t.Errorf("Vary = %q, want Accept-Encoding: %t", w.Header().Get("Vary"), tt.wantVary)
+			}
+		})
+	}
+}
+
+func TestStaticVirtualHosts(t *testing.T) {
+	fsys := fstest.MapFS{
+		"example.com/index.html":  {Data: []byte("example")},
+		"example.com/app.js":      {Data: []byte("example app")},
+		"blog.example/index.html": {Data: []byte("blog")},
+		"default/index.html":      {Data: []byte("default")},
+		"secret.txt":              {Data: []byte("secret")},
+	}
+	strict := NewRouter()
+	strict.StaticWithOptions("/", fsys, StaticOptions{VirtualHosts: true})
+	lenient := NewRouter()
+	lenient.StaticWithOptions("/", fsys, StaticOptions{VirtualHosts: true, DefaultHost: "default"})
+
+	tests := []struct {
+		name       string
+		router     *Router
+		host, path string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "site index", router: strict, host: "example.com", path: "/", wantStatus: http.StatusOK, wantBody: "example"},
+		{name: "site file", router: strict, host: "example.com", path: "/app.js", wantStatus: http.StatusOK, wantBody: "example app"},
+		{name: "port and case ignored", router: strict, host: "Blog.Example:8080", path: "/", wantStatus: http.StatusOK, wantBody: "blog"},
+		{name: "unknown host", router: strict, host: "other.org", path: "/", wantStatus: http.StatusNotFound},
+		{name: "file of another site", router: strict, host: "blog.example", path: "/app.js", wantStatus: http.StatusNotFound},
+		{name: "host traversal", router: strict, host: "..", path: "/secret.txt", wantStatus: http.StatusNotFound},
+		{name: "default host", router: lenient, host: "other.org", path: "/", wantStatus: http.StatusOK, wantBody: "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Host = tt.host
+			w := httptest.NewRecorder()
+			tt.router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus || (tt.wantBody != "" && w.Body.String() != tt.wantBody) {
+				t.Errorf("got %d %q, want %d %q", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package shttp
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// routeNode is a node of the routing tree, a trie with one level per path
+// segment. Lookups try literal segments first, then single parameters, then
+// the multi-segment wildcard, backtracking on failure, so the most specific
+// pattern wins: "/users/me" over "/users/{id}" over "/users/". Unlike
+// ServeMux, overlapping patterns where neither is more specific (such as
+// "/a/{x}/c" and "/a/b/{y}") are allowed and resolved by the same order,
+// left to right.
+type routeNode struct {
+	// Routes of the pattern ending at this node, nil for interior nodes
+	routes *pathRoutes
+
+	// Number of segments of that pattern, and whether it ends with a
+	// multi-segment wildcard ({name...} or a trailing slash)
+	segments int
+	multi    bool
+
+	// Children by literal segment; "/" stands for a trailing slash ({$})
+	children map[string]*routeNode
+
+	// Child for a {name} parameter
+	param *routeNode
+
+	// Leaf for a {name...} wildcard or trailing slash at this position
+	wildcard *routeNode
+}
+
+// insert adds pattern, a normalized pattern (see parsePattern), to the tree.
+// Registering a pattern equivalent to an existing one, e.g. differing only
+// in parameter names, panics like ServeMux does.
+func (n *routeNode) insert(pattern string, pr *pathRoutes) {
+	segs := strings.Split(pattern[1:], "/")
+	for i, seg := range segs {
+		last := i == len(segs)-1
+		switch {
+		case last && (seg == "" || strings.HasSuffix(seg, "...}")):
+			// Trailing slash or {name...}: matches the rest of the path
+			if n.wildcard == nil {
+				n.wildcard = &routeNode{multi: true}
+			}
+			n = n.wildcard
+		case seg == "{$}":
+			n = n.child("/")
+		case strings.HasPrefix(seg, "{"):
+			if n.param == nil {
+				n.param = &routeNode{}
+			}
+			n = n.param
+		default:
+			if unescaped, err := url.PathUnescape(seg); err == nil {
+				seg = unescaped
+			}
+			n = n.child(seg)
+		}
+	}
+	if n.routes != nil {
+		panic(fmt.Sprintf("shttp: pattern %q conflicts with the registered pattern %q", pattern, n.routes.pattern))
+	}
+	n.routes = pr
+	n.segments = len(segs)
+}
+
+// child returns the child for a literal segment, creating it if needed.
+func (n *routeNode) child(seg string) *routeNode {
+	if n.children == nil {
+		n.children = make(map[string]*routeNode)
+	}
+	c, ok := n.children[seg]
+	if !ok {
+		c = &routeNode{}
+		n.children[seg] = c
+	}
+	return c
+}
+
+// matchFilter restricts lookups to the patterns registered for a method,
+// in method pattern mode. The zero value accepts every pattern.
+type matchFilter struct {
+	method string
+	only   bool
+}
+
+func (f matchFilter) accepts(pr *pathRoutes) bool {
+	return !f.only || pr.registered[f.method]
+}
+
+// match finds the node of the most specific pattern matching path, an
+// escaped and cleaned request path, appending the values of its parameters
+// to matches. It does not allocate unless the path has escaped characters
+// or more parameters than matches can hold.
+func (n *routeNode) match(path string, f matchFilter, matches []string) (*routeNode, []string) {
+	if n == nil {
+		return nil, nil
+	}
+	if path == "" {
+		if n.routes == nil || !f.accepts(n.routes) {
+			return nil, nil
+		}
+		return n, matches
+	}
+	seg, rest := firstSegment(path)
+	if c, ok := n.children[seg]; ok {
+		if m, values := c.match(rest, f, matches); m != nil {
+			return m, values
+		}
+	}
+	// Parameters never match the trailing slash
+	if seg != "/" {
+		if m, values := n.param.match(rest, f, append(matches, seg)); m != nil {
+			return m, values
+		}
+	}
+	if c := n.wildcard; c != nil && f.accepts(c.routes) {
+		if c.routes.named {
+			matches = append(matches, pathUnescape(path[1:]))
+		}
+		return c, matches
+	}
+	return nil, nil
+}
+
+// methods adds to set the methods registered on the patterns matching path.
+func (n *routeNode) methods(path string, set map[string]bool) {
+	if n == nil {
+		return
+	}
+	if path == "" {
+		if n.routes != nil {
+			for method := range n.routes.registered {
+				set[method] = true
+			}
+		}
+		return
+	}
+	seg, rest := firstSegment(path)
+	n.children[seg].methods(rest, set)
+	if seg != "/" {
+		n.param.methods(rest, set)
+	}
+	if n.wildcard != nil {
+		n.wildcard.methods("", set)
+	}
+}
+
+// exact reports whether the match of n for path is exact, i.e. a
+// multi-segment wildcard matched nothing. ServeMux only redirects "/tree"
+// to "/tree/" when the latter matches exactly.
+func (n *routeNode) exact(path string) bool {
+	if n == nil {
+		return false
+	}
+	if !n.multi {
+		return true
+	}
+	if !strings.HasSuffix(path, "/") {
+		return false
+	}
+	return n.segments == strings.Count(path, "/")
+}
+
+// firstSegment splits the first segment, unescaped, off path. A trailing
+// slash is returned as the segment "/".
+func firstSegment(path string) (seg, rest string) {
+	if path == "/" {
+		return "/", ""
+	}
+	path = path[1:]
+	i := strings.IndexByte(path, '/')
+	if i < 0 {
+		i = len(path)
+	}
+	return pathUnescape(path[:i]), path[i:]
+}
+
+// pathUnescape unescapes s, keeping it as is when it is not valid.
+func pathUnescape(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	if unescaped, err := url.PathUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
+
+// cleanPath returns the canonical form of an escaped path, eliminating .
+// and .. elements and duplicate slashes but keeping a trailing slash, like
+// ServeMux does before matching.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	np := path.Clean(p)
+	if p[len(p)-1] == '/' && np != "/" {
+		if len(p) == len(np)+1 && strings.HasPrefix(p, np) {
+			np = p
+		} else {
+			np += "/"
+		}
+	}
+	return np
+}
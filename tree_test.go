@@ -0,0 +1,129 @@
+package shttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestRouterMatchesServeMux checks the routing tree against ServeMux for
+// patterns both accept.
+func TestRouterMatchesServeMux(t *testing.T) {
+	patterns := []string{
+		"/",
+		"/users/{id}",
+		"/users/me",
+		"/users/{id}/posts/{post}",
+		"/static/",
+		"/files/{path...}",
+		"/exact/{$}",
+		"/tree/",
+		"/tree/leaf",
+	}
+	router := NewRouter()
+	mux := http.NewServeMux()
+	for _, p := range patterns {
+		router.ANY(p, func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			fmt.Fprint(w, describeMatch(r, p))
+			return nil
+		})
+		mux.HandleFunc(p, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, describeMatch(r, p))
+		})
+	}
+
+	paths := []string{
+		"/", "/users/42", "/users/me", "/users/42/posts/7", "/users/a%2Fb",
+		"/users/42/", "/users", "/static", "/static/", "/static/css/a.css",
+		"/files", "/files/", "/files/a/b%20c", "/exact/", "/exact", "/exact/x",
+		"/tree", "/tree/leaf", "/tree/leaf/", "/x/../users/me",
+		"//users/42", "/users/./42?q=1", "/unknown/deep/path",
+	}
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			want := httptest.NewRecorder()
+			mux.ServeHTTP(want, httptest.NewRequest(http.MethodGet, path, nil))
+			got := httptest.NewRecorder()
+			router.ServeHTTP(got, httptest.NewRequest(http.MethodGet, path, nil))
+
+			if isRedirect(want.Code) {
+				if !isRedirect(got.Code) || got.Header().Get("Location") != want.Header().Get("Location") {
+					t.Errorf("got %d Location %q, want redirect to %q", got.Code, got.Header().Get("Location"), want.Header().Get("Location"))
+				}
+				return
+			}
+			if got.Code != want.Code || got.Body.String() != want.Body.String() {
+				t.Errorf("got %d %q, want %d %q", got.Code, got.Body.String(), want.Code, want.Body.String())
+			}
+		})
+	}
+}
+
+func describeMatch(r *http.Request, pattern string) string {
+	values := []string{pattern}
+	for _, name := range []string{"id", "post", "path", "x"} {
+		if v := r.PathValue(name); v != "" {
+			values = append(values, name+"="+v)
+		}
+	}
+	return strings.Join(values, " ")
+}
+
+func isRedirect(status int) bool {
+	return status == http.StatusMovedPermanently || status == http.StatusTemporaryRedirect
+}
+
+func TestRouterPriority(t *testing.T) {
+	router := NewRouter()
+	// Overlapping patterns ServeMux refuses: the literal segment wins,
+	// left to right.
+	router.GET("/a/{x}/c", simpleHandler("x-c"))
+	router.GET("/a/b/{y}", simpleHandler("b-y"))
+	router.GET("/a/{x}/{y}", simpleHandler("x-y"))
+
+	tests := map[string]string{
+		"/a/b/c": "b-y",
+		"/a/z/c": "x-c",
+		"/a/z/d": "x-y",
+		"/a/b/d": "b-y",
+	}
+	for path, want := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Body.String() != want {
+			t.Errorf("%s = %q, want %q", path, w.Body.String(), want)
+		}
+	}
+}
+
+func TestRouterPatternConflict(t *testing.T) {
+	router := NewRouter()
+	router.GET("/users/{id}", simpleHandler("user"))
+	defer func() {
+		if recover() == nil {
+			t.Error("registering an equivalent pattern did not panic")
+		}
+	}()
+	router.GET("/users/{name}", simpleHandler("user"))
+}
+
+func TestRouteTreeMatchAllocs(t *testing.T) {
+	router := NewRouter()
+	router.GET("/users/{id}/posts/{post}", simpleHandler("post"))
+	router.GET("/files/{path...}", simpleHandler("file"))
+
+	var buf [maxInlineParams]string
+	for _, path := range []string{"/users/42/posts/7", "/files/a/b/c.txt"} {
+		allocs := testing.AllocsPerRun(100, func() {
+			if n, _ := router.tree.match(path, matchFilter{}, buf[:0]); n == nil {
+				t.Fatalf("%s did not match", path)
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("matching %s allocates %.0f times, want 0", path, allocs)
+		}
+	}
+}
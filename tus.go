@@ -0,0 +1,349 @@
+package shttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tusVersion is the tus protocol version implemented by TusUpload.
+const tusVersion = "1.0.0"
+
+// ErrUploadNotFound is returned by TusStore implementations for unknown uploads.
+var ErrUploadNotFound = NewHTTPError(http.StatusNotFound, "upload not found")
+
+// ErrUploadOffsetConflict is returned by TusStore implementations for writes
+// at another offset than the upload's current one, or while another write
+// to the upload is in progress.
+var ErrUploadOffsetConflict = NewHTTPError(http.StatusConflict, "Upload-Offset does not match the current offset")
+
+// TusUploadInfo describes a resumable upload.
+type TusUploadInfo struct {
+	ID        string
+	Size      int64
+	Offset    int64
+	Metadata  map[string]string
+	ExpiresAt time.Time
+}
+
+// TusStore persists resumable uploads. Implementations must be safe for
+// concurrent use.
+type TusStore interface {
+	// Create registers a new upload and returns its ID.
+	Create(ctx context.Context, info TusUploadInfo) (string, error)
+
+	// Info returns the upload's current state, or ErrUploadNotFound.
+	Info(ctx context.Context, id string) (TusUploadInfo, error)
+
+	// Write appends data from r at offset and returns the number of bytes
+	// stored. When offset is not the current offset, or another write to
+	// the upload is in progress, it stores nothing and returns
+	// ErrUploadOffsetConflict: checking and appending must be atomic, so two
+	// PATCH requests for the same offset cannot both append. Data read
+	// before an error must be kept so the client can resume from the new
+	// offset.
+	Write(ctx context.Context, id string, offset int64, r io.Reader) (int64, error)
+
+	// Delete removes the upload and its data.
+	Delete(ctx context.Context, id string) error
+}
+
+// TusOptions configures TusUploadWithOptions.
+type TusOptions struct {
+	// Maximum upload size in bytes (0 for no limit), advertised as Tus-Max-Size
+	MaxSize int64
+
+	// Time after creation at which unfinished uploads expire (default 24h)
+	Expiration time.Duration
+}
+
+// TusUpload mounts a tus.io 1.0.0 resumable upload endpoint at path, with
+// the creation, expiration and termination extensions. Uploads are created
+// with POST path and addressed as path/{id}.
+func (r *Router) TusUpload(path string, store TusStore) {
+	r.TusUploadWithOptions(path, store, TusOptions{})
+}
+
+// TusUploadWithOptions is like TusUpload with explicit options.
+func (r *Router) TusUploadWithOptions(path string, store TusStore, opts TusOptions) {
+	if opts.Expiration <= 0 {
+		opts.Expiration = 24 * time.Hour
+	}
+	path = strings.TrimSuffix(path, "/")
+	// Location headers need the full path, including any group prefix.
+	t := &tusHandler{store: store, opts: opts, path: r.fullPath(path)}
+
+	r.Handle(http.MethodOptions, path, t.options)
+	r.Handle(http.MethodPost, path, t.tusResumable(t.create))
+	r.Handle(http.MethodHead, path+"/{id}", t.tusResumable(t.head))
+	// Chunks can be large and slow; the protocol itself handles resumption.
+	r.Handle(http.MethodPatch, path+"/{id}", t.tusResumable(t.patch), NoTimeout())
+	r.Handle(http.MethodDelete, path+"/{id}", t.tusResumable(t.delete))
+}
+
+// tusHandler implements the tus protocol on top of a TusStore.
+type tusHandler struct {
+	store TusStore
+	opts  TusOptions
+	path  string
+}
+
+// tusResumable enforces the Tus-Resumable header and sets it on responses.
+func (t *tusHandler) tusResumable(next Handler) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Tus-Resumable", tusVersion)
+		if r.Header.Get("Tus-Resumable") != tusVersion {
+			w.Header().Set("Tus-Version", tusVersion)
+			return NewHTTPError(http.StatusPreconditionFailed, "unsupported tus version")
+		}
+		return next(ctx, w, r)
+	}
+}
+
+func (t *tusHandler) options(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", "creation,expiration,termination")
+	if t.opts.MaxSize > 0 {
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(t.opts.MaxSize, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (t *tusHandler) create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		return NewHTTPError(http.StatusBadRequest, "invalid Upload-Length")
+	}
+	if t.opts.MaxSize > 0 && size > t.opts.MaxSize {
+		return NewHTTPError(http.StatusRequestEntityTooLarge, "upload exceeds Tus-Max-Size")
+	}
+	metadata, err := parseTusMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		return NewHTTPError(http.StatusBadRequest, "invalid Upload-Metadata")
+	}
+
+	info := TusUploadInfo{Size: size, Metadata: metadata, ExpiresAt: time.Now().Add(t.opts.Expiration)}
+	id, err := t.store.Create(ctx, info)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", t.path+"/"+id)
+	w.Header().Set("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+func (t *tusHandler) head(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	info, err := t.lookup(ctx, PathValue(r, "id"))
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(info.Size, 10))
+	w.Header().Set("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (t *tusHandler) patch(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		return NewHTTPError(http.StatusUnsupportedMediaType, "Content-Type must be application/offset+octet-stream")
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return NewHTTPError(http.StatusBadRequest, "invalid Upload-Offset")
+	}
+
+	id := PathValue(r, "id")
+	info, err := t.lookup(ctx, id)
+	if err != nil {
+		return err
+	}
+	if offset != info.Offset {
+		return ErrUploadOffsetConflict
+	}
+
+	// Never accept more than the declared upload length.
+	body := io.LimitReader(r.Body, info.Size-info.Offset)
+	n, err := t.store.Write(ctx, id, offset, body)
+	if err != nil && n == 0 {
+		return err
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset+n, 10))
+	w.Header().Set("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (t *tusHandler) delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := PathValue(r, "id")
+	if _, err := t.lookup(ctx, id); err != nil {
+		return err
+	}
+	if err := t.store.Delete(ctx, id); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// lookup returns the upload's info, removing it and answering 410 Gone once
+// it has expired.
+func (t *tusHandler) lookup(ctx context.Context, id string) (TusUploadInfo, error) {
+	info, err := t.store.Info(ctx, id)
+	if err != nil {
+		return info, err
+	}
+	if !info.ExpiresAt.IsZero() && time.Now().After(info.ExpiresAt) {
+		_ = t.store.Delete(ctx, id)
+		return info, NewHTTPError(http.StatusGone, "upload expired")
+	}
+	return info, nil
+}
+
+// parseTusMetadata decodes an Upload-Metadata header: comma separated
+// "key base64value" pairs, where the value may be omitted.
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if header == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("empty metadata key")
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, err
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+// tusSweepInterval is the minimum time between two sweeps of the expired
+// uploads of a MemoryTusStore.
+const tusSweepInterval = time.Minute
+
+// MemoryTusStore is an in-memory TusStore for tests and development.
+// Expired uploads are removed when accessed, and swept when new uploads are
+// created, so abandoned ones do not accumulate.
+type MemoryTusStore struct {
+	mu        sync.Mutex
+	uploads   map[string]*memoryUpload
+	lastSweep time.Time
+}
+
+type memoryUpload struct {
+	info TusUploadInfo // guarded by MemoryTusStore.mu
+
+	// Held while a write appends to data, without blocking other uploads
+	writing sync.Mutex
+	data    bytes.Buffer
+}
+
+// NewMemoryTusStore creates an empty in-memory TusStore.
+func NewMemoryTusStore() *MemoryTusStore {
+	return &MemoryTusStore{uploads: make(map[string]*memoryUpload)}
+}
+
+// Create implements TusStore.
+func (s *MemoryTusStore) Create(ctx context.Context, info TusUploadInfo) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now := time.Now(); now.Sub(s.lastSweep) >= tusSweepInterval {
+		s.sweep(now)
+		s.lastSweep = now
+	}
+	info.ID = generateRequestID()
+	s.uploads[info.ID] = &memoryUpload{info: info}
+	return info.ID, nil
+}
+
+// sweep removes the uploads expired at now.
+func (s *MemoryTusStore) sweep(now time.Time) {
+	for id, u := range s.uploads {
+		if !u.info.ExpiresAt.IsZero() && now.After(u.info.ExpiresAt) {
+			delete(s.uploads, id)
+		}
+	}
+}
+
+// Info implements TusStore.
+func (s *MemoryTusStore) Info(ctx context.Context, id string) (TusUploadInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.uploads[id]
+	if !ok {
+		return TusUploadInfo{}, ErrUploadNotFound
+	}
+	return u.info, nil
+}
+
+// Write implements TusStore. The body is read holding the upload's own
+// lock only, so a slow client does not hold up other uploads.
+func (s *MemoryTusStore) Write(ctx context.Context, id string, offset int64, r io.Reader) (int64, error) {
+	u, err := s.upload(id)
+	if err != nil {
+		return 0, err
+	}
+	if !u.writing.TryLock() {
+		return 0, ErrUploadOffsetConflict
+	}
+	defer u.writing.Unlock()
+
+	s.mu.Lock()
+	current := u.info.Offset
+	s.mu.Unlock()
+	if offset != current {
+		return 0, ErrUploadOffsetConflict
+	}
+
+	n, err := u.data.ReadFrom(r)
+	s.mu.Lock()
+	u.info.Offset += n
+	s.mu.Unlock()
+	return n, err
+}
+
+// Delete implements TusStore.
+func (s *MemoryTusStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.uploads, id)
+	return nil
+}
+
+// Data returns a copy of the bytes received so far for an upload.
+func (s *MemoryTusStore) Data(id string) []byte {
+	u, err := s.upload(id)
+	if err != nil {
+		return nil
+	}
+	u.writing.Lock()
+	defer u.writing.Unlock()
+	return bytes.Clone(u.data.Bytes())
+}
+
+func (s *MemoryTusStore) upload(id string) (*memoryUpload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.uploads[id]
+	if !ok {
+		return nil, ErrUploadNotFound
+	}
+	return u, nil
+}
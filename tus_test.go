@@ -0,0 +1,188 @@
+package shttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tusRequest builds a tus request carrying the Tus-Resumable header.
+func tusRequest(method, target, body string, headers map[string]string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Tus-Resumable", tusVersion)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req
+}
+
+func TestTusUpload(t *testing.T) {
+	store := NewMemoryTusStore()
+	router := NewRouter()
+	router.TusUploadWithOptions("/uploads", store, TusOptions{MaxSize: 10})
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Discovery
+	rec := serve(httptest.NewRequest(http.MethodOptions, "/uploads", nil))
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Tus-Max-Size") != "10" {
+		t.Fatalf("OPTIONS = %d, Tus-Max-Size %q", rec.Code, rec.Header().Get("Tus-Max-Size"))
+	}
+
+	// Creation
+	rec = serve(tusRequest(http.MethodPost, "/uploads", "", map[string]string{
+		"Upload-Length":   "9",
+		"Upload-Metadata": "filename aGVsbG8udHh0",
+	}))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST = %d, want 201", rec.Code)
+	}
+	location := rec.Header().Get("Location")
+	id := strings.TrimPrefix(location, "/uploads/")
+	info, err := store.Info(context.Background(), id)
+	if err != nil {
+		t.Fatalf("upload %q not stored: %v", location, err)
+	}
+	if info.Metadata["filename"] != "hello.txt" {
+		t.Errorf("metadata = %v", info.Metadata)
+	}
+
+	patch := func(offset, body string) *httptest.ResponseRecorder {
+		return serve(tusRequest(http.MethodPatch, location, body, map[string]string{
+			"Content-Type":  "application/offset+octet-stream",
+			"Upload-Offset": offset,
+		}))
+	}
+
+	tests := []struct {
+		name       string
+		req        func() *httptest.ResponseRecorder
+		wantStatus int
+		wantOffset string
+	}{
+		{name: "First chunk", req: func() *httptest.ResponseRecorder { return patch("0", "hello") }, wantStatus: http.StatusNoContent, wantOffset: "5"},
+		{name: "Stale offset", req: func() *httptest.ResponseRecorder { return patch("0", "hello") }, wantStatus: http.StatusConflict},
+		{name: "HEAD reports offset", req: func() *httptest.ResponseRecorder {
+			return serve(tusRequest(http.MethodHead, location, "", nil))
+		}, wantStatus: http.StatusOK, wantOffset: "5"},
+		{name: "Chunk beyond length is truncated", req: func() *httptest.ResponseRecorder { return patch("5", " tus!!") }, wantStatus: http.StatusNoContent, wantOffset: "9"},
+		{name: "Wrong content type", req: func() *httptest.ResponseRecorder {
+			return serve(tusRequest(http.MethodPatch, location, "x", map[string]string{"Upload-Offset": "9"}))
+		}, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "Missing Tus-Resumable", req: func() *httptest.ResponseRecorder {
+			return serve(httptest.NewRequest(http.MethodHead, location, nil))
+		}, wantStatus: http.StatusPreconditionFailed},
+		{name: "Too large", req: func() *httptest.ResponseRecorder {
+			return serve(tusRequest(http.MethodPost, "/uploads", "", map[string]string{"Upload-Length": "11"}))
+		}, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "Unknown upload", req: func() *httptest.ResponseRecorder {
+			return serve(tusRequest(http.MethodHead, "/uploads/missing", "", nil))
+		}, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.req()
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Upload-Offset"); got != tt.wantOffset {
+				t.Errorf("Upload-Offset = %q, want %q", got, tt.wantOffset)
+			}
+		})
+	}
+
+	if got := string(store.Data(id)); got != "hello tus" {
+		t.Errorf("stored data = %q, want %q", got, "hello tus")
+	}
+
+	// Termination
+	if rec := serve(tusRequest(http.MethodDelete, location, "", nil)); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE = %d, want 204", rec.Code)
+	}
+	if _, err := store.Info(context.Background(), id); err == nil {
+		t.Error("upload still exists after DELETE")
+	}
+}
+
+func TestTusUploadExpiration(t *testing.T) {
+	store := NewMemoryTusStore()
+	router := NewRouter()
+	router.TusUploadWithOptions("/uploads", store, TusOptions{Expiration: time.Millisecond})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, tusRequest(http.MethodPost, "/uploads", "", map[string]string{"Upload-Length": "3"}))
+	location := rec.Header().Get("Location")
+
+	time.Sleep(5 * time.Millisecond)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, tusRequest(http.MethodHead, location, "", nil))
+	if rec.Code != http.StatusGone {
+		t.Fatalf("HEAD after expiry = %d, want 410", rec.Code)
+	}
+	if _, err := store.Info(context.Background(), strings.TrimPrefix(location, "/uploads/")); err == nil {
+		t.Error("expired upload was not removed")
+	}
+}
+
+func TestMemoryTusStoreWrite(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryTusStore()
+	slow, _ := store.Create(ctx, TusUploadInfo{Size: 10})
+	other, _ := store.Create(ctx, TusUploadInfo{Size: 10})
+
+	// A slow client holds the first upload
+	pr, pw := io.Pipe()
+	done := make(chan int64)
+	go func() {
+		n, _ := store.Write(ctx, slow, 0, pr)
+		done <- n
+	}()
+	pw.Write([]byte("abc"))
+
+	if _, err := store.Write(ctx, slow, 0, strings.NewReader("xyz")); !errors.Is(err, ErrUploadOffsetConflict) {
+		t.Errorf("concurrent write error = %v, want ErrUploadOffsetConflict", err)
+	}
+	if n, err := store.Write(ctx, other, 0, strings.NewReader("other")); err != nil || n != 5 {
+		t.Errorf("write to another upload = %d, %v; want it not to wait", n, err)
+	}
+
+	pw.Close()
+	if n := <-done; n != 3 {
+		t.Fatalf("slow write stored %d bytes, want 3", n)
+	}
+	if _, err := store.Write(ctx, slow, 0, strings.NewReader("xyz")); !errors.Is(err, ErrUploadOffsetConflict) {
+		t.Errorf("stale offset write error = %v, want ErrUploadOffsetConflict", err)
+	}
+	if got := string(store.Data(slow)); got != "abc" {
+		t.Errorf("stored data = %q, want abc", got)
+	}
+}
+
+func TestMemoryTusStoreSweep(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryTusStore()
+	expired, _ := store.Create(ctx, TusUploadInfo{ExpiresAt: time.Now().Add(-time.Second)})
+	live, _ := store.Create(ctx, TusUploadInfo{ExpiresAt: time.Now().Add(time.Hour)})
+
+	// Sweeps run on Create, at most once per tusSweepInterval
+	store.lastSweep = time.Time{}
+	store.Create(ctx, TusUploadInfo{})
+
+	if _, err := store.Info(ctx, expired); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("expired upload still stored: %v", err)
+	}
+	if _, err := store.Info(ctx, live); err != nil {
+		t.Errorf("live upload removed: %v", err)
+	}
+}
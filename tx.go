@@ -0,0 +1,58 @@
+package shttp
+
+import (
+	"context"
+	"net/http"
+)
+
+// txKey is the context key for the unit of work of type T.
+type txKey[T any] struct{}
+
+// TxMiddleware opens a unit of work (e.g. a database transaction) per request.
+// The value returned by begin is available to handlers via Tx[T]. It is
+// committed when the handler returns nil and rolled back when the handler
+// returns an error or panics; the panic is re-raised afterwards so
+// RecoveryMiddleware still sees it.
+//
+// A commit error is returned to the router, so handlers should avoid writing
+// the response body before the transaction's outcome is known when a failed
+// commit must be reported to the client.
+func TxMiddleware[T any](begin func(ctx context.Context) (T, error), commit, rollback func(T) error) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			tx, err := begin(ctx)
+			if err != nil {
+				return err
+			}
+
+			defer func() {
+				if rec := recover(); rec != nil {
+					rollbackTx(ctx, rollback, tx)
+					panic(rec)
+				}
+			}()
+
+			if err := next(context.WithValue(ctx, txKey[T]{}, tx), w, r); err != nil {
+				rollbackTx(ctx, rollback, tx)
+				return err
+			}
+			return commit(tx)
+		}
+	}
+}
+
+// rollbackTx rolls tx back, logging (rather than returning) a failure so the
+// handler's original error still determines the response.
+func rollbackTx[T any](ctx context.Context, rollback func(T) error, tx T) {
+	if err := rollback(tx); err != nil {
+		if logger := GetLogger(ctx); logger != nil {
+			logger.Errorf(ctx, "[http.tx] Rollback failed: %v, request_id: %s", err, GetRequestID(ctx))
+		}
+	}
+}
+
+// Tx returns the unit of work opened by TxMiddleware[T] for the current request.
+func Tx[T any](ctx context.Context) (T, bool) {
+	tx, ok := ctx.Value(txKey[T]{}).(T)
+	return tx, ok
+}
@@ -0,0 +1,72 @@
+package shttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeTx records how a unit of work finished.
+type fakeTx struct {
+	outcome string
+}
+
+func TestTxMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		handler     Handler
+		wantOutcome string
+		wantPanic   bool
+	}{
+		{
+			name: "Commit on success",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				if _, ok := Tx[*fakeTx](ctx); !ok {
+					return errors.New("tx not in context")
+				}
+				return nil
+			},
+			wantOutcome: "commit",
+		},
+		{
+			name:        "Rollback on error",
+			handler:     errorHandler("boom"),
+			wantOutcome: "rollback",
+		},
+		{
+			name: "Rollback on panic",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				panic("boom")
+			},
+			wantOutcome: "rollback",
+			wantPanic:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &fakeTx{}
+			mw := TxMiddleware(
+				func(ctx context.Context) (*fakeTx, error) { return tx, nil },
+				func(tx *fakeTx) error { tx.outcome = "commit"; return nil },
+				func(tx *fakeTx) error { tx.outcome = "rollback"; return nil },
+			)
+
+			func() {
+				defer func() {
+					if rec := recover(); (rec != nil) != tt.wantPanic {
+						t.Errorf("panic = %v, want panic %v", rec, tt.wantPanic)
+					}
+				}()
+				req := httptest.NewRequest(http.MethodPost, "/test", nil)
+				executeMiddlewareTest(t, mw, tt.handler, req)
+			}()
+
+			if tx.outcome != tt.wantOutcome {
+				t.Errorf("outcome = %q, want %q", tx.outcome, tt.wantOutcome)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package shttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// StatusCoder is implemented by response types choosing their own status
+// code; see HandlerFor.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// HandlerFor adapts a typed function to a Handler, doing the request
+// decoding and response encoding:
+//
+//	type GetOrder struct {
+//		ID     int    `path:"id"`
+//		Expand bool   `query:"expand"`
+//		Tenant string `header:"X-Tenant"`
+//	}
+//
+//	r.GET("/orders/{id}", shttp.HandlerFor(func(ctx context.Context, in GetOrder) (Order, error) {
+//		...
+//	}))
+//
+// A request body is decoded into Req with Decode, then the fields tagged
+// `path`, `query` and `header` are set from the path parameters, query
+// parameters and request headers, overriding the body, and Req is
+// validated when it implements Validator. Supported field types are
+// strings, integers, floats, booleans and slices of those for repeated
+// query parameters; invalid values are answered with 400.
+//
+// Resp is written with JSON, including its header tags. The status is the
+// one returned by its StatusCode method when it implements StatusCoder,
+// otherwise 201 Created for POST and 200 OK; a nil Resp (pointer, map,
+// slice or interface) is answered with 204 No Content.
+func HandlerFor[Req any, Resp any](fn func(ctx context.Context, req Req) (Resp, error)) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		var req Req
+		if r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0 {
+			if err := Decode(r, &req); err != nil {
+				return err
+			}
+		}
+		if err := bindRequestFields(r, &req); err != nil {
+			return err
+		}
+		if err := Validate(&req); err != nil {
+			return err
+		}
+
+		resp, err := fn(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		if rv := reflect.ValueOf(resp); !rv.IsValid() || isNilValue(rv) {
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+		status := http.StatusOK
+		if r.Method == http.MethodPost {
+			status = http.StatusCreated
+		}
+		if coder, ok := any(resp).(StatusCoder); ok {
+			status = coder.StatusCode()
+		}
+		return JSON(w, status, resp)
+	}
+}
+
+// isNilValue reports whether rv is a nil pointer, map, slice or interface.
+func isNilValue(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// bindRequestFields sets the path, query and header tagged fields of the
+// struct v points to, including those of embedded structs.
+func bindRequestFields(r *http.Request, v any) error {
+	rv := reflect.ValueOf(v).Elem()
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	return bindStructFields(r, rv)
+}
+
+func bindStructFields(r *http.Request, rv reflect.Value) error {
+	rt := rv.Type()
+	query := r.URL.Query()
+	for i := range rt.NumField() {
+		field := rt.Field(i)
+		fv := rv.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := bindStructFields(r, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		var source, name string
+		var values []string
+		if name = field.Tag.Get("path"); name != "" {
+			source = "path parameter"
+			if v := PathValue(r, name); v != "" {
+				values = []string{v}
+			}
+		} else if name = field.Tag.Get("query"); name != "" {
+			source = "query parameter"
+			values = query[name]
+		} else if name = field.Tag.Get("header"); name != "" {
+			source = "header"
+			values = r.Header.Values(name)
+		}
+		if len(values) == 0 {
+			continue
+		}
+		if err := setFieldValues(fv, values); err != nil {
+			return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid %s %s: %v", source, name, err))
+		}
+	}
+	return nil
+}
+
+// setFieldValues parses values into fv: every value for a slice, the first
+// one otherwise.
+func setFieldValues(fv reflect.Value, values []string) error {
+	if fv.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := setFieldValue(slice.Index(i), v); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+	return setFieldValue(fv, values[0])
+}
+
+func setFieldValue(fv reflect.Value, v string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(v, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", v)
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(v, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a positive integer", v)
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(v, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a number", v)
+		}
+		fv.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", v)
+		}
+		fv.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
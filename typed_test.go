@@ -0,0 +1,102 @@
+package shttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type getOrder struct {
+	ID     int      `path:"id"`
+	Expand bool     `query:"expand"`
+	Fields []string `query:"field"`
+	Tenant string   `header:"X-Tenant"`
+}
+
+type order struct {
+	ID     int      `json:"id"`
+	Name   string   `json:"name"`
+	Fields []string `json:"fields,omitempty"`
+	Tenant string   `json:"-" header:"X-Tenant"`
+}
+
+type createOrder struct {
+	Name string `json:"name"`
+}
+
+func (c createOrder) Validate() error {
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+type accepted struct {
+	Job string `json:"job"`
+}
+
+func (accepted) StatusCode() int { return http.StatusAccepted }
+
+func TestHandlerFor(t *testing.T) {
+	router := NewRouter()
+	router.GET("/orders/{id}", HandlerFor(func(ctx context.Context, in getOrder) (order, error) {
+		if in.ID == 404 {
+			return order{}, NewHTTPError(http.StatusNotFound, "no such order")
+		}
+		name := "plain"
+		if in.Expand {
+			name = "expanded"
+		}
+		return order{ID: in.ID, Name: name, Fields: in.Fields, Tenant: in.Tenant}, nil
+	}))
+	router.POST("/orders", HandlerFor(func(ctx context.Context, in createOrder) (*order, error) {
+		return &order{ID: 1, Name: in.Name}, nil
+	}))
+	router.POST("/orders/{id}/export", HandlerFor(func(ctx context.Context, in getOrder) (accepted, error) {
+		return accepted{Job: "export"}, nil
+	}))
+	router.DELETE("/orders/{id}", HandlerFor(func(ctx context.Context, in getOrder) (*order, error) {
+		return nil, nil
+	}))
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantBody   string
+		wantHeader string
+	}{
+		{"path, query and header", http.MethodGet, "/orders/7?expand=true&field=a&field=b", "", http.StatusOK, `{"id":7,"name":"expanded","fields":["a","b"]}`, "acme"},
+		{"invalid path parameter", http.MethodGet, "/orders/seven", "", http.StatusBadRequest, "invalid path parameter id: \"seven\" is not an integer", ""},
+		{"invalid query parameter", http.MethodGet, "/orders/7?expand=maybe", "", http.StatusBadRequest, "invalid query parameter expand: \"maybe\" is not a boolean", ""},
+		{"handler error", http.MethodGet, "/orders/404", "", http.StatusNotFound, "no such order", ""},
+		{"POST creates", http.MethodPost, "/orders", `{"name":"book"}`, http.StatusCreated, `{"id":1,"name":"book"}`, ""},
+		{"validation", http.MethodPost, "/orders", `{"name":""}`, http.StatusUnprocessableEntity, "invalid request: name is required", ""},
+		{"StatusCoder", http.MethodPost, "/orders/7/export", "", http.StatusAccepted, `{"job":"export"}`, ""},
+		{"nil response", http.MethodDelete, "/orders/7", "", http.StatusNoContent, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Tenant", "acme")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if got := strings.TrimSpace(w.Body.String()); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+			if got := w.Header().Get("X-Tenant"); got != tt.wantHeader {
+				t.Errorf("X-Tenant = %q, want %q", got, tt.wantHeader)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package shttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/andres-vara/slogr"
+)
+
+// WatchdogConfig configures WatchdogMiddleware.
+type WatchdogConfig struct {
+	// Directory the profiles are written to. Required.
+	Dir string
+
+	// Trigger a capture when the p99 latency of the recent requests exceeds
+	// this value, once the window holds enough samples for a meaningful p99
+	// (Window or 100, whichever is smaller). Zero disables the latency
+	// trigger.
+	P99Threshold time.Duration
+
+	// Trigger a capture when more than this many requests are in flight.
+	// Zero disables the in-flight trigger.
+	InFlightThreshold int
+
+	// Number of recent request latencies used to compute the p99 (default 1000).
+	Window int
+
+	// Duration of the CPU profile captured on trigger. Zero captures only a
+	// goroutine dump.
+	CPUProfileDuration time.Duration
+
+	// Minimum time between two captures (default 5 minutes).
+	MinInterval time.Duration
+
+	// Logger used to report captures. Optional.
+	Logger *slogr.Logger
+}
+
+// watchdogMinSamples is the number of latencies the p99 needs at least,
+// unless the window is smaller.
+const watchdogMinSamples = 100
+
+// watchdog holds the runtime state of a WatchdogMiddleware.
+type watchdog struct {
+	cfg      WatchdogConfig
+	inFlight atomic.Int64
+
+	mu          sync.Mutex
+	samples     []time.Duration
+	next        int
+	lastCapture time.Time
+}
+
+// WatchdogMiddleware creates a middleware that captures a goroutine dump (and
+// optionally a CPU profile) into cfg.Dir when the p99 latency or the number of
+// in-flight requests crosses the configured thresholds. Captures are rate
+// limited by cfg.MinInterval and written asynchronously so requests are never
+// delayed by profiling.
+func WatchdogMiddleware(cfg WatchdogConfig) Middleware {
+	if cfg.Window <= 0 {
+		cfg.Window = 1000
+	}
+	if cfg.MinInterval <= 0 {
+		cfg.MinInterval = 5 * time.Minute
+	}
+	wd := &watchdog{cfg: cfg, samples: make([]time.Duration, 0, cfg.Window)}
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			n := wd.inFlight.Add(1)
+			defer wd.inFlight.Add(-1)
+			if cfg.InFlightThreshold > 0 && n > int64(cfg.InFlightThreshold) {
+				wd.trigger(ctx, fmt.Sprintf("in_flight=%d", n))
+			}
+
+			start := time.Now()
+			err := next(ctx, w, r)
+			wd.observe(ctx, time.Since(start))
+			return err
+		}
+	}
+}
+
+// observe records a request latency and triggers a capture when the p99 of
+// the window exceeds the threshold. The p99 is only recomputed for slow
+// samples, since fast ones cannot push it over the threshold, and once
+// there are enough samples for one slow request not to be the p99.
+func (wd *watchdog) observe(ctx context.Context, d time.Duration) {
+	wd.mu.Lock()
+	if len(wd.samples) < wd.cfg.Window {
+		wd.samples = append(wd.samples, d)
+	} else {
+		wd.samples[wd.next] = d
+		wd.next = (wd.next + 1) % wd.cfg.Window
+	}
+	var sorted []time.Duration
+	if wd.cfg.P99Threshold > 0 && d > wd.cfg.P99Threshold &&
+		len(wd.samples) >= min(wd.cfg.Window, watchdogMinSamples) {
+		sorted = slices.Clone(wd.samples)
+	}
+	wd.mu.Unlock()
+	if sorted == nil {
+		return
+	}
+
+	// Sort outside the lock, other requests keep recording meanwhile
+	slices.Sort(sorted)
+	if p99 := sorted[(len(sorted)*99)/100]; p99 > wd.cfg.P99Threshold {
+		wd.trigger(ctx, fmt.Sprintf("p99=%s", p99))
+	}
+}
+
+// trigger starts a capture unless one happened within MinInterval.
+func (wd *watchdog) trigger(ctx context.Context, reason string) {
+	wd.mu.Lock()
+	now := time.Now()
+	if !wd.lastCapture.IsZero() && now.Sub(wd.lastCapture) < wd.cfg.MinInterval {
+		wd.mu.Unlock()
+		return
+	}
+	wd.lastCapture = now
+	wd.mu.Unlock()
+
+	// Detach from the request so the capture outlives it.
+	go wd.capture(context.WithoutCancel(ctx), reason, now)
+}
+
+// capture writes the goroutine dump and, if configured, a CPU profile.
+func (wd *watchdog) capture(ctx context.Context, reason string, at time.Time) {
+	stamp := at.Format("20060102T150405")
+	if err := os.MkdirAll(wd.cfg.Dir, 0o755); err != nil {
+		wd.logError(ctx, "create profile dir", err)
+		return
+	}
+
+	goroutinePath := filepath.Join(wd.cfg.Dir, "goroutine-"+stamp+".txt")
+	if err := writeProfile(goroutinePath, func(f *os.File) error {
+		return pprof.Lookup("goroutine").WriteTo(f, 2)
+	}); err != nil {
+		wd.logError(ctx, "goroutine dump", err)
+	}
+
+	if wd.cfg.CPUProfileDuration > 0 {
+		cpuPath := filepath.Join(wd.cfg.Dir, "cpu-"+stamp+".pprof")
+		if err := writeProfile(cpuPath, func(f *os.File) error {
+			if err := pprof.StartCPUProfile(f); err != nil {
+				return err
+			}
+			time.Sleep(wd.cfg.CPUProfileDuration)
+			pprof.StopCPUProfile()
+			return nil
+		}); err != nil {
+			wd.logError(ctx, "cpu profile", err)
+		}
+	}
+
+	if wd.cfg.Logger != nil {
+		wd.cfg.Logger.Infof(ctx, "[watchdog.capture] reason=%s dir=%s", reason, wd.cfg.Dir)
+	}
+}
+
+func (wd *watchdog) logError(ctx context.Context, what string, err error) {
+	if wd.cfg.Logger != nil {
+		wd.cfg.Logger.Errorf(ctx, "[watchdog.capture] %s failed: %v", what, err)
+	}
+}
+
+// writeProfile fills path using write. The data goes to a temporary file
+// first so a partially written profile is never visible under its final name.
+func writeProfile(path string, write func(*os.File) error) error {
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,79 @@
+package shttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchdogMiddleware(t *testing.T) {
+	slow := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		time.Sleep(2 * time.Millisecond)
+		return nil
+	}
+	tests := []struct {
+		name        string
+		cfg         WatchdogConfig
+		handler     Handler
+		requests    int
+		wantCapture bool
+	}{
+		{
+			name:        "Latency over threshold captures goroutine dump",
+			cfg:         WatchdogConfig{P99Threshold: time.Millisecond, Window: 10},
+			handler:     slow,
+			requests:    10,
+			wantCapture: true,
+		},
+		{
+			name:        "Too few samples do not capture",
+			cfg:         WatchdogConfig{P99Threshold: time.Millisecond},
+			handler:     slow,
+			requests:    10,
+			wantCapture: false,
+		},
+		{
+			name:        "Fast request does not capture",
+			cfg:         WatchdogConfig{P99Threshold: time.Second},
+			handler:     simpleHandler("ok"),
+			requests:    1,
+			wantCapture: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Dir = t.TempDir()
+
+			handler := WatchdogMiddleware(tt.cfg)(tt.handler)
+			for range tt.requests {
+				req := httptest.NewRequest(http.MethodGet, "/test", nil)
+				handler(req.Context(), httptest.NewRecorder(), req)
+			}
+
+			// Captures run asynchronously; poll briefly for the dump.
+			var files []string
+			deadline := time.Now().Add(time.Second)
+			for time.Now().Before(deadline) {
+				files, _ = filepath.Glob(filepath.Join(tt.cfg.Dir, "goroutine-*.txt"))
+				if len(files) > 0 || !tt.wantCapture {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			if gotCapture := len(files) > 0; gotCapture != tt.wantCapture {
+				t.Fatalf("captured = %v, want %v", gotCapture, tt.wantCapture)
+			}
+			if tt.wantCapture {
+				if info, err := os.Stat(files[0]); err != nil || info.Size() == 0 {
+					t.Errorf("goroutine dump is empty or unreadable: %v", err)
+				}
+			}
+		})
+	}
+}
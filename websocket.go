@@ -0,0 +1,380 @@
+package shttp
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// WebSocket message types (RFC 6455 opcodes).
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+)
+
+const (
+	wsContinuation = 0x0
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+)
+
+// CloseNormal is the WebSocket close code (1000) of a normal closure.
+const CloseNormal = 1000
+
+// Other WebSocket close codes (RFC 6455, section 7.4.1).
+const (
+	closeProtocolError = 1002
+	closeInvalidData   = 1007
+	closeTooBig        = 1009
+	closeNoStatus      = 1005
+)
+
+// defaultWebSocketMessageSize is the default UpgradeOptions.MaxMessageSize.
+const defaultWebSocketMessageSize = 1 << 20
+
+// websocketGUID is appended to the client's key to compute the accept key.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// ErrWebSocketProtocol is returned by WebSocketConn.ReadMessage when the
+// client breaks the protocol; the connection is closed.
+var ErrWebSocketProtocol = errors.New("shttp: websocket protocol error")
+
+// WebSocketCloseError is returned by WebSocketConn.ReadMessage once the client
+// closed the connection.
+type WebSocketCloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *WebSocketCloseError) Error() string {
+	return fmt.Sprintf("shttp: websocket closed with code %d: %s", e.Code, e.Reason)
+}
+
+// UpgradeOptions configures UpgradeWithOptions.
+type UpgradeOptions struct {
+	// Reports whether the request's Origin is accepted (default: requests
+	// without Origin, and those whose Origin host is the request's Host).
+	// Browsers send cookies with cross-site WebSocket requests, so accepting
+	// any origin lets other sites act on behalf of the user.
+	CheckOrigin func(r *http.Request) bool
+
+	// Subprotocols supported by the server; the first one offered by the
+	// client that is supported is selected (see WebSocketConn.Subprotocol)
+	Subprotocols []string
+
+	// Largest message accepted from the client (default 1 MiB); larger
+	// messages close the connection with code 1009
+	MaxMessageSize int64
+}
+
+// WebSocketConn is a server-side WebSocket connection. ReadMessage must be
+// called from one goroutine at a time; WriteMessage and Close are safe for
+// concurrent use. Close must be called once the connection is done with, so
+// Shutdown stops waiting for it.
+type WebSocketConn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	maxSize     int64
+	subprotocol string
+
+	wmu       sync.Mutex
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// Upgrade switches the request to the WebSocket protocol, so WebSocket
+// endpoints are registered as normal routes, behind the router's middleware.
+// The connection is tracked (see TrackConnection): on shutdown the client
+// gets a close frame with CloseGoingAway and ReadMessage fails. Once
+// upgraded, the handler owns the connection until it returns; it must not
+// use w anymore. Routes using it need NoTimeout, otherwise the router's
+// default timeout ends the connection's context:
+//
+//	server.GET("/ws", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+//		ws, err := shttp.Upgrade(ctx, w, r)
+//		if err != nil {
+//			return err
+//		}
+//		defer ws.Close(shttp.CloseNormal, "")
+//		for {
+//			typ, msg, err := ws.ReadMessage()
+//			if err != nil {
+//				return nil
+//			}
+//			if err := ws.WriteMessage(typ, msg); err != nil {
+//				return nil
+//			}
+//		}
+//	}, shttp.NoTimeout())
+//
+// Requests that are not valid WebSocket handshakes fail with 400, those
+// from another origin with 403.
+func Upgrade(ctx context.Context, w http.ResponseWriter, r *http.Request) (*WebSocketConn, error) {
+	return UpgradeWithOptions(ctx, w, r, UpgradeOptions{})
+}
+
+// UpgradeWithOptions is Upgrade with explicit options.
+func UpgradeWithOptions(ctx context.Context, w http.ResponseWriter, r *http.Request, opts UpgradeOptions) (*WebSocketConn, error) {
+	if opts.CheckOrigin == nil {
+		opts.CheckOrigin = sameOrigin
+	}
+	if opts.MaxMessageSize <= 0 {
+		opts.MaxMessageSize = defaultWebSocketMessageSize
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	switch {
+	case r.Method != http.MethodGet,
+		!headerHasToken(r.Header, "Connection", "upgrade"),
+		!headerHasToken(r.Header, "Upgrade", "websocket"),
+		key == "":
+		return nil, NewHTTPError(http.StatusBadRequest, "not a websocket handshake")
+	case r.Header.Get("Sec-WebSocket-Version") != "13":
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, NewHTTPError(http.StatusUpgradeRequired, "unsupported websocket version")
+	case !opts.CheckOrigin(r):
+		return nil, NewHTTPError(http.StatusForbidden, "websocket origin not allowed")
+	}
+
+	var subprotocol string
+	for _, offered := range strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",") {
+		if offered = strings.TrimSpace(offered); slices.Contains(opts.Subprotocols, offered) {
+			subprotocol = offered
+			break
+		}
+	}
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("shttp: websocket upgrade: %w", err)
+	}
+	// The server's read and write timeouts would otherwise end the connection
+	conn.SetDeadline(time.Time{})
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	resp := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n"
+	if subprotocol != "" {
+		resp += "Sec-WebSocket-Protocol: " + subprotocol + "\r\n"
+	}
+	if _, err := io.WriteString(conn, resp+"\r\n"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	ws := &WebSocketConn{
+		conn:        conn,
+		br:          brw.Reader,
+		maxSize:     opts.MaxMessageSize,
+		subprotocol: subprotocol,
+		closed:      make(chan struct{}),
+	}
+	untrack := TrackConnection(ctx, func(ctx context.Context) error {
+		if err := ws.Close(CloseGoingAway, "server shutting down"); !errors.Is(err, net.ErrClosed) {
+			return err
+		}
+		return nil
+	})
+	go func() {
+		<-ws.closed
+		untrack()
+	}()
+	return ws, nil
+}
+
+// sameOrigin is the default UpgradeOptions.CheckOrigin.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// headerHasToken reports whether the comma-separated header contains token,
+// case-insensitively.
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Subprotocol returns the subprotocol selected during the handshake, if any.
+func (c *WebSocketConn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// NetConn returns the underlying connection.
+func (c *WebSocketConn) NetConn() net.Conn {
+	return c.conn
+}
+
+// ReadMessage reads the next text or binary message, answering pings in
+// the meantime. Once the client closes the connection it returns a
+// *WebSocketCloseError.
+func (c *WebSocketConn) ReadMessage() (messageType int, data []byte, err error) {
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			closeErr := &WebSocketCloseError{Code: closeNoStatus}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Reason = string(payload[2:])
+			}
+			c.Close(CloseNormal, "")
+			return 0, nil, closeErr
+		case wsContinuation:
+			if messageType == 0 {
+				return 0, nil, c.fail(closeProtocolError, "unexpected continuation frame")
+			}
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.fail(closeProtocolError, "expected continuation frame")
+			}
+			messageType = int(op)
+		default:
+			return 0, nil, c.fail(closeProtocolError, "unknown opcode")
+		}
+
+		if int64(len(data)+len(payload)) > c.maxSize {
+			return 0, nil, c.fail(closeTooBig, "message too big")
+		}
+		data = append(data, payload...)
+		if fin {
+			if messageType == TextMessage && !utf8.Valid(data) {
+				return 0, nil, c.fail(closeInvalidData, "invalid UTF-8")
+			}
+			return messageType, data, nil
+		}
+	}
+}
+
+// readFrame reads and unmasks one frame.
+func (c *WebSocketConn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, op = header[0]&0x80 != 0, header[0]&0x0f
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(closeProtocolError, "no extension negotiated")
+	}
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, c.fail(closeProtocolError, "client frames must be masked")
+	}
+
+	size := int64(header[1] & 0x7f)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		size = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		size = int64(binary.BigEndian.Uint64(ext[:]) & (1<<63 - 1))
+	}
+	if op >= wsClose && (size > 125 || !fin) {
+		return false, 0, nil, c.fail(closeProtocolError, "invalid control frame")
+	}
+	if size > c.maxSize {
+		return false, 0, nil, c.fail(closeTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, size)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// fail closes the connection with code after a protocol violation.
+func (c *WebSocketConn) fail(code int, reason string) error {
+	c.Close(code, reason)
+	return fmt.Errorf("%w: %s", ErrWebSocketProtocol, reason)
+}
+
+// WriteMessage sends data as a single message of the given type
+// (TextMessage or BinaryMessage).
+func (c *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("shttp: invalid websocket message type %d", messageType)
+	}
+	return c.writeFrame(byte(messageType), data)
+}
+
+// writeFrame writes one final, unmasked frame.
+func (c *WebSocketConn) writeFrame(op byte, payload []byte) error {
+	header := make([]byte, 2, 10+len(payload))
+	header[0] = 0x80 | op
+	switch n := len(payload); {
+	case n <= 125:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err := c.conn.Write(append(header, payload...))
+	return err
+}
+
+// Close sends a close frame with code and reason and closes the connection.
+// Only the first call has an effect.
+func (c *WebSocketConn) Close(code int, reason string) error {
+	err := net.ErrClosed
+	c.closeOnce.Do(func() {
+		c.wmu.Lock()
+		writeErr := WriteWebSocketClose(c.conn, code, reason)
+		c.wmu.Unlock()
+		err = errors.Join(writeErr, c.conn.Close())
+		close(c.closed)
+	})
+	return err
+}
@@ -0,0 +1,205 @@
+package shttp
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/andres-vara/slogr"
+)
+
+// dialWebSocket performs the client side of the handshake against ts.
+func dialWebSocket(t *testing.T, ts *httptest.Server, path string, header http.Header) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatalf("writing handshake: %v", err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatalf("reading handshake response: %v", err)
+	}
+	return conn, br, resp
+}
+
+// writeClientFrame writes a masked frame, as clients must.
+func writeClientFrame(t *testing.T, w io.Writer, first byte, payload []byte) {
+	t.Helper()
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{first, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := w.Write(frame); err != nil {
+		t.Fatalf("writing frame: %v", err)
+	}
+}
+
+// readServerFrame reads a short unmasked frame.
+func readServerFrame(t *testing.T, r io.Reader) (op byte, payload []byte) {
+	t.Helper()
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		t.Fatalf("reading frame: %v", err)
+	}
+	payload = make([]byte, header[1]&0x7f)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatalf("reading payload: %v", err)
+	}
+	return header[0] & 0x0f, payload
+}
+
+func TestUpgradeEcho(t *testing.T) {
+	server := New(context.Background(), &Config{Logger: slogr.New(io.Discard, slogr.DefaultOptions())})
+	status := make(chan int, 1)
+	server.Use(func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			err := next(ctx, w, r)
+			status <- finalStatus(w.(*responseWriter), err)
+			return err
+		}
+	})
+	server.GET("/ws", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		ws, err := UpgradeWithOptions(ctx, w, r, UpgradeOptions{Subprotocols: []string{"chat"}})
+		if err != nil {
+			return err
+		}
+		defer ws.Close(CloseNormal, "")
+		for {
+			typ, msg, err := ws.ReadMessage()
+			if err != nil {
+				return nil
+			}
+			if err := ws.WriteMessage(typ, msg); err != nil {
+				return nil
+			}
+		}
+	}, NoTimeout())
+
+	ts := httptest.NewUnstartedServer(server)
+	ts.Config.BaseContext = server.HTTPServer().BaseContext
+	ts.Start()
+	defer ts.Close()
+
+	conn, br, resp := dialWebSocket(t, ts, "/ws", http.Header{"Sec-Websocket-Protocol": {"other, chat"}})
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", resp.StatusCode)
+	}
+	// Example key and accept value from RFC 6455, section 1.3
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q", got)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Protocol"); got != "chat" {
+		t.Errorf("Sec-WebSocket-Protocol = %q, want chat", got)
+	}
+
+	// A fragmented text message with a ping in between
+	writeClientFrame(t, conn, 0x01, []byte("hel"))
+	writeClientFrame(t, conn, 0x89, []byte("p"))
+	writeClientFrame(t, conn, 0x80, []byte("lo"))
+	if op, payload := readServerFrame(t, br); op != wsPong || string(payload) != "p" {
+		t.Errorf("frame = %x %q, want pong", op, payload)
+	}
+	if op, payload := readServerFrame(t, br); op != TextMessage || string(payload) != "hello" {
+		t.Errorf("frame = %x %q, want the echoed text", op, payload)
+	}
+	if got := server.Stats().TrackedConnections; got != 1 {
+		t.Errorf("TrackedConnections = %d, want 1", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	op, payload := readServerFrame(t, br)
+	if op != wsClose || len(payload) < 2 || binary.BigEndian.Uint16(payload) != CloseGoingAway {
+		t.Errorf("frame = %x %q, want a going away close frame", op, payload)
+	}
+	if got := <-status; got != http.StatusSwitchingProtocols {
+		t.Errorf("status seen by middleware = %d, want 101", got)
+	}
+}
+
+func TestUpgradeClientClose(t *testing.T) {
+	readErr := make(chan error, 1)
+	router := NewRouter()
+	router.GET("/ws", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		ws, err := Upgrade(ctx, w, r)
+		if err != nil {
+			return err
+		}
+		defer ws.Close(CloseNormal, "")
+		_, _, err = ws.ReadMessage()
+		readErr <- err
+		return nil
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	conn, br, _ := dialWebSocket(t, ts, "/ws", nil)
+	writeClientFrame(t, conn, 0x88, append(binary.BigEndian.AppendUint16(nil, 4000), "bye"...))
+
+	var closeErr *WebSocketCloseError
+	if err := <-readErr; !errors.As(err, &closeErr) || closeErr.Code != 4000 || closeErr.Reason != "bye" {
+		t.Errorf("ReadMessage() error = %v, want close 4000 bye", err)
+	}
+	if op, payload := readServerFrame(t, br); op != wsClose || binary.BigEndian.Uint16(payload) != CloseNormal {
+		t.Errorf("frame = %x %q, want the close reply", op, payload)
+	}
+}
+
+func TestUpgradeRejects(t *testing.T) {
+	router := NewRouter()
+	router.GET("/ws", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		ws, err := Upgrade(ctx, w, r)
+		if err != nil {
+			return err
+		}
+		return ws.Close(CloseNormal, "")
+	})
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{"plain request", http.Header{}, http.StatusBadRequest},
+		{"old version", http.Header{"Connection": {"Upgrade"}, "Upgrade": {"websocket"}, "Sec-Websocket-Key": {"x"}, "Sec-Websocket-Version": {"8"}}, http.StatusUpgradeRequired},
+		{"cross origin", http.Header{"Connection": {"keep-alive, Upgrade"}, "Upgrade": {"websocket"}, "Sec-Websocket-Key": {"x"}, "Sec-Websocket-Version": {"13"}, "Origin": {"https://evil.example"}}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+			req.Header = tt.header
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}